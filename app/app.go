@@ -1,25 +1,36 @@
 package app
 
 import (
+	"Commentary/internal/auth"
 	"Commentary/internal/common"
 	"Commentary/internal/config"
 	"Commentary/internal/db"
 	"Commentary/internal/graph"
+	"Commentary/internal/graph/model"
+	"Commentary/internal/pubsub"
+	"Commentary/internal/ratelimit"
+	"Commentary/internal/repo"
+	"context"
 	"database/sql"
+	"errors"
 	"github.com/sirupsen/logrus"
 )
 
 type App struct {
-	Resolver       *graph.Resolver
-	CommentService common.CommentService
-	PostService    common.PostService
-	UserService    common.UserService
+	Resolver        *graph.Resolver
+	CommentService  common.CommentService
+	PostService     common.PostService
+	UserService     common.UserService
+	ReactionService common.ReactionService
+	MentionService  common.MentionService
+	TokenManager    *auth.TokenManager
+	RateLimiter     *ratelimit.Limiter
 }
 
 func InitApp(cfg *config.Config) *App {
 	var DB *sql.DB
 	var err error
-	if cfg.Database.StoreInDB {
+	if cfg.Database.StoreInDB || cfg.PubSub.Backend == pubsub.BackendPostgres {
 		DB, err = db.InitDB(cfg)
 		if err != nil {
 			logrus.Fatal(err)
@@ -29,18 +40,63 @@ func InitApp(cfg *config.Config) *App {
 	factory := NewServiceFactory(cfg, DB)
 
 	postService := factory.CreatePostService()
-	commentService, broker := factory.CreateCommentService()
-	postService.SetCommentService(commentService)
+	commentService := factory.CreateCommentService()
 	userService := factory.CreateUserService()
+	reactionService := factory.CreateReactionService()
+	searchService := factory.CreateSearchService()
+	notificationService := factory.CreateNotificationService()
+	moderationService := factory.CreateModerationService()
+	grantModerators(moderationService, cfg.Moderation.ModeratorIDs)
+	seedModerators(userService, moderationService, cfg.Moderation.Moderators)
 
-	resolver := graph.NewResolver(postService, commentService, userService, broker)
+	resolver := graph.NewResolver(postService, commentService, userService, reactionService, searchService,
+		notificationService, moderationService, factory.Broker(), factory.Presence(), factory.Markdown())
 
 	logrus.Info("Initialized App")
 
 	return &App{
-		Resolver:       resolver,
-		CommentService: commentService,
-		PostService:    postService,
-		UserService:    userService,
+		Resolver:        resolver,
+		CommentService:  commentService,
+		PostService:     postService,
+		UserService:     userService,
+		ReactionService: reactionService,
+		MentionService:  factory.CreateMentionService(),
+		TokenManager:    factory.TokenManager(),
+		RateLimiter:     factory.RateLimiter(),
+	}
+}
+
+// grantModerators gives the moderator role to the configured users. Users that are not registered
+// are skipped, the role is never granted to whoever registers with their ID later.
+func grantModerators(moderationService common.ModerationService, userIDs []int) {
+	for _, userID := range userIDs {
+		if err := moderationService.GrantModerator(context.Background(), userID); err != nil {
+			logrus.WithError(err).Warnf("failed to grant the moderator role to user %d", userID)
+		}
+	}
+}
+
+// seedModerators registers the configured moderator accounts that do not exist yet and gives them
+// the moderator role. An existing account is granted the role only if the configured password is its
+// password, so that whoever registered the username first does not become a moderator.
+func seedModerators(userService common.UserService, moderationService common.ModerationService,
+	accounts []config.ModeratorAccount) {
+	ctx := context.Background()
+	for _, account := range accounts {
+		user, err := userService.CreateUser(ctx, account.Username, account.Password)
+		if errors.Is(err, repo.ErrUserAlreadyExists) {
+			var payload *model.AuthPayload
+			if payload, err = userService.Login(ctx, account.Username, account.Password); err == nil {
+				user = payload.User
+			}
+		}
+		if err != nil {
+			logrus.WithError(err).Warnf("failed to seed moderator %q", account.Username)
+			continue
+		}
+
+		if err = moderationService.GrantModerator(ctx, user.ID); err != nil {
+			logrus.WithError(err).Warnf("failed to grant the moderator role to %q", account.Username)
+		}
 	}
 }
@@ -2,9 +2,14 @@ package main
 
 import (
 	"Commentary/app"
+	"Commentary/internal/auth"
 	"Commentary/internal/config"
 	"Commentary/internal/graph"
+	"Commentary/internal/loader"
 	"Commentary/internal/logger"
+	"Commentary/internal/pubsub"
+	"Commentary/internal/querylimit"
+	"Commentary/internal/ratelimit"
 	"github.com/99designs/gqlgen/graphql/handler"
 	"github.com/99designs/gqlgen/graphql/handler/extension"
 	"github.com/99designs/gqlgen/graphql/handler/lru"
@@ -28,7 +33,10 @@ func main() {
 
 	port := strconv.Itoa(cfg.Server.Port)
 
-	srv := handler.New(graph.NewExecutableSchema(graph.Config{Resolvers: appObj.Resolver}))
+	srv := handler.New(graph.NewExecutableSchema(graph.Config{
+		Resolvers:  appObj.Resolver,
+		Complexity: graph.NewComplexity(),
+	}))
 
 	srv.AddTransport(transport.Options{})
 	srv.AddTransport(transport.GET{})
@@ -41,17 +49,32 @@ func main() {
 	}
 	srv.AddTransport(transport.Websocket{
 		Upgrader: upgrader,
+		InitFunc: auth.WebsocketInitFunc(appObj.TokenManager),
 	})
 
 	srv.SetQueryCache(lru.New[*ast.QueryDocument](1000))
+	srv.SetErrorPresenter(graph.ErrorPresenter)
 
 	srv.Use(extension.Introspection{})
+	srv.Use(&querylimit.Extension{
+		MaxComplexity: cfg.QueryLimits.MaxComplexity,
+		MaxDepth:      cfg.QueryLimits.MaxDepth,
+	})
+	srv.Use(loader.Extension{
+		PostService:     appObj.PostService,
+		CommentService:  appObj.CommentService,
+		UserService:     appObj.UserService,
+		ReactionService: appObj.ReactionService,
+		MentionService:  appObj.MentionService,
+	})
+	srv.Use(pubsub.Extension{})
+	srv.Use(ratelimit.Extension{Limiter: appObj.RateLimiter})
 	srv.Use(extension.AutomaticPersistedQuery{
 		Cache: lru.New[string](100),
 	})
 
 	http.Handle("/", playground.Handler("GraphQL playground", "/query"))
-	http.Handle("/query", srv)
+	http.Handle("/query", ratelimit.Middleware(cfg.RateLimit.TrustProxy, auth.Middleware(appObj.TokenManager, srv)))
 
 	log.Printf("connect to http://localhost:%s/ for GraphQL playground", port)
 	log.Fatal(http.ListenAndServe(":"+port, nil))
@@ -1,64 +1,235 @@
 package app
 
 import (
+	"Commentary/internal/auth"
 	"Commentary/internal/common"
 	"Commentary/internal/config"
+	"Commentary/internal/db"
+	"Commentary/internal/filter"
 	"Commentary/internal/inmemory/imrepo"
-	"Commentary/internal/inmemory/imservice"
+	"Commentary/internal/markdown"
+	"Commentary/internal/pagination"
+	"Commentary/internal/presence"
 	"Commentary/internal/pubsub"
+	"Commentary/internal/ratelimit"
+	"Commentary/internal/repo"
 	"Commentary/internal/repo/pgdb"
 	"Commentary/internal/service"
 	"database/sql"
+	"github.com/sirupsen/logrus"
 )
 
+// repos are the storage the services work with, PostgreSQL or the in-memory one.
+type repos struct {
+	posts         repo.PostRepo
+	comments      repo.CommentRepo
+	users         repo.UserRepo
+	reactions     repo.ReactionRepo
+	search        repo.SearchRepo
+	notifications repo.NotificationRepo
+	mentions      repo.MentionRepo
+	moderation    repo.ModerationRepo
+}
+
+func newRepos(cfg *config.Config, DB *sql.DB) repos {
+	if cfg.Database.StoreInDB {
+		return repos{
+			posts:         pgdb.NewPostRepo(DB, ranking(cfg)),
+			comments:      pgdb.NewCommentRepo(DB, ranking(cfg)),
+			users:         pgdb.NewUserRepo(DB),
+			reactions:     pgdb.NewReactionRepo(DB),
+			search:        pgdb.NewSearchRepo(DB),
+			notifications: pgdb.NewNotificationRepo(DB),
+			mentions:      pgdb.NewMentionRepo(DB),
+			moderation:    pgdb.NewModerationRepo(DB),
+		}
+	}
+
+	memory := imrepo.NewInMemoryRepo(ranking(cfg))
+	return repos{
+		posts:         memory,
+		comments:      memory,
+		users:         memory,
+		reactions:     memory,
+		search:        memory,
+		notifications: memory,
+		mentions:      memory,
+		moderation:    memory,
+	}
+}
+
 type ServiceFactory struct {
 	cfg         *config.Config
-	db          *sql.DB
-	imRepo      *imrepo.InMemoryRepo
+	repos       repos
+	tokens      *auth.TokenManager
+	broker      pubsub.Broker
+	presence    *presence.Tracker
+	markdown    *markdown.Renderer
+	filters     *filter.Pipeline
+	limiter     *ratelimit.Limiter
 	postService common.PostService
+	// notificationService is shared by the comment service producing notifications and the resolvers.
+	notificationService common.NotificationService
+	mentionService      common.MentionService
+	moderationService   common.ModerationService
 }
 
 func NewServiceFactory(cfg *config.Config, db *sql.DB) *ServiceFactory {
+	broker := newBroker(cfg, db)
 	return &ServiceFactory{
-		cfg:    cfg,
-		db:     db,
-		imRepo: imrepo.NewInMemoryRepo(),
+		cfg:      cfg,
+		repos:    newRepos(cfg, db),
+		tokens:   newTokens(cfg),
+		broker:   broker,
+		presence: newPresence(cfg, broker),
+		markdown: newMarkdown(cfg),
+		filters:  newFilters(cfg),
+		limiter:  newLimiter(cfg),
+	}
+}
+
+func newTokens(cfg *config.Config) *auth.TokenManager {
+	tokens, err := auth.NewTokenManager(cfg.Auth)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	return tokens
+}
+
+func newLimiter(cfg *config.Config) *ratelimit.Limiter {
+	limiter, err := ratelimit.NewLimiter(cfg.RateLimit.Limits)
+	if err != nil {
+		logrus.Fatalf("invalid rate limits: %v", err)
+	}
+	return limiter
+}
+
+func newFilters(cfg *config.Config) *filter.Pipeline {
+	pipeline, err := filter.NewPipeline(cfg.Filters)
+	if err != nil {
+		logrus.Fatalf("invalid content filters: %v", err)
+	}
+	return pipeline
+}
+
+func newMarkdown(cfg *config.Config) *markdown.Renderer {
+	renderer, err := markdown.NewRenderer(cfg.Markdown.CacheSize)
+	if err != nil {
+		logrus.Fatalf("invalid markdown cache size %d: %v", cfg.Markdown.CacheSize, err)
 	}
+	return renderer
+}
+
+// newPresence shares the presence through the broker only when it reaches other replicas.
+func newPresence(cfg *config.Config, broker pubsub.Broker) *presence.Tracker {
+	if cfg.Presence.TTL <= 0 {
+		logrus.Fatalf("presence ttl must be positive, got %v", cfg.Presence.TTL)
+	}
+	if cfg.PubSub.Backend != pubsub.BackendPostgres {
+		broker = nil
+	}
+	return presence.NewTracker(cfg.Presence.TTL, broker)
+}
+
+func newBroker(cfg *config.Config, DB *sql.DB) pubsub.Broker {
+	options := pubsub.Options{
+		Buffer:       cfg.PubSub.Buffer,
+		Policy:       pubsub.Policy(cfg.PubSub.Overflow),
+		BlockTimeout: cfg.PubSub.BlockTimeout,
+	}
+	if err := options.Validate(); err != nil {
+		logrus.Fatal(err)
+	}
+
+	switch cfg.PubSub.Backend {
+	case pubsub.BackendMemory:
+		return pubsub.NewLocalBroker(options)
+	case pubsub.BackendPostgres:
+		broker, err := pubsub.NewPostgresBroker(DB, db.DSN(cfg), options)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		return broker
+	default:
+		logrus.Fatalf("unknown pubsub backend %q", cfg.PubSub.Backend)
+		return nil
+	}
+}
+
+func ranking(cfg *config.Config) pagination.Ranking {
+	return pagination.Ranking{Negative: cfg.Reactions.Negative}
+}
+
+func (f *ServiceFactory) TokenManager() *auth.TokenManager {
+	return f.tokens
+}
+
+func (f *ServiceFactory) Broker() pubsub.Broker {
+	return f.broker
+}
+
+func (f *ServiceFactory) Presence() *presence.Tracker {
+	return f.presence
+}
+
+func (f *ServiceFactory) Markdown() *markdown.Renderer {
+	return f.markdown
+}
+
+func (f *ServiceFactory) RateLimiter() *ratelimit.Limiter {
+	return f.limiter
 }
 
 func (f *ServiceFactory) CreatePostService() common.PostService {
 	if f.postService == nil {
-		if f.cfg.Database.StoreInDB {
-			f.postService = service.NewPostService(
-				pgdb.NewPostRepo(f.db),
-				pgdb.NewUserRepo(f.db),
-			)
-		} else {
-			f.postService = imservice.NewPostService(f.imRepo)
-		}
+		f.postService = service.NewPostService(f.repos.posts, f.CreateMentionService(), f.CreateModerationService(),
+			f.filters, f.broker)
 	}
 	return f.postService
 }
 
-func (f *ServiceFactory) CreateCommentService() (common.CommentService, *pubsub.Broker) {
-	broker := pubsub.NewBroker()
-	if f.cfg.Database.StoreInDB {
-		return service.NewCommentService(
-			pgdb.NewCommentRepo(f.db),
-			pgdb.NewPostRepo(f.db),
-			pgdb.NewUserRepo(f.db),
-			f.CreatePostService(),
-			broker), broker
-	}
-	return imservice.NewCommentService(
-		f.imRepo,
+func (f *ServiceFactory) CreateCommentService() common.CommentService {
+	return service.NewCommentService(
+		f.repos.comments,
 		f.CreatePostService(),
-		broker), broker
+		f.CreateNotificationService(),
+		f.CreateMentionService(),
+		f.CreateModerationService(),
+		f.filters,
+		f.broker)
 }
 
-func (f *ServiceFactory) CreateUserService() common.UserService {
-	if f.cfg.Database.StoreInDB {
-		return service.NewUserService(pgdb.NewUserRepo(f.db))
+func (f *ServiceFactory) CreateNotificationService() common.NotificationService {
+	if f.notificationService == nil {
+		f.notificationService = service.NewNotificationService(f.repos.notifications, f.repos.comments,
+			f.repos.users, f.CreateModerationService(), f.broker)
+	}
+	return f.notificationService
+}
+
+func (f *ServiceFactory) CreateMentionService() common.MentionService {
+	if f.mentionService == nil {
+		f.mentionService = service.NewMentionService(f.repos.mentions, f.repos.users)
+	}
+	return f.mentionService
+}
+
+func (f *ServiceFactory) CreateModerationService() common.ModerationService {
+	if f.moderationService == nil {
+		f.moderationService = service.NewModerationService(f.repos.moderation, f.repos.users, f.repos.comments,
+			f.repos.posts, f.broker)
 	}
-	return imservice.NewUserService(f.imRepo)
+	return f.moderationService
+}
+
+func (f *ServiceFactory) CreateUserService() common.UserService {
+	return service.NewUserService(f.repos.users, f.tokens)
+}
+
+func (f *ServiceFactory) CreateReactionService() common.ReactionService {
+	return service.NewReactionService(f.repos.reactions, f.cfg.Reactions.Types)
+}
+
+func (f *ServiceFactory) CreateSearchService() common.SearchService {
+	return service.NewSearchService(f.repos.search, f.CreateModerationService())
 }
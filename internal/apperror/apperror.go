@@ -0,0 +1,37 @@
+package apperror
+
+// Kind is the class of a domain error, clients get it as extensions.code.
+type Kind string
+
+const (
+	NotFound   Kind = "NOT_FOUND"
+	Forbidden  Kind = "FORBIDDEN"
+	Validation Kind = "VALIDATION"
+	Conflict   Kind = "CONFLICT"
+	Internal   Kind = "INTERNAL"
+	// SlowConsumer ends a subscription whose client did not keep up with its events.
+	SlowConsumer Kind = "SLOW_CONSUMER"
+)
+
+// InternalMessage replaces the message of internal errors, their details are only logged.
+const InternalMessage = "internal server error"
+
+// Error is a domain error along with the input field that caused it, if any.
+type Error struct {
+	Kind    Kind
+	Field   string
+	Message string
+	Err     error
+}
+
+func New(kind Kind, field, message string, err error) *Error {
+	return &Error{Kind: kind, Field: field, Message: message, Err: err}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
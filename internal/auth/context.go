@@ -0,0 +1,24 @@
+package auth
+
+import "context"
+
+type ctxKey struct{}
+
+func WithUserID(ctx context.Context, userID int) context.Context {
+	return context.WithValue(ctx, ctxKey{}, userID)
+}
+
+// UserIDFromContext returns the ID of the authenticated user, if there is one.
+func UserIDFromContext(ctx context.Context) (int, bool) {
+	userID, ok := ctx.Value(ctxKey{}).(int)
+	return userID, ok
+}
+
+// ViewerID is like UserIDFromContext, but fails for anonymous requests.
+func ViewerID(ctx context.Context) (int, error) {
+	userID, ok := UserIDFromContext(ctx)
+	if !ok {
+		return 0, ErrUnauthenticated
+	}
+	return userID, nil
+}
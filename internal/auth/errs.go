@@ -0,0 +1,13 @@
+package auth
+
+import "errors"
+
+var (
+	ErrUnauthenticated    = errors.New("authentication required")
+	ErrInvalidToken       = errors.New("invalid token")
+	ErrExpiredToken       = errors.New("token expired")
+	ErrInvalidCredentials = errors.New("invalid username or password")
+	ErrWeakPassword       = errors.New("password must be at least 8 characters long")
+	ErrLongPassword       = errors.New("password must be at most 72 bytes long")
+	ErrWeakSecret         = errors.New("token secret must be at least 32 bytes long and not the placeholder")
+)
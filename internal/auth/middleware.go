@@ -0,0 +1,51 @@
+package auth
+
+import (
+	"context"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"strings"
+)
+
+// Middleware authenticates requests carrying an "Authorization: Bearer <token>" header.
+// Requests without the header are passed through anonymously.
+func Middleware(tm *TokenManager, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := bearerToken(r.Header.Get("Authorization"))
+		if token == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		userID, err := tm.Parse(token, AccessToken)
+		if err != nil {
+			logrus.WithError(err).Debug("rejected access token")
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(WithUserID(r.Context(), userID)))
+	})
+}
+
+// WebsocketInitFunc authenticates websocket connections by the "Authorization" field of the
+// connection_init payload, since browsers can't set headers on websocket requests.
+func WebsocketInitFunc(tm *TokenManager) transport.WebsocketInitFunc {
+	return func(ctx context.Context, initPayload transport.InitPayload) (context.Context, *transport.InitPayload, error) {
+		token := bearerToken(initPayload.Authorization())
+		if token == "" {
+			return ctx, &initPayload, nil
+		}
+
+		userID, err := tm.Parse(token, AccessToken)
+		if err != nil {
+			return ctx, nil, err
+		}
+		return WithUserID(ctx, userID), &initPayload, nil
+	}
+}
+
+func bearerToken(header string) string {
+	return strings.TrimSpace(strings.TrimPrefix(header, "Bearer "))
+}
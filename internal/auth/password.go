@@ -0,0 +1,48 @@
+package auth
+
+import (
+	"golang.org/x/crypto/bcrypt"
+	"sync"
+)
+
+const (
+	minPasswordLength = 8
+	// maxPasswordLength is the number of bytes bcrypt hashes.
+	maxPasswordLength = 72
+)
+
+// dummyHash is compared with the passwords of unknown users, so that logging in takes as long
+// whether the user exists or not.
+var dummyHash = sync.OnceValue(func() []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+	return hash
+})
+
+func HashPassword(password string) (string, error) {
+	if len(password) < minPasswordLength {
+		return "", ErrWeakPassword
+	}
+	if len(password) > maxPasswordLength {
+		return "", ErrLongPassword
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+func CheckPassword(hash, password string) error {
+	if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)); err != nil {
+		return ErrInvalidCredentials
+	}
+	return nil
+}
+
+// RejectPassword checks the password of an unknown user, it takes as long as CheckPassword
+// and always fails.
+func RejectPassword(password string) error {
+	_ = bcrypt.CompareHashAndPassword(dummyHash(), []byte(password))
+	return ErrInvalidCredentials
+}
@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"Commentary/internal/auth"
+	"Commentary/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	secret      = "a secret of at least thirty-two bytes"
+	otherSecret = "another secret of thirty-two bytes"
+)
+
+func newTokenManager(t *testing.T, cfg config.Auth) *auth.TokenManager {
+	tm, err := auth.NewTokenManager(cfg)
+	require.NoError(t, err)
+	return tm
+}
+
+func TestNewTokenManagerRejectsWeakSecret(t *testing.T) {
+	for _, weak := range []string{"", "change-me", "short secret"} {
+		_, err := auth.NewTokenManager(config.Auth{Secret: weak})
+		assert.ErrorIs(t, err, auth.ErrWeakSecret, weak)
+	}
+}
+
+func TestIssueAndParse(t *testing.T) {
+	tm := newTokenManager(t, config.Auth{Secret: secret, AccessTTL: time.Minute, RefreshTTL: time.Hour})
+
+	access, refresh, err := tm.IssuePair(42)
+	require.NoError(t, err)
+
+	userID, err := tm.Parse(access, auth.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, 42, userID)
+
+	userID, err = tm.Parse(refresh, auth.RefreshToken)
+	require.NoError(t, err)
+	assert.Equal(t, 42, userID)
+
+	_, err = tm.Parse(refresh, auth.AccessToken)
+	assert.ErrorIs(t, err, auth.ErrInvalidToken)
+}
+
+func TestParseRejectsForeignSignature(t *testing.T) {
+	tm := newTokenManager(t, config.Auth{Secret: secret, AccessTTL: time.Minute})
+	other := newTokenManager(t, config.Auth{Secret: otherSecret, AccessTTL: time.Minute})
+
+	token, err := other.Issue(1, auth.AccessToken)
+	require.NoError(t, err)
+
+	_, err = tm.Parse(token, auth.AccessToken)
+	assert.ErrorIs(t, err, auth.ErrInvalidToken)
+}
+
+func TestParseRejectsExpiredToken(t *testing.T) {
+	tm := newTokenManager(t, config.Auth{Secret: secret, AccessTTL: -time.Minute})
+
+	token, err := tm.Issue(1, auth.AccessToken)
+	require.NoError(t, err)
+
+	_, err = tm.Parse(token, auth.AccessToken)
+	assert.ErrorIs(t, err, auth.ErrExpiredToken)
+}
+
+func TestPassword(t *testing.T) {
+	_, err := auth.HashPassword("short")
+	assert.ErrorIs(t, err, auth.ErrWeakPassword)
+	_, err = auth.HashPassword(strings.Repeat("long", 19))
+	assert.ErrorIs(t, err, auth.ErrLongPassword)
+
+	hash, err := auth.HashPassword("long enough")
+	require.NoError(t, err)
+	assert.NoError(t, auth.CheckPassword(hash, "long enough"))
+	assert.ErrorIs(t, auth.CheckPassword(hash, "wrong password"), auth.ErrInvalidCredentials)
+	assert.ErrorIs(t, auth.RejectPassword("long enough"), auth.ErrInvalidCredentials)
+}
@@ -6,19 +6,89 @@ import (
 )
 
 type PostService interface {
-	SetCommentService(commentService CommentService)
 	CreatePost(ctx context.Context, input model.CreatePostInput) (*model.Post, error)
-	GetPost(ctx context.Context, id int, limit, offset *int) (*model.Post, error)
+	GetPost(ctx context.Context, id int) (*model.Post, error)
+	GetPostsByIDs(ctx context.Context, ids []int) (map[int]*model.Post, error)
 	ToggleComments(ctx context.Context, postID int) (*model.Post, error)
-	GetPosts(ctx context.Context, limit, offset *int) ([]*model.Post, error)
+	GetPosts(ctx context.Context, first *int, after *string, sort model.SortOrder) (*model.PostConnection, error)
+	UpdatePost(ctx context.Context, postID int, input model.UpdatePostInput) (*model.Post, error)
+	DeletePost(ctx context.Context, postID int) (bool, error)
 }
 
 type CommentService interface {
-	GetComments(ctx context.Context, postID int, limit, offset *int) ([]*model.Comment, error)
+	GetComments(ctx context.Context, postID int, first *int, after *string,
+		sort model.SortOrder) (*model.CommentConnection, error)
+	GetCommentsByPostIDs(ctx context.Context, postIDs []int, first *int,
+		sort model.SortOrder) (map[int]*model.CommentConnection, error)
+	// GetReplies returns one page of replies to every given comment, keyed by the comment.
+	GetReplies(ctx context.Context, parentIDs []int, first *int, after *string,
+		depth int) (map[int]*model.CommentConnection, error)
+	GetReplyCounts(ctx context.Context, ids []int) (map[int]int, error)
+	GetCommentsByIDs(ctx context.Context, ids []int) (map[int]*model.Comment, error)
+	// GetCommentsSince returns the comments of a post created after since, a comment ID
+	// or an RFC 3339 timestamp, oldest first.
+	GetCommentsSince(ctx context.Context, postID int, since string) ([]*model.Comment, error)
 	CreateComment(ctx context.Context, input model.CreateCommentInput) (*model.Comment, error)
-	GetCommentsForPosts(ctx context.Context, postIDs []int) ([]*model.Comment, error)
+	UpdateComment(ctx context.Context, commentID int, content string) (*model.Comment, error)
+	GetRevisions(ctx context.Context, commentIDs []int) (map[int][]*model.CommentRevision, error)
+	// DeleteComment returns the tombstone left in place of a comment with replies,
+	// or nil if the comment was removed completely.
+	DeleteComment(ctx context.Context, commentID int) (*model.Comment, error)
+}
+
+type NotificationService interface {
+	// Notify tells the author of the parent comment and the users mentioned in a new comment about it.
+	Notify(ctx context.Context, comment *model.Comment) error
+	GetNotifications(ctx context.Context, first *int, after *string,
+		unreadOnly bool) (*model.NotificationConnection, error)
+	// MarkNotificationsRead marks the viewer's notifications read, all of them if ids is nil,
+	// and returns the number of notifications that were unread.
+	MarkNotificationsRead(ctx context.Context, ids []int) (int, error)
+}
+
+type MentionService interface {
+	// SetPostMentions resolves the users mentioned in the post content and saves them in place of
+	// the mentions of the previous content. Unknown usernames are left as plain text.
+	SetPostMentions(ctx context.Context, postID int, content string) error
+	SetCommentMentions(ctx context.Context, commentID int, content string) error
+	GetPostMentions(ctx context.Context, postIDs []int) (map[int][]*model.Mention, error)
+	GetCommentMentions(ctx context.Context, commentIDs []int) (map[int][]*model.Mention, error)
+}
+
+type ModerationService interface {
+	// IsModerator tells whether the viewer has the moderator role, anonymous viewers never do.
+	IsModerator(ctx context.Context) (bool, error)
+	ReportPost(ctx context.Context, postID int, reason string) error
+	ReportComment(ctx context.Context, commentID int, reason string) error
+	// FlagPost and FlagComment put the target on the queue with the reasons of the content filters
+	// that flagged it, no reasons mean nothing to flag.
+	FlagPost(ctx context.Context, postID int, flags []string) error
+	FlagComment(ctx context.Context, commentID int, flags []string) error
+	// GetQueue returns the open reports, oldest first.
+	GetQueue(ctx context.Context, first *int, after *string) (*model.ReportConnection, error)
+	// ModeratePost and ModerateComment apply the action and resolve the open reports of the target.
+	ModeratePost(ctx context.Context, postID int, action model.ModerationAction) error
+	ModerateComment(ctx context.Context, commentID int, action model.ModerationAction) error
+	// GrantModerator gives the moderator role to an existing user. It is an administrative step
+	// and checks no viewer.
+	GrantModerator(ctx context.Context, userID int) error
 }
 
 type UserService interface {
-	CreateUser(ctx context.Context, username string) (*model.User, error)
+	CreateUser(ctx context.Context, username, password string) (*model.User, error)
+	Login(ctx context.Context, username, password string) (*model.AuthPayload, error)
+	Refresh(ctx context.Context, refreshToken string) (*model.AuthPayload, error)
+	GetUsersByIDs(ctx context.Context, ids []int) (map[int]*model.User, error)
+}
+
+type ReactionService interface {
+	React(ctx context.Context, input model.ReactionInput) ([]*model.ReactionCount, error)
+	Unreact(ctx context.Context, input model.ReactionInput) ([]*model.ReactionCount, error)
+	GetPostReactions(ctx context.Context, postIDs []int) (map[int][]*model.ReactionCount, error)
+	GetCommentReactions(ctx context.Context, commentIDs []int) (map[int][]*model.ReactionCount, error)
+}
+
+type SearchService interface {
+	Search(ctx context.Context, text string, kinds []model.SearchKind, first *int,
+		after *string) (*model.SearchConnection, error)
 }
@@ -4,6 +4,7 @@ import (
 	"github.com/ilyakaznacheev/cleanenv"
 	"github.com/sirupsen/logrus"
 	"os"
+	"time"
 )
 
 type Server struct {
@@ -23,10 +24,102 @@ type Logger struct {
 	FileName string `yaml:"filename" env-required:"true"`
 }
 
+type Auth struct {
+	// Secret signs the tokens. It is taken from AUTH_SECRET and must be at least 32 bytes long.
+	Secret     string        `yaml:"secret" env:"AUTH_SECRET" env-required:"true"`
+	AccessTTL  time.Duration `yaml:"access_ttl" env-default:"15m"`
+	RefreshTTL time.Duration `yaml:"refresh_ttl" env-default:"720h"`
+}
+
+type Reactions struct {
+	Types    []string `yaml:"types" env-default:"like,dislike,heart,laugh"`
+	Negative []string `yaml:"negative" env-default:"dislike"`
+}
+
+type PubSub struct {
+	// Backend is "memory" for a single instance or "postgres" to deliver events between replicas.
+	Backend string `yaml:"backend" env-default:"memory"`
+	// Buffer is the number of events kept for a subscriber that falls behind.
+	Buffer int `yaml:"buffer" env-default:"10"`
+	// Overflow is what happens when the buffer is full: "drop_oldest", "disconnect" or "block".
+	Overflow string `yaml:"overflow" env-default:"drop_oldest"`
+	// BlockTimeout is how long the "block" policy waits for a subscriber before dropping the event.
+	BlockTimeout time.Duration `yaml:"block_timeout" env-default:"1s"`
+}
+
+type Presence struct {
+	// TTL is how long a viewer or a typing user is shown without a heartbeat.
+	TTL time.Duration `yaml:"ttl" env-default:"10s"`
+}
+
+type Markdown struct {
+	// CacheSize is the number of rendered contents kept in memory.
+	CacheSize int `yaml:"cache_size" env-default:"10000"`
+}
+
+type Moderation struct {
+	// ModeratorIDs are the registered users given the moderator role on startup.
+	ModeratorIDs []int `yaml:"moderator_ids"`
+	// Moderators are accounts registered on startup unless they exist and given the moderator role,
+	// the in-memory storage, empty on startup, gets its moderators this way.
+	Moderators []ModeratorAccount `yaml:"moderators"`
+}
+
+type ModeratorAccount struct {
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+type QueryLimits struct {
+	// MaxComplexity is the highest cost of an operation. Every field costs one and paginated
+	// fields multiply the cost of their items by first. Zero disables the limit.
+	MaxComplexity int `yaml:"max_complexity" env-default:"5000"`
+	// MaxDepth is how deeply the fields of an operation may be nested. Zero disables the limit.
+	MaxDepth int `yaml:"max_depth" env-default:"15"`
+}
+
+type Limit struct {
+	// Burst is how many operations a client may make at once.
+	Burst int `yaml:"burst"`
+	// Every is how often a client may make one more operation.
+	Every time.Duration `yaml:"every"`
+}
+
+type RateLimit struct {
+	// TrustProxy takes the client IP from X-Forwarded-For. Enable it only behind a proxy setting the header.
+	TrustProxy bool `yaml:"trust_proxy"`
+	// Limits by mutation or subscription field name, "subscriptions" applies to every subscription
+	// without a limit of its own.
+	Limits map[string]Limit `yaml:"limits"`
+}
+
+// Filter is one step of the content filter pipeline run on posts and comments.
+type Filter struct {
+	// Type is "banned_words", "links", "repeated_chars" or "max_length".
+	Type string `yaml:"type"`
+	// Action is "reject", "mask" or "flag".
+	Action string `yaml:"action"`
+	// Words are the banned words, matched as whole words regardless of case.
+	Words []string `yaml:"words"`
+	// Max is the number of links, repeats of a character in a row or characters allowed.
+	Max int `yaml:"max"`
+	// Targets are "posts" and "comments", the filter runs on both when none are set.
+	Targets []string `yaml:"targets"`
+}
+
 type Config struct {
-	Server   Server   `yaml:"server"`
-	Database Database `yaml:"database"`
-	Logger   Logger   `yaml:"logger"`
+	Server      Server      `yaml:"server"`
+	Database    Database    `yaml:"database"`
+	Logger      Logger      `yaml:"logger"`
+	Auth        Auth        `yaml:"auth"`
+	Reactions   Reactions   `yaml:"reactions"`
+	PubSub      PubSub      `yaml:"pubsub"`
+	Presence    Presence    `yaml:"presence"`
+	Markdown    Markdown    `yaml:"markdown"`
+	Moderation  Moderation  `yaml:"moderation"`
+	Filters     []Filter    `yaml:"filters"`
+	RateLimit   RateLimit   `yaml:"rate_limit"`
+	QueryLimits QueryLimits `yaml:"query_limits"`
 }
 
 func MustLoad() (*Config, error) {
@@ -0,0 +1,577 @@
+package contract
+
+import (
+	"Commentary/app"
+	"Commentary/internal/apperror"
+	"Commentary/internal/auth"
+	"Commentary/internal/common"
+	"Commentary/internal/config"
+	"Commentary/internal/graph"
+	"Commentary/internal/graph/model"
+	"Commentary/internal/loader"
+	"Commentary/internal/pagination"
+	"Commentary/internal/service"
+	"context"
+	"database/sql"
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// postgresDSN names the variable with the DSN of a disposable database for the PostgreSQL run,
+// its schema is dropped before every test.
+const postgresDSN = "CONTRACT_POSTGRES_DSN"
+
+func newConfig(storeInDB bool) *config.Config {
+	return &config.Config{
+		Database:  config.Database{StoreInDB: storeInDB},
+		Auth:      config.Auth{Secret: "a contract secret of at least 32 bytes", AccessTTL: time.Minute, RefreshTTL: time.Hour},
+		Reactions: config.Reactions{Types: []string{"like"}},
+		PubSub:    config.PubSub{Backend: "memory", Buffer: 10, Overflow: "drop_oldest", BlockTimeout: time.Second},
+		Presence:  config.Presence{TTL: time.Second},
+		Markdown:  config.Markdown{CacheSize: 10},
+	}
+}
+
+func newMemoryBackend(t *testing.T) *app.ServiceFactory {
+	return app.NewServiceFactory(newConfig(false), nil)
+}
+
+func newPostgresBackend(t *testing.T) *app.ServiceFactory {
+	dsn := os.Getenv(postgresDSN)
+	if dsn == "" {
+		t.Skipf("%s is not set", postgresDSN)
+	}
+	db, err := sql.Open("postgres", dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	_, err = db.Exec("DROP SCHEMA public CASCADE; CREATE SCHEMA public")
+	require.NoError(t, err)
+	migrations, err := filepath.Glob("../../migrations/*.up.sql")
+	require.NoError(t, err)
+	sort.Strings(migrations)
+	for _, migration := range migrations {
+		statements, err := os.ReadFile(migration)
+		require.NoError(t, err)
+		_, err = db.Exec(string(statements))
+		require.NoError(t, err, migration)
+	}
+
+	return app.NewServiceFactory(newConfig(true), db)
+}
+
+// The contract runs against both storages built by the service factory and checks the errors
+// by their kind in the domain taxonomy, as clients see them.
+func TestMemoryBackend(t *testing.T) {
+	runContract(t, newMemoryBackend)
+}
+
+func TestPostgresBackend(t *testing.T) {
+	runContract(t, newPostgresBackend)
+}
+
+func runContract(t *testing.T, newBackend func(t *testing.T) *app.ServiceFactory) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, factory *app.ServiceFactory)
+	}{
+		{"duplicate username", testDuplicateUsername},
+		{"missing post", testMissingPost},
+		{"invalid page", testInvalidPage},
+		{"reply", testReply},
+		{"replies of several comments", testRepliesOfSeveralComments},
+		{"viewer role within a request", testViewerRole},
+		{"reply to missing parent", testReplyToMissingParent},
+		{"reply to parent of another post", testReplyToParentOfAnotherPost},
+		{"edit of missing comment", testEditMissingComment},
+		{"moderator of missing user", testModeratorOfMissingUser},
+		{"hidden post", testHiddenPost},
+		{"hidden thread", testHiddenThread},
+		{"replay since a time in another zone", testSinceInAnotherZone},
+		{"notifications of hidden comments", testNotificationsOfHiddenComments},
+		{"post length", testPostLength},
+		{"comment length", testCommentLength},
+		{"username length", testUsernameLength},
+		{"title length", testTitleLength},
+		{"post deletion events", testPostDeletionEvents},
+		{"toggle comments of another author", testToggleCommentsOfAnotherAuthor},
+		{"comment revisions", testCommentRevisions},
+		{"delete deleted comment", testDeleteDeletedComment},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.run(t, newBackend(t))
+		})
+	}
+}
+
+func assertKind(t *testing.T, err error, kind apperror.Kind, field string) {
+	t.Helper()
+	require.Error(t, err)
+	domainErr := graph.Classify(err)
+	require.NotNil(t, domainErr, err.Error())
+	assert.Equal(t, kind, domainErr.Kind)
+	assert.Equal(t, field, domainErr.Field)
+}
+
+// newAuthor registers a user and returns a context acting on their behalf.
+func newAuthor(t *testing.T, factory *app.ServiceFactory, username string) context.Context {
+	user, err := factory.CreateUserService().CreateUser(context.Background(), username, "password")
+	require.NoError(t, err)
+	return auth.WithUserID(context.Background(), user.ID)
+}
+
+// newModerator registers a user, grants them the moderator role and returns a context acting on their behalf.
+func newModerator(t *testing.T, factory *app.ServiceFactory, username string) context.Context {
+	ctx := newAuthor(t, factory, username)
+	userID, _ := auth.UserIDFromContext(ctx)
+	require.NoError(t, factory.CreateModerationService().GrantModerator(context.Background(), userID))
+	return ctx
+}
+
+func newPost(t *testing.T, ctx context.Context, factory *app.ServiceFactory) *model.Post {
+	post, err := factory.CreatePostService().CreatePost(ctx,
+		model.CreatePostInput{Title: "title", Content: "content", Commentable: true})
+	require.NoError(t, err)
+	return post
+}
+
+func testDuplicateUsername(t *testing.T, factory *app.ServiceFactory) {
+	users := factory.CreateUserService()
+	_, err := users.CreateUser(context.Background(), "user", "password")
+	require.NoError(t, err)
+
+	_, err = users.CreateUser(context.Background(), "user", "password")
+	assertKind(t, err, apperror.Conflict, "username")
+}
+
+func testMissingPost(t *testing.T, factory *app.ServiceFactory) {
+	_, err := factory.CreatePostService().GetPost(context.Background(), 404)
+	assertKind(t, err, apperror.NotFound, "")
+}
+
+func testInvalidPage(t *testing.T, factory *app.ServiceFactory) {
+	posts := factory.CreatePostService()
+	first, after := -1, "not a cursor"
+
+	_, err := posts.GetPosts(context.Background(), &first, nil, model.SortOrderNewest)
+	assertKind(t, err, apperror.Validation, "first")
+	_, err = posts.GetPosts(context.Background(), nil, &after, model.SortOrderNewest)
+	assertKind(t, err, apperror.Validation, "after")
+	tooMany := pagination.MaxFirst + 1
+	_, err = posts.GetPosts(context.Background(), &tooMany, nil, model.SortOrderNewest)
+	assertKind(t, err, apperror.Validation, "first")
+}
+
+func testReply(t *testing.T, factory *app.ServiceFactory) {
+	ctx := newAuthor(t, factory, "author")
+	post := newPost(t, ctx, factory)
+	comments := factory.CreateCommentService()
+
+	root, err := comments.CreateComment(ctx, model.CreateCommentInput{PostID: post.ID, Content: "root"})
+	require.NoError(t, err)
+	reply, err := comments.CreateComment(ctx,
+		model.CreateCommentInput{PostID: post.ID, Content: "reply", Parent: &root.ID})
+	require.NoError(t, err)
+
+	replies, err := comments.GetReplies(ctx, []int{root.ID}, nil, nil, 1)
+	require.NoError(t, err)
+	require.Len(t, replies[root.ID].Edges, 1)
+	assert.Equal(t, reply.ID, replies[root.ID].Edges[0].Node.ID)
+}
+
+// countingComments counts the calls of GetReplies.
+type countingComments struct {
+	common.CommentService
+	calls atomic.Int32
+}
+
+func (c *countingComments) GetReplies(ctx context.Context, parentIDs []int, first *int, after *string,
+	depth int) (map[int]*model.CommentConnection, error) {
+	c.calls.Add(1)
+	return c.CommentService.GetReplies(ctx, parentIDs, first, after, depth)
+}
+
+func testRepliesOfSeveralComments(t *testing.T, factory *app.ServiceFactory) {
+	ctx := newAuthor(t, factory, "author")
+	post := newPost(t, ctx, factory)
+	comments := &countingComments{CommentService: factory.CreateCommentService()}
+
+	var roots []*model.Comment
+	for i := 0; i < 3; i++ {
+		root, err := comments.CreateComment(ctx, model.CreateCommentInput{PostID: post.ID, Content: "root"})
+		require.NoError(t, err)
+		roots = append(roots, root)
+		for j := 0; j <= i; j++ {
+			_, err = comments.CreateComment(ctx, model.CreateCommentInput{PostID: post.ID, Content: "reply",
+				Parent: &root.ID})
+			require.NoError(t, err)
+		}
+	}
+
+	loaders := loader.NewLoaders(factory.CreatePostService(), comments, factory.CreateUserService(),
+		factory.CreateReactionService(), factory.CreateMentionService())
+	first := 2
+	var wg sync.WaitGroup
+	connections := make([]*model.CommentConnection, len(roots))
+	for i, root := range roots {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var err error
+			connections[i], err = loaders.Replies.Load(ctx, loader.NewRepliesKey(root.ID, &first, nil, 1))
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), comments.calls.Load())
+	for i, connection := range connections {
+		assert.Len(t, connection.Edges, min(i+1, first))
+		assert.Equal(t, i+1 > first, connection.PageInfo.HasNextPage)
+		for _, edge := range connection.Edges {
+			assert.Equal(t, roots[i].ID, *edge.Node.ParentID)
+		}
+	}
+}
+
+func testViewerRole(t *testing.T, factory *app.ServiceFactory) {
+	ctx := newAuthor(t, factory, "author")
+	userID, _ := auth.UserIDFromContext(ctx)
+	moderation := factory.CreateModerationService()
+
+	request := service.WithViewerRole(ctx)
+	moderator, err := moderation.IsModerator(request)
+	require.NoError(t, err)
+	require.False(t, moderator)
+
+	require.NoError(t, moderation.GrantModerator(context.Background(), userID))
+	moderator, err = moderation.IsModerator(request)
+	require.NoError(t, err)
+	assert.False(t, moderator, "the role is looked up once per request")
+	moderator, err = moderation.IsModerator(service.WithViewerRole(ctx))
+	require.NoError(t, err)
+	assert.True(t, moderator)
+}
+
+func testReplyToMissingParent(t *testing.T, factory *app.ServiceFactory) {
+	ctx := newAuthor(t, factory, "author")
+	post := newPost(t, ctx, factory)
+	parentID := 404
+
+	_, err := factory.CreateCommentService().CreateComment(ctx,
+		model.CreateCommentInput{PostID: post.ID, Content: "reply", Parent: &parentID})
+	assertKind(t, err, apperror.NotFound, "parent")
+}
+
+func testReplyToParentOfAnotherPost(t *testing.T, factory *app.ServiceFactory) {
+	ctx := newAuthor(t, factory, "author")
+	first, second := newPost(t, ctx, factory), newPost(t, ctx, factory)
+	comments := factory.CreateCommentService()
+
+	parent, err := comments.CreateComment(ctx, model.CreateCommentInput{PostID: first.ID, Content: "parent"})
+	require.NoError(t, err)
+
+	_, err = comments.CreateComment(ctx,
+		model.CreateCommentInput{PostID: second.ID, Content: "reply", Parent: &parent.ID})
+	assertKind(t, err, apperror.NotFound, "parent")
+}
+
+func testEditMissingComment(t *testing.T, factory *app.ServiceFactory) {
+	ctx := newAuthor(t, factory, "author")
+
+	_, err := factory.CreateCommentService().UpdateComment(ctx, 404, "content")
+	assertKind(t, err, apperror.NotFound, "")
+}
+
+func testModeratorOfMissingUser(t *testing.T, factory *app.ServiceFactory) {
+	err := factory.CreateModerationService().GrantModerator(context.Background(), 404)
+	assertKind(t, err, apperror.NotFound, "")
+}
+
+func testHiddenPost(t *testing.T, factory *app.ServiceFactory) {
+	ctx := newAuthor(t, factory, "author")
+	moderatorCtx := newModerator(t, factory, "moderator")
+	post := newPost(t, ctx, factory)
+	posts := factory.CreatePostService()
+
+	require.NoError(t, factory.CreateModerationService().ModeratePost(moderatorCtx, post.ID,
+		model.ModerationActionHide))
+
+	_, err := posts.GetPost(ctx, post.ID)
+	assertKind(t, err, apperror.NotFound, "")
+	byID, err := posts.GetPostsByIDs(ctx, []int{post.ID})
+	require.NoError(t, err)
+	assert.Empty(t, byID)
+
+	hidden, err := posts.GetPost(moderatorCtx, post.ID)
+	require.NoError(t, err)
+	assert.True(t, hidden.Hidden)
+}
+
+func testHiddenThread(t *testing.T, factory *app.ServiceFactory) {
+	ctx := newAuthor(t, factory, "author")
+	moderatorCtx := newModerator(t, factory, "moderator")
+	post := newPost(t, ctx, factory)
+	comments, moderation := factory.CreateCommentService(), factory.CreateModerationService()
+	since := time.Now().Add(-time.Hour).Format(time.RFC3339)
+
+	root, err := comments.CreateComment(ctx, model.CreateCommentInput{PostID: post.ID, Content: "root"})
+	require.NoError(t, err)
+	reply, err := comments.CreateComment(ctx, model.CreateCommentInput{PostID: post.ID, Content: "reply", Parent: &root.ID})
+	require.NoError(t, err)
+
+	// the reply is found with its parent, if at all
+	visible := func(viewer context.Context, shown bool) {
+		t.Helper()
+		byID, err := comments.GetCommentsByIDs(viewer, []int{root.ID, reply.ID})
+		require.NoError(t, err)
+		missed, err := comments.GetCommentsSince(viewer, post.ID, since)
+		require.NoError(t, err)
+		hits, err := factory.CreateSearchService().Search(viewer, "reply", nil, nil, nil)
+		require.NoError(t, err)
+		assert.Equal(t, shown, len(byID) == 2 && len(missed) == 2 && len(hits.Edges) == 1)
+		assert.Equal(t, !shown, len(byID) == 0 && len(missed) == 0 && len(hits.Edges) == 0)
+	}
+
+	require.NoError(t, moderation.ModerateComment(moderatorCtx, root.ID, model.ModerationActionHide))
+	visible(ctx, false)
+	visible(moderatorCtx, true)
+
+	// only moderators reply to a hidden comment or to a reply under it
+	for _, parentID := range []int{root.ID, reply.ID} {
+		_, err = comments.CreateComment(ctx, model.CreateCommentInput{PostID: post.ID, Content: "reply", Parent: &parentID})
+		assertKind(t, err, apperror.NotFound, "parent")
+	}
+
+	require.NoError(t, moderation.ModerateComment(moderatorCtx, root.ID, model.ModerationActionRestore))
+	require.NoError(t, moderation.ModeratePost(moderatorCtx, post.ID, model.ModerationActionHide))
+	visible(ctx, false)
+	visible(moderatorCtx, true)
+	_, err = comments.CreateComment(moderatorCtx, model.CreateCommentInput{PostID: post.ID, Content: "reply", Parent: &reply.ID})
+	require.NoError(t, err)
+}
+
+func testSinceInAnotherZone(t *testing.T, factory *app.ServiceFactory) {
+	ctx := newAuthor(t, factory, "author")
+	post := newPost(t, ctx, factory)
+	comments := factory.CreateCommentService()
+
+	comment, err := comments.CreateComment(ctx, model.CreateCommentInput{PostID: post.ID, Content: "comment"})
+	require.NoError(t, err)
+
+	_, offset := comment.Created.In(time.Local).Zone()
+	zone := time.FixedZone("elsewhere", offset+5*60*60)
+	for _, tt := range []struct {
+		since    time.Time
+		replayed int
+	}{
+		{comment.Created.Add(-time.Second), 1},
+		{comment.Created.Add(time.Second), 0},
+	} {
+		missed, err := comments.GetCommentsSince(ctx, post.ID, tt.since.In(zone).Format(time.RFC3339Nano))
+		require.NoError(t, err)
+		assert.Len(t, missed, tt.replayed)
+	}
+}
+
+func testNotificationsOfHiddenComments(t *testing.T, factory *app.ServiceFactory) {
+	ctx := newAuthor(t, factory, "author")
+	otherCtx := newAuthor(t, factory, "other")
+	moderatorCtx := newModerator(t, factory, "moderator")
+	post := newPost(t, ctx, factory)
+	comments, moderation := factory.CreateCommentService(), factory.CreateModerationService()
+
+	root, err := comments.CreateComment(ctx, model.CreateCommentInput{PostID: post.ID, Content: "root"})
+	require.NoError(t, err)
+	reply, err := comments.CreateComment(otherCtx, model.CreateCommentInput{PostID: post.ID, Content: "reply",
+		Parent: &root.ID})
+	require.NoError(t, err)
+
+	notified := func(shown bool) {
+		t.Helper()
+		notifications, err := factory.CreateNotificationService().GetNotifications(ctx, nil, nil, false)
+		require.NoError(t, err)
+		if !shown {
+			assert.Empty(t, notifications.Edges)
+			return
+		}
+		require.Len(t, notifications.Edges, 1)
+		assert.Equal(t, reply.ID, notifications.Edges[0].Node.CommentID)
+	}
+
+	notified(true)
+	require.NoError(t, moderation.ModerateComment(moderatorCtx, reply.ID, model.ModerationActionHide))
+	notified(false)
+	require.NoError(t, moderation.ModerateComment(moderatorCtx, reply.ID, model.ModerationActionRestore))
+	require.NoError(t, moderation.ModeratePost(moderatorCtx, post.ID, model.ModerationActionHide))
+	notified(false)
+	require.NoError(t, moderation.ModeratePost(moderatorCtx, post.ID, model.ModerationActionRestore))
+	notified(true)
+
+	// a moderator's reply under a hidden comment notifies nobody, the author could not load it
+	require.NoError(t, moderation.ModerateComment(moderatorCtx, root.ID, model.ModerationActionHide))
+	_, err = comments.CreateComment(moderatorCtx, model.CreateCommentInput{PostID: post.ID, Content: "reply",
+		Parent: &root.ID})
+	require.NoError(t, err)
+	require.NoError(t, moderation.ModerateComment(moderatorCtx, root.ID, model.ModerationActionRestore))
+	notified(true)
+}
+
+func testPostLength(t *testing.T, factory *app.ServiceFactory) {
+	ctx := newAuthor(t, factory, "author")
+	posts := factory.CreatePostService()
+
+	_, err := posts.CreatePost(ctx, model.CreatePostInput{Title: "title", Content: strings.Repeat("я", 5000)})
+	require.NoError(t, err)
+	_, err = posts.CreatePost(ctx, model.CreatePostInput{Title: "title", Content: strings.Repeat("я", 5001)})
+	assertKind(t, err, apperror.Validation, "content")
+}
+
+func testCommentLength(t *testing.T, factory *app.ServiceFactory) {
+	ctx := newAuthor(t, factory, "author")
+	post := newPost(t, ctx, factory)
+	comments := factory.CreateCommentService()
+
+	_, err := comments.CreateComment(ctx, model.CreateCommentInput{PostID: post.ID, Content: strings.Repeat("я", 2000)})
+	require.NoError(t, err)
+	_, err = comments.CreateComment(ctx, model.CreateCommentInput{PostID: post.ID, Content: strings.Repeat("я", 2001)})
+	assertKind(t, err, apperror.Validation, "content")
+}
+
+func testCommentRevisions(t *testing.T, factory *app.ServiceFactory) {
+	ctx := newAuthor(t, factory, "author")
+	otherCtx := newAuthor(t, factory, "other")
+	moderatorCtx := newModerator(t, factory, "moderator")
+	post := newPost(t, ctx, factory)
+	comments, moderation := factory.CreateCommentService(), factory.CreateModerationService()
+
+	root, err := comments.CreateComment(ctx, model.CreateCommentInput{PostID: post.ID, Content: "v1"})
+	require.NoError(t, err)
+	_, err = comments.CreateComment(ctx, model.CreateCommentInput{PostID: post.ID, Content: "reply", Parent: &root.ID})
+	require.NoError(t, err)
+	root, err = comments.UpdateComment(ctx, root.ID, "v2")
+	require.NoError(t, err)
+
+	for _, viewer := range []struct {
+		ctx     context.Context
+		allowed bool
+	}{{ctx, true}, {moderatorCtx, true}, {otherCtx, false}, {context.Background(), false}} {
+		allowed, err := service.SeesRevisions(viewer.ctx, moderation, root)
+		require.NoError(t, err)
+		assert.Equal(t, viewer.allowed, allowed)
+	}
+	revisions, err := comments.GetRevisions(ctx, []int{root.ID})
+	require.NoError(t, err)
+	assert.Len(t, revisions[root.ID], 1)
+
+	_, err = comments.DeleteComment(ctx, root.ID)
+	require.NoError(t, err)
+	revisions, err = comments.GetRevisions(ctx, []int{root.ID})
+	require.NoError(t, err)
+	assert.Empty(t, revisions[root.ID])
+}
+
+func testDeleteDeletedComment(t *testing.T, factory *app.ServiceFactory) {
+	ctx := newAuthor(t, factory, "author")
+	post := newPost(t, ctx, factory)
+	comments := factory.CreateCommentService()
+
+	root, err := comments.CreateComment(ctx, model.CreateCommentInput{PostID: post.ID, Content: "root"})
+	require.NoError(t, err)
+	_, err = comments.CreateComment(ctx, model.CreateCommentInput{PostID: post.ID, Content: "reply", Parent: &root.ID})
+	require.NoError(t, err)
+
+	tombstone, err := comments.DeleteComment(ctx, root.ID)
+	require.NoError(t, err)
+	require.NotNil(t, tombstone)
+	_, err = comments.DeleteComment(ctx, root.ID)
+	assertKind(t, err, apperror.Conflict, "")
+
+	// the report of a comment a moderator failed to delete stays in the queue
+	moderatorCtx := newModerator(t, factory, "moderator")
+	moderation := factory.CreateModerationService()
+	require.NoError(t, moderation.ReportComment(ctx, root.ID, "spam"))
+	err = moderation.ModerateComment(moderatorCtx, root.ID, model.ModerationActionDelete)
+	assertKind(t, err, apperror.Conflict, "")
+	queue, err := moderation.GetQueue(moderatorCtx, nil, nil)
+	require.NoError(t, err)
+	assert.Len(t, queue.Edges, 1)
+}
+
+func testUsernameLength(t *testing.T, factory *app.ServiceFactory) {
+	users := factory.CreateUserService()
+
+	_, err := users.CreateUser(context.Background(), strings.Repeat("я", 20), "password")
+	require.NoError(t, err)
+	_, err = users.CreateUser(context.Background(), strings.Repeat("ю", 21), "password")
+	assertKind(t, err, apperror.Validation, "username")
+	for _, username := range []string{"", " \t "} {
+		_, err = users.CreateUser(context.Background(), username, "password")
+		assertKind(t, err, apperror.Validation, "username")
+	}
+}
+
+func testTitleLength(t *testing.T, factory *app.ServiceFactory) {
+	ctx := newAuthor(t, factory, "author")
+	posts := factory.CreatePostService()
+
+	_, err := posts.CreatePost(ctx, model.CreatePostInput{Title: strings.Repeat("я", 150), Content: "content"})
+	require.NoError(t, err)
+	_, err = posts.CreatePost(ctx, model.CreatePostInput{Title: strings.Repeat("я", 151), Content: "content"})
+	assertKind(t, err, apperror.Validation, "title")
+}
+
+func testPostDeletionEvents(t *testing.T, factory *app.ServiceFactory) {
+	ctx := newAuthor(t, factory, "author")
+	moderatorCtx := newModerator(t, factory, "moderator")
+	broker := factory.Broker()
+
+	for _, remove := range []func(postID int) error{
+		func(postID int) error {
+			_, err := factory.CreatePostService().DeletePost(ctx, postID)
+			return err
+		},
+		func(postID int) error {
+			return factory.CreateModerationService().ModeratePost(moderatorCtx, postID, model.ModerationActionDelete)
+		},
+	} {
+		post := newPost(t, ctx, factory)
+		sub := broker.Subscribe(post.ID, nil)
+
+		require.NoError(t, remove(post.ID))
+		select {
+		case event := <-sub.Events():
+			require.IsType(t, &model.PostDeleted{}, event)
+			assert.Equal(t, post.ID, event.(*model.PostDeleted).PostID)
+		case <-time.After(time.Second):
+			require.FailNow(t, "no deletion event")
+		}
+		broker.Unsubscribe(sub)
+	}
+}
+
+func testToggleCommentsOfAnotherAuthor(t *testing.T, factory *app.ServiceFactory) {
+	ctx := newAuthor(t, factory, "author")
+	otherCtx := newAuthor(t, factory, "other")
+	post := newPost(t, ctx, factory)
+	posts := factory.CreatePostService()
+
+	_, err := posts.ToggleComments(context.Background(), post.ID)
+	assertKind(t, err, apperror.Forbidden, "")
+	_, err = posts.ToggleComments(otherCtx, post.ID)
+	assertKind(t, err, apperror.Forbidden, "")
+
+	toggled, err := posts.ToggleComments(ctx, post.ID)
+	require.NoError(t, err)
+	assert.False(t, toggled.Commentable)
+}
@@ -9,8 +9,8 @@ import (
 	"time"
 )
 
-func InitDB(cfg *config.Config) (*sql.DB, error) {
-	dsn := fmt.Sprintf(
+func DSN(cfg *config.Config) string {
+	return fmt.Sprintf(
 		"host=%s dbname=%s port=%d user=%s password=%s sslmode=disable",
 		cfg.Database.Host,
 		cfg.Database.Name,
@@ -18,8 +18,10 @@ func InitDB(cfg *config.Config) (*sql.DB, error) {
 		cfg.Database.User,
 		cfg.Database.Password,
 	)
+}
 
-	DB, err := sql.Open("postgres", dsn)
+func InitDB(cfg *config.Config) (*sql.DB, error) {
+	DB, err := sql.Open("postgres", DSN(cfg))
 	if err != nil {
 		logrus.WithError(err).Fatal("error connecting to database")
 		return nil, err
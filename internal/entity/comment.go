@@ -2,11 +2,18 @@ package entity
 
 import "time"
 
+// DeletedCommentContent replaces the content of a deleted comment that still has replies.
+const DeletedCommentContent = "[deleted]"
+
 type Comment struct {
-	ID       int       `json:"id" db:"id"`
-	PostID   int       `json:"post" db:"post_id"`
-	AuthorID int       `json:"author" db:"author_id"`
-	Content  string    `json:"content" db:"content"`
-	Created  time.Time `json:"created" db:"created"`
-	ParentID *int      `json:"parent,omitempty" db:"parent_id"`
+	ID       int        `json:"id" db:"id"`
+	PostID   int        `json:"post" db:"post_id"`
+	AuthorID int        `json:"author" db:"author_id"`
+	Content  string     `json:"content" db:"content"`
+	Created  time.Time  `json:"created" db:"created"`
+	ParentID *int       `json:"parent,omitempty" db:"parent_id"`
+	Edited   *time.Time `json:"edited,omitempty" db:"edited"`
+	Deleted  bool       `json:"deleted" db:"deleted"`
+	Hidden   bool       `json:"hidden" db:"hidden"`
+	Score    float64    `json:"-" db:"-"`
 }
@@ -0,0 +1,10 @@
+package entity
+
+// Mention links an @username in the content of a post or a comment to the user.
+// Offset and Length count characters and cover the @ sign.
+type Mention struct {
+	Target ReactionTarget `json:"-"`
+	UserID int            `json:"user" db:"user_id"`
+	Offset int            `json:"offset" db:"start"`
+	Length int            `json:"length" db:"length"`
+}
@@ -0,0 +1,21 @@
+package entity
+
+import "time"
+
+type NotificationKind string
+
+const (
+	NotificationReply   NotificationKind = "reply"
+	NotificationMention NotificationKind = "mention"
+)
+
+// Notification tells a user about a comment replying to or mentioning them.
+type Notification struct {
+	ID        int              `json:"id" db:"id"`
+	UserID    int              `json:"user" db:"user_id"`
+	Kind      NotificationKind `json:"kind" db:"kind"`
+	CommentID int              `json:"comment" db:"comment_id"`
+	ActorID   int              `json:"actor" db:"actor_id"`
+	Created   time.Time        `json:"created" db:"created"`
+	Read      bool             `json:"read" db:"read"`
+}
@@ -0,0 +1,32 @@
+package entity
+
+import "time"
+
+type TargetKind string
+
+const (
+	TargetPost    TargetKind = "post"
+	TargetComment TargetKind = "comment"
+)
+
+// ReactionTarget points to a post or a comment.
+type ReactionTarget struct {
+	Kind TargetKind
+	ID   int
+}
+
+type Reaction struct {
+	ID      int            `json:"id" db:"id"`
+	UserID  int            `json:"user" db:"user_id"`
+	Target  ReactionTarget `json:"-"`
+	Type    string         `json:"type" db:"type"`
+	Created time.Time      `json:"created" db:"created"`
+}
+
+// ReactionCount aggregates reactions of one type left on a target.
+type ReactionCount struct {
+	TargetID      int
+	Type          string
+	Count         int
+	ViewerReacted bool
+}
@@ -0,0 +1,15 @@
+package entity
+
+import "time"
+
+// Report of a post or a comment waiting for a moderator. Reports are resolved together
+// when a moderator hides, restores or deletes their target. Reports made by content filters
+// have no reporter.
+type Report struct {
+	ID         int            `json:"id" db:"id"`
+	Target     ReactionTarget `json:"-"`
+	ReporterID *int           `json:"reporter,omitempty" db:"reporter_id"`
+	Reason     string         `json:"reason" db:"reason"`
+	Created    time.Time      `json:"created" db:"created"`
+	Resolved   bool           `json:"resolved" db:"resolved"`
+}
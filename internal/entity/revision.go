@@ -0,0 +1,12 @@
+package entity
+
+import "time"
+
+// CommentRevision keeps a content version of a comment that was replaced by an edit.
+type CommentRevision struct {
+	ID        int       `json:"id" db:"id"`
+	CommentID int       `json:"comment" db:"comment_id"`
+	Content   string    `json:"content" db:"content"`
+	EditorID  int       `json:"editor" db:"editor_id"`
+	Edited    time.Time `json:"edited" db:"edited"`
+}
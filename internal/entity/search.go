@@ -0,0 +1,15 @@
+package entity
+
+// SearchHit is a post or a comment matching a search query. Snippet is an HTML-escaped fragment
+// of its text with the matched words wrapped in SnippetStart and SnippetStop.
+type SearchHit struct {
+	Kind    TargetKind
+	ID      int
+	Rank    float64
+	Snippet string
+}
+
+const (
+	SnippetStart = "<mark>"
+	SnippetStop  = "</mark>"
+)
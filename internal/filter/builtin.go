@@ -0,0 +1,152 @@
+package filter
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	wordPattern = regexp.MustCompile(`[\p{L}\p{N}_]+`)
+	// linkPattern matches the links Markdown turns into anchors, explicit and autolinked ones.
+	linkPattern = regexp.MustCompile(`(?i)(?:https?://|ftp://|mailto:|www\.)[^\s<>()\[\]]+`)
+)
+
+// stars obscures every character of s.
+func stars(s string) string {
+	return strings.Repeat("*", utf8.RuneCountInString(s))
+}
+
+// BannedWords finds whole words of the list regardless of case.
+type BannedWords struct {
+	words map[string]struct{}
+}
+
+func NewBannedWords(words []string) *BannedWords {
+	set := make(map[string]struct{}, len(words))
+	for _, word := range words {
+		set[strings.ToLower(word)] = struct{}{}
+	}
+	return &BannedWords{words: set}
+}
+
+func (f *BannedWords) Check(content string) string {
+	for _, loc := range wordPattern.FindAllStringIndex(content, -1) {
+		if _, ok := f.words[strings.ToLower(content[loc[0]:loc[1]])]; ok {
+			return "content contains banned words"
+		}
+	}
+	return ""
+}
+
+func (f *BannedWords) Mask(content string) string {
+	return wordPattern.ReplaceAllStringFunc(content, func(word string) string {
+		if _, ok := f.words[strings.ToLower(word)]; ok {
+			return stars(word)
+		}
+		return word
+	})
+}
+
+// LinkLimit limits the number of links in the content.
+type LinkLimit struct {
+	max int
+}
+
+func NewLinkLimit(max int) *LinkLimit {
+	return &LinkLimit{max: max}
+}
+
+func (f *LinkLimit) Check(content string) string {
+	if len(linkPattern.FindAllStringIndex(content, f.max+1)) > f.max {
+		return fmt.Sprintf("content contains more than %d links", f.max)
+	}
+	return ""
+}
+
+// Mask keeps the first links and obscures the rest of them.
+func (f *LinkLimit) Mask(content string) string {
+	seen := 0
+	return linkPattern.ReplaceAllStringFunc(content, func(link string) string {
+		seen++
+		if seen > f.max {
+			return stars(link)
+		}
+		return link
+	})
+}
+
+// RepeatedChars limits how many times in a row a character may repeat, whitespace aside,
+// so that indentation of code is never spam.
+type RepeatedChars struct {
+	max int
+}
+
+func NewRepeatedChars(max int) *RepeatedChars {
+	return &RepeatedChars{max: max}
+}
+
+func (f *RepeatedChars) Check(content string) string {
+	var prev rune
+	run := 0
+	for _, r := range content {
+		if r == prev {
+			run++
+		} else {
+			prev, run = r, 1
+		}
+		if run > f.max && !unicode.IsSpace(r) {
+			return fmt.Sprintf("content repeats a character more than %d times in a row", f.max)
+		}
+	}
+	return ""
+}
+
+// Mask shortens the runs of a character to the limit.
+func (f *RepeatedChars) Mask(content string) string {
+	var masked strings.Builder
+	var prev rune
+	run := 0
+	for _, r := range content {
+		if r == prev {
+			run++
+		} else {
+			prev, run = r, 1
+		}
+		if run > f.max && !unicode.IsSpace(r) {
+			continue
+		}
+		masked.WriteRune(r)
+	}
+	return masked.String()
+}
+
+// MaxLength limits the number of characters in the content.
+type MaxLength struct {
+	max int
+}
+
+func NewMaxLength(max int) *MaxLength {
+	return &MaxLength{max: max}
+}
+
+func (f *MaxLength) Check(content string) string {
+	if utf8.RuneCountInString(content) > f.max {
+		return fmt.Sprintf("content exceeds the limitation of %d symbols", f.max)
+	}
+	return ""
+}
+
+// Mask cuts the content off at the limit.
+func (f *MaxLength) Mask(content string) string {
+	count := 0
+	for i := range content {
+		if count == f.max {
+			return content[:i]
+		}
+		count++
+	}
+	return content
+}
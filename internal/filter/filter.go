@@ -0,0 +1,143 @@
+package filter
+
+import (
+	"Commentary/internal/config"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// Action is what the pipeline does with content a filter objects to.
+type Action string
+
+const (
+	// ActionReject refuses to save the content.
+	ActionReject Action = "reject"
+	// ActionMask saves the content with the offending parts obscured.
+	ActionMask Action = "mask"
+	// ActionFlag saves the content as is and puts it on the moderation queue.
+	ActionFlag Action = "flag"
+)
+
+// Target is the kind of content a filter runs on.
+type Target string
+
+const (
+	TargetPosts    Target = "posts"
+	TargetComments Target = "comments"
+)
+
+var ErrRejected = errors.New("content rejected")
+
+// RejectedError tells the author why the content was refused.
+type RejectedError struct {
+	Reason string
+}
+
+func (e *RejectedError) Error() string {
+	return e.Reason
+}
+
+func (e *RejectedError) Unwrap() error {
+	return ErrRejected
+}
+
+// ContentFilter checks the content of posts and comments on creation and on edit.
+type ContentFilter interface {
+	// Check returns why the content violates the filter, or an empty string if it does not.
+	Check(content string) string
+	// Mask returns the content with the parts violating the filter obscured.
+	Mask(content string) string
+}
+
+type rule struct {
+	filter ContentFilter
+	action Action
+	// targets are the kinds of content the rule runs on, no targets mean all of them.
+	targets []Target
+}
+
+func (r rule) runsOn(target Target) bool {
+	return len(r.targets) == 0 || slices.Contains(r.targets, target)
+}
+
+// Pipeline runs the filters in the configured order.
+type Pipeline struct {
+	rules []rule
+}
+
+func NewPipeline(cfg []config.Filter) (*Pipeline, error) {
+	pipeline := &Pipeline{}
+	for _, filterCfg := range cfg {
+		filter, err := newFilter(filterCfg)
+		if err != nil {
+			return nil, err
+		}
+
+		action := Action(filterCfg.Action)
+		switch action {
+		case ActionReject, ActionMask, ActionFlag:
+		default:
+			return nil, fmt.Errorf("unknown action %q of filter %q", filterCfg.Action, filterCfg.Type)
+		}
+		var targets []Target
+		for _, target := range filterCfg.Targets {
+			switch Target(target) {
+			case TargetPosts, TargetComments:
+				targets = append(targets, Target(target))
+			default:
+				return nil, fmt.Errorf("unknown target %q of filter %q", target, filterCfg.Type)
+			}
+		}
+		pipeline.rules = append(pipeline.rules, rule{filter: filter, action: action, targets: targets})
+	}
+	return pipeline, nil
+}
+
+func newFilter(cfg config.Filter) (ContentFilter, error) {
+	switch cfg.Type {
+	case "banned_words":
+		return NewBannedWords(cfg.Words), nil
+	case "links":
+		return limited(cfg, NewLinkLimit(cfg.Max))
+	case "repeated_chars":
+		return limited(cfg, NewRepeatedChars(cfg.Max))
+	case "max_length":
+		return limited(cfg, NewMaxLength(cfg.Max))
+	}
+	return nil, fmt.Errorf("unknown filter %q", cfg.Type)
+}
+
+// limited returns the filter if its configured limit makes sense.
+func limited(cfg config.Filter, filter ContentFilter) (ContentFilter, error) {
+	if cfg.Max < 1 {
+		return nil, fmt.Errorf("filter %q needs a positive max", cfg.Type)
+	}
+	return filter, nil
+}
+
+// Apply runs the content of the target kind through the filters configured for it. It returns
+// the content to save, masked where needed, and the reasons of the filters that flagged it.
+// A rejecting filter stops the pipeline with a *RejectedError.
+func (p *Pipeline) Apply(target Target, content string) (string, []string, error) {
+	var flags []string
+	for _, rule := range p.rules {
+		if !rule.runsOn(target) {
+			continue
+		}
+		reason := rule.filter.Check(content)
+		if reason == "" {
+			continue
+		}
+
+		switch rule.action {
+		case ActionReject:
+			return "", nil, &RejectedError{Reason: reason}
+		case ActionMask:
+			content = rule.filter.Mask(content)
+		case ActionFlag:
+			flags = append(flags, reason)
+		}
+	}
+	return content, flags, nil
+}
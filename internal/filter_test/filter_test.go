@@ -0,0 +1,91 @@
+package filter
+
+import (
+	"Commentary/internal/config"
+	"Commentary/internal/filter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestBannedWords(t *testing.T) {
+	words := filter.NewBannedWords([]string{"spam", "Хлам"})
+
+	assert.NotEmpty(t, words.Check("Buy SPAM now"))
+	assert.NotEmpty(t, words.Check("это хлам"))
+	assert.Empty(t, words.Check("spammer and antispam"))
+	assert.Equal(t, "Buy **** now, ****!", words.Mask("Buy SPAM now, хлам!"))
+}
+
+func TestLinkLimit(t *testing.T) {
+	links := filter.NewLinkLimit(1)
+
+	assert.Empty(t, links.Check("see https://a.b"))
+	assert.NotEmpty(t, links.Check("see https://a.b and www.c.d"))
+	assert.Equal(t, "see https://a.b and *******", links.Mask("see https://a.b and www.c.d"))
+}
+
+func TestRepeatedChars(t *testing.T) {
+	repeated := filter.NewRepeatedChars(3)
+
+	assert.Empty(t, repeated.Check("wow!!!"))
+	assert.Empty(t, repeated.Check("code:\n        indented"))
+	assert.NotEmpty(t, repeated.Check("wow!!!!"))
+	assert.Equal(t, "wooo!!!", repeated.Mask("woooooo!!!!!!"))
+}
+
+func TestMaxLength(t *testing.T) {
+	length := filter.NewMaxLength(5)
+
+	assert.Empty(t, length.Check("пятьб"))
+	assert.Equal(t, "content exceeds the limitation of 5 symbols", length.Check("шесть!"))
+	assert.Equal(t, "шесть", length.Mask("шесть!"))
+}
+
+func TestPipeline(t *testing.T) {
+	pipeline, err := filter.NewPipeline([]config.Filter{
+		{Type: "banned_words", Action: "mask", Words: []string{"spam"}},
+		{Type: "links", Action: "flag", Max: 1},
+		{Type: "max_length", Action: "reject", Max: 40},
+	})
+	require.NoError(t, err)
+
+	content, flags, err := pipeline.Apply(filter.TargetComments, "spam at https://a.b and https://c.d")
+	require.NoError(t, err)
+	assert.Equal(t, "**** at https://a.b and https://c.d", content)
+	assert.Equal(t, []string{"content contains more than 1 links"}, flags)
+
+	_, _, err = pipeline.Apply(filter.TargetPosts, strings.Repeat("a", 41))
+	assert.ErrorIs(t, err, filter.ErrRejected)
+	assert.EqualError(t, err, "content exceeds the limitation of 40 symbols")
+}
+
+func TestPipelineTargets(t *testing.T) {
+	pipeline, err := filter.NewPipeline([]config.Filter{
+		{Type: "max_length", Action: "reject", Max: 5, Targets: []string{"comments"}},
+		{Type: "banned_words", Action: "mask", Words: []string{"spam"}, Targets: []string{"posts"}},
+	})
+	require.NoError(t, err)
+
+	content, _, err := pipeline.Apply(filter.TargetPosts, "long spam post")
+	require.NoError(t, err)
+	assert.Equal(t, "long **** post", content)
+
+	_, _, err = pipeline.Apply(filter.TargetComments, "long spam comment")
+	assert.ErrorIs(t, err, filter.ErrRejected)
+}
+
+func TestPipelineConfigErrors(t *testing.T) {
+	_, err := filter.NewPipeline([]config.Filter{{Type: "profanity", Action: "reject"}})
+	assert.Error(t, err)
+
+	_, err = filter.NewPipeline([]config.Filter{{Type: "links", Action: "delete", Max: 1}})
+	assert.Error(t, err)
+
+	_, err = filter.NewPipeline([]config.Filter{{Type: "max_length", Action: "reject"}})
+	assert.Error(t, err)
+
+	_, err = filter.NewPipeline([]config.Filter{{Type: "links", Action: "flag", Max: 1, Targets: []string{"users"}}})
+	assert.Error(t, err)
+}
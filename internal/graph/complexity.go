@@ -0,0 +1,41 @@
+package graph
+
+import (
+	"Commentary/internal/graph/model"
+	"Commentary/internal/pagination"
+)
+
+// NewComplexity weighs paginated fields by the number of items they may return: the cost of
+// one item is multiplied by first, or by the default page size the services return without it.
+func NewComplexity() ComplexityRoot {
+	list := func(childComplexity int, first *int) int {
+		size := pagination.DefaultFirst
+		if first != nil {
+			size = *first
+		}
+		return 1 + childComplexity*max(size, 1)
+	}
+
+	var complexity ComplexityRoot
+	complexity.Query.Posts = func(childComplexity int, first *int, after *string, sort model.SortOrder) int {
+		return list(childComplexity, first)
+	}
+	complexity.Query.Search = func(childComplexity int, text string, kinds []model.SearchKind, first *int,
+		after *string) int {
+		return list(childComplexity, first)
+	}
+	complexity.Query.Notifications = func(childComplexity int, first *int, after *string, unreadOnly bool) int {
+		return list(childComplexity, first)
+	}
+	complexity.Query.ModerationQueue = func(childComplexity int, first *int, after *string) int {
+		return list(childComplexity, first)
+	}
+	complexity.Post.Comments = func(childComplexity int, first *int, after *string, sort model.SortOrder) int {
+		return list(childComplexity, first)
+	}
+	// a page of replies holds first of them whatever the depth is
+	complexity.Comment.Replies = func(childComplexity int, first *int, after *string, depth int) int {
+		return list(childComplexity, first)
+	}
+	return complexity
+}
@@ -0,0 +1,102 @@
+package graph
+
+import (
+	"Commentary/internal/apperror"
+	"Commentary/internal/auth"
+	"Commentary/internal/filter"
+	"Commentary/internal/loader"
+	"Commentary/internal/pagination"
+	"Commentary/internal/pubsub"
+	"Commentary/internal/repo"
+	"Commentary/internal/repo/pgdb"
+	"Commentary/internal/service"
+	"context"
+	"errors"
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/errcode"
+	"github.com/sirupsen/logrus"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// errorKinds maps the errors of the storages and the services to the domain taxonomy,
+// field is the input the error is caused by.
+var errorKinds = []struct {
+	kind  apperror.Kind
+	field string
+	errs  []error
+}{
+	{apperror.NotFound, "", []error{repo.ErrUserNotFound, repo.ErrPostNotFound, repo.ErrCommentNotFound,
+		repo.ErrReactionTargetNotFound, loader.ErrNotFound}},
+	{apperror.NotFound, "parent", []error{repo.ErrParentNotFound}},
+	{apperror.Forbidden, "", []error{auth.ErrUnauthenticated, auth.ErrInvalidToken, auth.ErrExpiredToken,
+		auth.ErrInvalidCredentials, service.ErrNotAuthor, service.ErrNotModerator, service.ErrCommentsDisabled}},
+	{apperror.Conflict, "username", []error{repo.ErrUserAlreadyExists}},
+	{apperror.Validation, "username", []error{service.ErrEmptyUsername, service.ErrUsernameTooLong}},
+	{apperror.Conflict, "", []error{service.ErrCommentDeleted, repo.ErrCommentDeleted}},
+	{apperror.SlowConsumer, "", []error{pubsub.ErrSlowConsumer}},
+	{apperror.Validation, "password", []error{auth.ErrWeakPassword, auth.ErrLongPassword}},
+	{apperror.Validation, "content", []error{filter.ErrRejected, service.ErrPostTooLong,
+		service.ErrCommentTooLong}},
+	{apperror.Validation, "title", []error{service.ErrTitleTooLong}},
+	{apperror.Validation, "first", []error{pagination.ErrInvalidFirst}},
+	{apperror.Validation, "after", []error{pagination.ErrInvalidCursor}},
+	{apperror.Validation, "since", []error{pagination.ErrInvalidSince, service.ErrReplayTooLong}},
+	{apperror.Validation, "depth", []error{service.ErrWrongDepth}},
+	{apperror.Validation, "text", []error{service.ErrEmptySearch}},
+	{apperror.Validation, "reason", []error{service.ErrEmptyReason, service.ErrReasonTooLong}},
+	{apperror.Validation, "type", []error{service.ErrUnknownReaction}},
+	{apperror.Validation, "input", []error{service.ErrEmptyUpdate, service.ErrInvalidReactionTarget}},
+}
+
+// Classify maps err to the domain taxonomy. Repository errors are reduced to their cause, so
+// that queries never reach clients, and the ones without a known cause are internal.
+// Errors outside the taxonomy give nil.
+func Classify(err error) *apperror.Error {
+	var domainErr *apperror.Error
+	if errors.As(err, &domainErr) {
+		return domainErr
+	}
+
+	message := err.Error()
+	var repoErr *pgdb.RepositoryError
+	if errors.As(err, &repoErr) && repoErr.Err != nil {
+		message = repoErr.Err.Error()
+	}
+	for _, rule := range errorKinds {
+		for _, target := range rule.errs {
+			if errors.Is(err, target) {
+				return apperror.New(rule.kind, rule.field, message, err)
+			}
+		}
+	}
+
+	if repoErr != nil {
+		return apperror.New(apperror.Internal, "", apperror.InternalMessage, err)
+	}
+	return nil
+}
+
+// ErrorPresenter puts the error code and the offending field into extensions. Errors created by
+// gqlgen and the handler extensions without a cause are already meant for clients, any other
+// error outside the taxonomy is internal: it is logged and the client gets a generic message.
+func ErrorPresenter(ctx context.Context, err error) *gqlerror.Error {
+	gqlErr := graphql.DefaultErrorPresenter(ctx, err)
+	if gqlErr.Err == nil {
+		return gqlErr
+	}
+
+	domainErr := Classify(gqlErr.Err)
+	if domainErr == nil {
+		domainErr = apperror.New(apperror.Internal, "", apperror.InternalMessage, gqlErr.Err)
+	}
+	if domainErr.Kind == apperror.Internal {
+		logrus.WithError(gqlErr.Err).Error("internal error")
+	}
+
+	gqlErr.Message = domainErr.Message
+	errcode.Set(gqlErr, string(domainErr.Kind))
+	if domainErr.Field != "" {
+		gqlErr.Extensions["field"] = domainErr.Field
+	}
+	return gqlErr
+}
@@ -0,0 +1,180 @@
+package graph
+
+import (
+	"Commentary/internal/graph/model"
+	"Commentary/internal/pubsub"
+	"Commentary/internal/service"
+	"context"
+	"github.com/sirupsen/logrus"
+)
+
+// subscribe subscribes to the post's events until the subscription context is done. The deletion
+// of the post passes every filter, so that the subscription ends with it.
+func subscribe(ctx context.Context, broker pubsub.Broker, postID int, filter pubsub.Filter) *pubsub.Subscription {
+	sub := broker.Subscribe(postID, func(event model.Event) bool {
+		return postDeleted(event) || filter == nil || filter(event)
+	})
+	pubsub.Track(ctx, sub)
+
+	go func() {
+		<-ctx.Done()
+		broker.Unsubscribe(sub)
+		logrus.Debugf("unsubscribed from postID %v", postID)
+	}()
+
+	return sub
+}
+
+// postDeleted tells whether the event is the deletion of the subscribed post.
+func postDeleted(event model.Event) bool {
+	_, ok := event.(*model.PostDeleted)
+	return ok
+}
+
+// forward converts the events of the subscription into the values sent to the client,
+// the events convert rejects are not sent. The values end with the deletion of the post.
+func forward[T any](ctx context.Context, sub *pubsub.Subscription, convert func(event model.Event) (T, bool)) <-chan T {
+	values := make(chan T)
+	go func() {
+		defer close(values)
+		for event := range sub.Events() {
+			if postDeleted(event) {
+				return
+			}
+			value, ok := convert(event)
+			if !ok {
+				continue
+			}
+			select {
+			case values <- value:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return values
+}
+
+// maxQueuedLive is the number of live comments read ahead while the missed ones are replayed.
+const maxQueuedLive = service.MaxReplay
+
+// replay sends the missed comments and then the live ones of the subscription, skipping
+// the live comments already replayed, until the post is deleted. While the missed comments are sent, live ones are read
+// into a queue behind them, so that they do not pile up in the subscription buffer, where
+// the overflow policy would drop them or disconnect the client.
+func replay(ctx context.Context, sub *pubsub.Subscription, missed []*model.Comment) <-chan *model.Comment {
+	comments := make(chan *model.Comment)
+	go func() {
+		defer close(comments)
+		replayed := make(map[int]struct{}, len(missed))
+		for _, comment := range missed {
+			replayed[comment.ID] = struct{}{}
+		}
+
+		queue, replaying := missed, len(missed)
+		events := sub.Events()
+		for {
+			var out chan<- *model.Comment
+			var next *model.Comment
+			in := events
+			if len(queue) > 0 {
+				out, next = comments, queue[0]
+				// once the replay is over, the queue is emptied before reading on,
+				// so that slow clients are left to the overflow policy again
+				if replaying == 0 || len(queue)-replaying >= maxQueuedLive {
+					in = nil
+				}
+			} else if events == nil {
+				return
+			}
+
+			select {
+			case out <- next:
+				queue = queue[1:]
+				if replaying > 0 {
+					replaying--
+				}
+			case event, ok := <-in:
+				if !ok {
+					events = nil
+					continue
+				}
+				if postDeleted(event) {
+					return
+				}
+				comment := event.(*model.CommentCreated).Comment
+				if _, ok := replayed[comment.ID]; !ok {
+					queue = append(queue, comment)
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return comments
+}
+
+// inThread reports whether the event concerns a direct reply to the parent comment,
+// nil parent matches every event. Toggling comments concerns every thread of the post.
+func inThread(event model.CommentEvent, parentID *int) bool {
+	if parentID == nil {
+		return true
+	}
+	if _, ok := event.(*model.CommentsToggled); ok {
+		return true
+	}
+
+	eventParentID := parentOf(event)
+	return eventParentID != nil && *eventParentID == *parentID
+}
+
+// hiddenThread tells whether the event concerns a reply under a hidden comment.
+func hiddenThread(event model.CommentEvent) bool {
+	switch e := event.(type) {
+	case *model.CommentCreated:
+		return e.HiddenThread
+	case *model.CommentUpdated:
+		return e.HiddenThread
+	case *model.CommentDeleted:
+		return e.HiddenThread
+	default:
+		return false
+	}
+}
+
+// parentOf returns the parent of the comment the event concerns, nil for root comments
+// and events concerning the whole post.
+func parentOf(event model.CommentEvent) *int {
+	switch e := event.(type) {
+	case *model.CommentCreated:
+		return e.Comment.ParentID
+	case *model.CommentUpdated:
+		return e.Comment.ParentID
+	case *model.CommentDeleted:
+		return e.ParentID
+	default:
+		return nil
+	}
+}
+
+// visibleEvent hides from non-moderators the comments a moderator has just hidden,
+// they see such a comment deleted instead. The events of replies under a hidden comment
+// are not sent to them at all.
+func visibleEvent(event model.CommentEvent, moderator bool) (model.CommentEvent, bool) {
+	if moderator {
+		return event, true
+	}
+	if hiddenThread(event) {
+		return nil, false
+	}
+
+	updated, ok := event.(*model.CommentUpdated)
+	if !ok || !updated.Comment.Hidden {
+		return event, true
+	}
+	return &model.CommentDeleted{
+		Sequence:  updated.Sequence,
+		CommentID: updated.Comment.ID,
+		ParentID:  updated.Comment.ParentID,
+	}, true
+}
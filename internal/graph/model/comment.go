@@ -5,11 +5,14 @@ import (
 )
 
 type Comment struct {
-	ID      int        `json:"id"`
-	Post    *Post      `json:"post"`
-	Author  *User      `json:"author"`
-	Content string     `json:"content"`
-	Created time.Time  `json:"created"`
-	Parent  *Comment   `json:"parent,omitempty"`
-	Replies []*Comment `json:"replies"`
+	ID       int        `json:"id"`
+	PostID   int        `json:"-"`
+	AuthorID int        `json:"-"`
+	Content  string     `json:"content"`
+	Created  time.Time  `json:"created"`
+	ParentID *int       `json:"-"`
+	EditedAt *time.Time `json:"editedAt,omitempty"`
+	Deleted  bool       `json:"deleted"`
+	Hidden   bool       `json:"hidden"`
+	Score    float64    `json:"-"`
 }
@@ -0,0 +1,157 @@
+package model
+
+// Event is anything the broker delivers to subscribers.
+type Event interface {
+	GetSequence() int
+	// WithSequence returns a copy of the event numbered for one subscription.
+	WithSequence(sequence int) Event
+}
+
+type CommentEvent interface {
+	Event
+	IsCommentEvent()
+}
+
+// HiddenThread of the comment events tells whether a comment above the one concerned, or its post,
+// is hidden. It is worked out once when the event is published, subscribers who are not moderators
+// get none of such events.
+type CommentCreated struct {
+	Sequence     int      `json:"sequence"`
+	Comment      *Comment `json:"comment"`
+	HiddenThread bool     `json:"-"`
+}
+
+type CommentUpdated struct {
+	Sequence     int      `json:"sequence"`
+	Comment      *Comment `json:"comment"`
+	HiddenThread bool     `json:"-"`
+}
+
+// CommentDeleted carries the tombstone left in place of the comment,
+// Tombstone is nil if the comment was removed completely.
+type CommentDeleted struct {
+	Sequence     int      `json:"sequence"`
+	CommentID    int      `json:"commentID"`
+	ParentID     *int     `json:"parentID,omitempty"`
+	Tombstone    *Comment `json:"tombstone,omitempty"`
+	HiddenThread bool     `json:"-"`
+}
+
+type CommentsToggled struct {
+	Sequence    int  `json:"sequence"`
+	PostID      int  `json:"-"`
+	Commentable bool `json:"commentable"`
+}
+
+func (CommentCreated) IsCommentEvent() {}
+
+func (e CommentCreated) GetSequence() int { return e.Sequence }
+
+func (e CommentCreated) WithSequence(sequence int) Event {
+	e.Sequence = sequence
+	return &e
+}
+
+func (CommentUpdated) IsCommentEvent() {}
+
+func (e CommentUpdated) GetSequence() int { return e.Sequence }
+
+func (e CommentUpdated) WithSequence(sequence int) Event {
+	e.Sequence = sequence
+	return &e
+}
+
+func (CommentDeleted) IsCommentEvent() {}
+
+func (e CommentDeleted) GetSequence() int { return e.Sequence }
+
+func (e CommentDeleted) WithSequence(sequence int) Event {
+	e.Sequence = sequence
+	return &e
+}
+
+func (CommentsToggled) IsCommentEvent() {}
+
+func (e CommentsToggled) GetSequence() int { return e.Sequence }
+
+func (e CommentsToggled) WithSequence(sequence int) Event {
+	e.Sequence = sequence
+	return &e
+}
+
+// PostCreated is published to the feed of all posts.
+type PostCreated struct {
+	Sequence int
+	Post     *Post
+}
+
+// PostUpdated is published to the post when its comments are toggled or its content is edited.
+type PostUpdated struct {
+	Sequence int
+	Post     *Post
+}
+
+// PostDeleted is published to the deleted post, it ends the subscriptions to the post.
+type PostDeleted struct {
+	Sequence int
+	PostID   int
+}
+
+// NotificationAdded is published to the topic of the notified user.
+type NotificationAdded struct {
+	Sequence     int
+	Notification *Notification
+}
+
+// PresenceShared is published to the post by every replica with the presence of its own viewers,
+// on changes and as a heartbeat, for the other replicas to count them in.
+type PresenceShared struct {
+	Sequence int
+	Replica  string
+	Viewers  int
+	Typing   []int
+}
+
+func (e PostCreated) GetSequence() int { return e.Sequence }
+
+func (e PostCreated) WithSequence(sequence int) Event {
+	e.Sequence = sequence
+	return &e
+}
+
+func (e PostUpdated) GetSequence() int { return e.Sequence }
+
+func (e PostUpdated) WithSequence(sequence int) Event {
+	e.Sequence = sequence
+	return &e
+}
+
+func (e PostDeleted) GetSequence() int { return e.Sequence }
+
+func (e PostDeleted) WithSequence(sequence int) Event {
+	e.Sequence = sequence
+	return &e
+}
+
+func (e NotificationAdded) GetSequence() int { return e.Sequence }
+
+func (e NotificationAdded) WithSequence(sequence int) Event {
+	e.Sequence = sequence
+	return &e
+}
+
+func (e PresenceShared) GetSequence() int { return e.Sequence }
+
+func (e PresenceShared) WithSequence(sequence int) Event {
+	e.Sequence = sequence
+	return &e
+}
+
+// SubscriptionStats counts events handled by the broker of this instance.
+type SubscriptionStats struct {
+	Subscribers  int `json:"subscribers"`
+	Published    int `json:"published"`
+	Delivered    int `json:"delivered"`
+	Dropped      int `json:"dropped"`
+	Disconnected int `json:"disconnected"`
+}
@@ -0,0 +1,8 @@
+package model
+
+// Mention of a user in a post or a comment, offset and length count characters and cover the @ sign.
+type Mention struct {
+	UserID int `json:"-"`
+	Offset int `json:"offset"`
+	Length int `json:"length"`
+}
@@ -6,10 +6,12 @@ import (
 
 type Post struct {
 	ID          int        `json:"id"`
-	Author      *User      `json:"author"`
+	AuthorID    int        `json:"-"`
 	Title       string     `json:"title"`
 	Content     string     `json:"content"`
 	Created     time.Time  `json:"created"`
 	Commentable bool       `json:"commentable"`
-	Comments    []*Comment `json:"comments"`
+	EditedAt    *time.Time `json:"editedAt,omitempty"`
+	Hidden      bool       `json:"hidden"`
+	Score       float64    `json:"-"`
 }
@@ -0,0 +1,7 @@
+package model
+
+// Presence of a post, typing users are resolved from TypingIDs.
+type Presence struct {
+	Viewers   int   `json:"viewers"`
+	TypingIDs []int `json:"-"`
+}
@@ -0,0 +1,13 @@
+package model
+
+type ReactionCount struct {
+	Type          string `json:"type"`
+	Count         int    `json:"count"`
+	ViewerReacted bool   `json:"viewerReacted"`
+}
+
+type ReactionInput struct {
+	PostID    *int   `json:"postID,omitempty"`
+	CommentID *int   `json:"commentID,omitempty"`
+	Type      string `json:"type"`
+}
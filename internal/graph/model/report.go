@@ -0,0 +1,72 @@
+package model
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+type ModerationAction string
+
+const (
+	ModerationActionHide    ModerationAction = "HIDE"
+	ModerationActionRestore ModerationAction = "RESTORE"
+	ModerationActionDelete  ModerationAction = "DELETE"
+)
+
+var AllModerationAction = []ModerationAction{
+	ModerationActionHide,
+	ModerationActionRestore,
+	ModerationActionDelete,
+}
+
+func (e ModerationAction) IsValid() bool {
+	switch e {
+	case ModerationActionHide, ModerationActionRestore, ModerationActionDelete:
+		return true
+	}
+	return false
+}
+
+func (e ModerationAction) String() string {
+	return string(e)
+}
+
+func (e *ModerationAction) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = ModerationAction(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid ModerationAction", str)
+	}
+	return nil
+}
+
+func (e ModerationAction) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+// Report is resolved into its target and reporter through loaders, exactly one of PostID and CommentID is set.
+// ReporterID is nil for reports made by content filters.
+type Report struct {
+	ID         int       `json:"id"`
+	PostID     *int      `json:"-"`
+	CommentID  *int      `json:"-"`
+	ReporterID *int      `json:"-"`
+	Reason     string    `json:"reason"`
+	Created    time.Time `json:"created"`
+}
+
+type ReportEdge struct {
+	Cursor string  `json:"cursor"`
+	Node   *Report `json:"node"`
+}
+
+type ReportConnection struct {
+	Edges    []*ReportEdge `json:"edges"`
+	PageInfo *PageInfo     `json:"pageInfo"`
+}
@@ -0,0 +1,13 @@
+package model
+
+import (
+	"time"
+)
+
+type CommentRevision struct {
+	ID        int       `json:"id"`
+	CommentID int       `json:"-"`
+	EditorID  int       `json:"-"`
+	Content   string    `json:"content"`
+	Edited    time.Time `json:"edited"`
+}
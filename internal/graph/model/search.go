@@ -0,0 +1,68 @@
+package model
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+)
+
+type SearchKind string
+
+const (
+	SearchKindPost    SearchKind = "POST"
+	SearchKindComment SearchKind = "COMMENT"
+)
+
+var AllSearchKind = []SearchKind{
+	SearchKindPost,
+	SearchKindComment,
+}
+
+func (e SearchKind) IsValid() bool {
+	switch e {
+	case SearchKindPost, SearchKindComment:
+		return true
+	}
+	return false
+}
+
+func (e SearchKind) String() string {
+	return string(e)
+}
+
+func (e *SearchKind) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = SearchKind(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid SearchKind", str)
+	}
+	return nil
+}
+
+func (e SearchKind) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type SearchResult interface {
+	IsSearchResult()
+}
+
+func (Post) IsSearchResult() {}
+
+func (Comment) IsSearchResult() {}
+
+type SearchEdge struct {
+	Cursor  string     `json:"cursor"`
+	Snippet string     `json:"snippet"`
+	Kind    SearchKind `json:"-"`
+	ID      int        `json:"-"`
+}
+
+type SearchConnection struct {
+	Edges    []*SearchEdge `json:"edges"`
+	PageInfo *PageInfo     `json:"pageInfo"`
+}
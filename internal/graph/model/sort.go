@@ -0,0 +1,54 @@
+package model
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+)
+
+type SortOrder string
+
+const (
+	SortOrderNewest        SortOrder = "NEWEST"
+	SortOrderOldest        SortOrder = "OLDEST"
+	SortOrderTop           SortOrder = "TOP"
+	SortOrderControversial SortOrder = "CONTROVERSIAL"
+	SortOrderHot           SortOrder = "HOT"
+)
+
+var AllSortOrder = []SortOrder{
+	SortOrderNewest,
+	SortOrderOldest,
+	SortOrderTop,
+	SortOrderControversial,
+	SortOrderHot,
+}
+
+func (e SortOrder) IsValid() bool {
+	switch e {
+	case SortOrderNewest, SortOrderOldest, SortOrderTop, SortOrderControversial, SortOrderHot:
+		return true
+	}
+	return false
+}
+
+func (e SortOrder) String() string {
+	return string(e)
+}
+
+func (e *SortOrder) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = SortOrder(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid SortOrder", str)
+	}
+	return nil
+}
+
+func (e SortOrder) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
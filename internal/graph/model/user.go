@@ -4,3 +4,9 @@ type User struct {
 	ID       int    `json:"id"`
 	Username string `json:"username"`
 }
+
+type AuthPayload struct {
+	AccessToken  string `json:"accessToken"`
+	RefreshToken string `json:"refreshToken"`
+	User         *User  `json:"user"`
+}
@@ -2,6 +2,8 @@ package graph
 
 import (
 	"Commentary/internal/common"
+	"Commentary/internal/markdown"
+	"Commentary/internal/presence"
 	"Commentary/internal/pubsub"
 )
 
@@ -10,18 +12,32 @@ import (
 // It serves as dependency injection for your app, add any dependencies you require here.
 
 type Resolver struct {
-	PostService    common.PostService
-	CommentService common.CommentService
-	UserService    common.UserService
-	broker         *pubsub.Broker
+	PostService         common.PostService
+	CommentService      common.CommentService
+	UserService         common.UserService
+	ReactionService     common.ReactionService
+	SearchService       common.SearchService
+	NotificationService common.NotificationService
+	ModerationService   common.ModerationService
+	broker              pubsub.Broker
+	presence            *presence.Tracker
+	markdown            *markdown.Renderer
 }
 
 func NewResolver(PostService common.PostService, CommentService common.CommentService,
-	UserService common.UserService, broker *pubsub.Broker) *Resolver {
+	UserService common.UserService, ReactionService common.ReactionService, SearchService common.SearchService,
+	NotificationService common.NotificationService, ModerationService common.ModerationService,
+	broker pubsub.Broker, presence *presence.Tracker, markdown *markdown.Renderer) *Resolver {
 	return &Resolver{
-		PostService:    PostService,
-		CommentService: CommentService,
-		UserService:    UserService,
-		broker:         broker,
+		PostService:         PostService,
+		CommentService:      CommentService,
+		UserService:         UserService,
+		ReactionService:     ReactionService,
+		SearchService:       SearchService,
+		NotificationService: NotificationService,
+		ModerationService:   ModerationService,
+		broker:              broker,
+		presence:            presence,
+		markdown:            markdown,
 	}
 }
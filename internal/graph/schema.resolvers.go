@@ -5,15 +5,104 @@ package graph
 // Code generated by github.com/99designs/gqlgen version v0.17.64
 
 import (
+	"Commentary/internal/auth"
 	"Commentary/internal/graph/model"
+	"Commentary/internal/loader"
+	"Commentary/internal/pubsub"
+	"Commentary/internal/service"
 	"context"
-	"github.com/sirupsen/logrus"
-	"sync"
+	"time"
 )
 
+// Post is the resolver for the post field.
+func (r *commentResolver) Post(ctx context.Context, obj *model.Comment) (*model.Post, error) {
+	return loader.FromContext(ctx).Posts.Load(ctx, obj.PostID)
+}
+
+// Author is the resolver for the author field.
+func (r *commentResolver) Author(ctx context.Context, obj *model.Comment) (*model.User, error) {
+	return loader.FromContext(ctx).Users.Load(ctx, obj.AuthorID)
+}
+
+// ContentHTML is the resolver for the contentHTML field.
+func (r *commentResolver) ContentHTML(ctx context.Context, obj *model.Comment) (string, error) {
+	return r.markdown.Render(obj.Content), nil
+}
+
+// Parent is the resolver for the parent field.
+func (r *commentResolver) Parent(ctx context.Context, obj *model.Comment) (*model.Comment, error) {
+	if obj.ParentID == nil {
+		return nil, nil
+	}
+	return loader.FromContext(ctx).Comments.Load(ctx, *obj.ParentID)
+}
+
+// ReplyCount is the resolver for the replyCount field.
+func (r *commentResolver) ReplyCount(ctx context.Context, obj *model.Comment) (int, error) {
+	return loader.FromContext(ctx).ReplyCounts.Load(ctx, obj.ID)
+}
+
+// Reactions is the resolver for the reactions field.
+func (r *commentResolver) Reactions(ctx context.Context, obj *model.Comment) ([]*model.ReactionCount, error) {
+	return loader.FromContext(ctx).CommentReactions.Load(ctx, obj.ID)
+}
+
+// Mentions is the resolver for the mentions field.
+func (r *commentResolver) Mentions(ctx context.Context, obj *model.Comment) ([]*model.Mention, error) {
+	if obj.Deleted {
+		return []*model.Mention{}, nil
+	}
+	return loader.FromContext(ctx).CommentMentions.Load(ctx, obj.ID)
+}
+
+// Replies is the resolver for the replies field.
+func (r *commentResolver) Replies(ctx context.Context, obj *model.Comment, first *int, after *string, depth int) (*model.CommentConnection, error) {
+	return loader.FromContext(ctx).Replies.Load(ctx, loader.NewRepliesKey(obj.ID, first, after, depth))
+}
+
+// Revisions is the resolver for the revisions field.
+func (r *commentResolver) Revisions(ctx context.Context, obj *model.Comment) ([]*model.CommentRevision, error) {
+	if obj.Deleted {
+		return []*model.CommentRevision{}, nil
+	}
+	allowed, err := service.SeesRevisions(ctx, r.ModerationService, obj)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		return []*model.CommentRevision{}, nil
+	}
+	return loader.FromContext(ctx).Revisions.Load(ctx, obj.ID)
+}
+
+// Editor is the resolver for the editor field.
+func (r *commentRevisionResolver) Editor(ctx context.Context, obj *model.CommentRevision) (*model.User, error) {
+	return loader.FromContext(ctx).Users.Load(ctx, obj.EditorID)
+}
+
+// Post is the resolver for the post field.
+func (r *commentsToggledResolver) Post(ctx context.Context, obj *model.CommentsToggled) (*model.Post, error) {
+	return loader.FromContext(ctx).Posts.Load(ctx, obj.PostID)
+}
+
+// User is the resolver for the user field.
+func (r *mentionResolver) User(ctx context.Context, obj *model.Mention) (*model.User, error) {
+	return loader.FromContext(ctx).Users.Load(ctx, obj.UserID)
+}
+
 // CreateUser is the resolver for the createUser field.
-func (r *mutationResolver) CreateUser(ctx context.Context, username string) (*model.User, error) {
-	return r.UserService.CreateUser(ctx, username)
+func (r *mutationResolver) CreateUser(ctx context.Context, username string, password string) (*model.User, error) {
+	return r.UserService.CreateUser(ctx, username, password)
+}
+
+// Login is the resolver for the login field.
+func (r *mutationResolver) Login(ctx context.Context, username string, password string) (*model.AuthPayload, error) {
+	return r.UserService.Login(ctx, username, password)
+}
+
+// Refresh is the resolver for the refresh field.
+func (r *mutationResolver) Refresh(ctx context.Context, refreshToken string) (*model.AuthPayload, error) {
+	return r.UserService.Refresh(ctx, refreshToken)
 }
 
 // CreatePost is the resolver for the createPost field.
@@ -26,62 +115,402 @@ func (r *mutationResolver) ToggleComments(ctx context.Context, postID int) (*mod
 	return r.PostService.ToggleComments(ctx, postID)
 }
 
+// UpdatePost is the resolver for the updatePost field.
+func (r *mutationResolver) UpdatePost(ctx context.Context, postID int, input model.UpdatePostInput) (*model.Post, error) {
+	return r.PostService.UpdatePost(ctx, postID, input)
+}
+
+// DeletePost is the resolver for the deletePost field.
+func (r *mutationResolver) DeletePost(ctx context.Context, postID int) (bool, error) {
+	return r.PostService.DeletePost(ctx, postID)
+}
+
 // CreateComment is the resolver for the createComment field.
 func (r *mutationResolver) CreateComment(ctx context.Context, input model.CreateCommentInput) (*model.Comment, error) {
 	return r.CommentService.CreateComment(ctx, input)
 }
 
+// UpdateComment is the resolver for the updateComment field.
+func (r *mutationResolver) UpdateComment(ctx context.Context, commentID int, content string) (*model.Comment, error) {
+	return r.CommentService.UpdateComment(ctx, commentID, content)
+}
+
+// DeleteComment is the resolver for the deleteComment field.
+func (r *mutationResolver) DeleteComment(ctx context.Context, commentID int) (*model.Comment, error) {
+	return r.CommentService.DeleteComment(ctx, commentID)
+}
+
+// React is the resolver for the react field.
+func (r *mutationResolver) React(ctx context.Context, input model.ReactionInput) ([]*model.ReactionCount, error) {
+	return r.ReactionService.React(ctx, input)
+}
+
+// Unreact is the resolver for the unreact field.
+func (r *mutationResolver) Unreact(ctx context.Context, input model.ReactionInput) ([]*model.ReactionCount, error) {
+	return r.ReactionService.Unreact(ctx, input)
+}
+
+// SetTyping is the resolver for the setTyping field.
+func (r *mutationResolver) SetTyping(ctx context.Context, postID int, typing bool) (bool, error) {
+	userID, err := auth.ViewerID(ctx)
+	if err != nil {
+		return false, err
+	}
+	if _, err = r.PostService.GetPost(ctx, postID); err != nil {
+		return false, err
+	}
+
+	r.presence.SetTyping(postID, userID, typing)
+	return true, nil
+}
+
+// MarkNotificationsRead is the resolver for the markNotificationsRead field.
+func (r *mutationResolver) MarkNotificationsRead(ctx context.Context, ids []int) (int, error) {
+	return r.NotificationService.MarkNotificationsRead(ctx, ids)
+}
+
+// ReportPost is the resolver for the reportPost field.
+func (r *mutationResolver) ReportPost(ctx context.Context, postID int, reason string) (bool, error) {
+	if err := r.ModerationService.ReportPost(ctx, postID, reason); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ReportComment is the resolver for the reportComment field.
+func (r *mutationResolver) ReportComment(ctx context.Context, commentID int, reason string) (bool, error) {
+	if err := r.ModerationService.ReportComment(ctx, commentID, reason); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ModeratePost is the resolver for the moderatePost field.
+func (r *mutationResolver) ModeratePost(ctx context.Context, postID int, action model.ModerationAction) (bool, error) {
+	if err := r.ModerationService.ModeratePost(ctx, postID, action); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ModerateComment is the resolver for the moderateComment field.
+func (r *mutationResolver) ModerateComment(ctx context.Context, commentID int, action model.ModerationAction) (bool, error) {
+	if err := r.ModerationService.ModerateComment(ctx, commentID, action); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Comment is the resolver for the comment field.
+func (r *notificationResolver) Comment(ctx context.Context, obj *model.Notification) (*model.Comment, error) {
+	return loader.FromContext(ctx).Comments.Load(ctx, obj.CommentID)
+}
+
+// Actor is the resolver for the actor field.
+func (r *notificationResolver) Actor(ctx context.Context, obj *model.Notification) (*model.User, error) {
+	return loader.FromContext(ctx).Users.Load(ctx, obj.ActorID)
+}
+
+// Author is the resolver for the author field.
+func (r *postResolver) Author(ctx context.Context, obj *model.Post) (*model.User, error) {
+	return loader.FromContext(ctx).Users.Load(ctx, obj.AuthorID)
+}
+
+// ContentHTML is the resolver for the contentHTML field.
+func (r *postResolver) ContentHTML(ctx context.Context, obj *model.Post) (string, error) {
+	return r.markdown.Render(obj.Content), nil
+}
+
+// Reactions is the resolver for the reactions field.
+func (r *postResolver) Reactions(ctx context.Context, obj *model.Post) ([]*model.ReactionCount, error) {
+	return loader.FromContext(ctx).PostReactions.Load(ctx, obj.ID)
+}
+
+// Mentions is the resolver for the mentions field.
+func (r *postResolver) Mentions(ctx context.Context, obj *model.Post) ([]*model.Mention, error) {
+	return loader.FromContext(ctx).PostMentions.Load(ctx, obj.ID)
+}
+
+// Comments is the resolver for the comments field.
+func (r *postResolver) Comments(ctx context.Context, obj *model.Post, first *int, after *string, sort model.SortOrder) (*model.CommentConnection, error) {
+	if after == nil {
+		return loader.FromContext(ctx).PostComments.Load(ctx, loader.NewPostCommentsKey(obj.ID, first, sort))
+	}
+	return r.CommentService.GetComments(ctx, obj.ID, first, after, sort)
+}
+
+// Typing is the resolver for the typing field.
+func (r *presenceResolver) Typing(ctx context.Context, obj *model.Presence) ([]*model.User, error) {
+	users := make([]*model.User, 0, len(obj.TypingIDs))
+	for _, userID := range obj.TypingIDs {
+		user, err := loader.FromContext(ctx).Users.Load(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, nil
+}
+
 // Posts is the resolver for the posts field.
-func (r *queryResolver) Posts(ctx context.Context, limit *int, offset *int) ([]*model.Post, error) {
-	return r.PostService.GetPosts(ctx, limit, offset)
+func (r *queryResolver) Posts(ctx context.Context, first *int, after *string, sort model.SortOrder) (*model.PostConnection, error) {
+	return r.PostService.GetPosts(ctx, first, after, sort)
+}
+
+// Post is the resolver for the post field.
+func (r *queryResolver) Post(ctx context.Context, postID int) (*model.Post, error) {
+	return r.PostService.GetPost(ctx, postID)
+}
+
+// Me is the resolver for the me field.
+func (r *queryResolver) Me(ctx context.Context) (*model.User, error) {
+	userID, ok := auth.UserIDFromContext(ctx)
+	if !ok {
+		return nil, nil
+	}
+	return loader.FromContext(ctx).Users.Load(ctx, userID)
+}
+
+// Search is the resolver for the search field.
+func (r *queryResolver) Search(ctx context.Context, text string, kinds []model.SearchKind, first *int, after *string) (*model.SearchConnection, error) {
+	return r.SearchService.Search(ctx, text, kinds, first, after)
+}
+
+// SubscriptionStats is the resolver for the subscriptionStats field.
+func (r *queryResolver) SubscriptionStats(ctx context.Context) (*model.SubscriptionStats, error) {
+	stats := r.broker.Stats()
+	return &model.SubscriptionStats{
+		Subscribers:  stats.Subscribers,
+		Published:    int(stats.Published),
+		Delivered:    int(stats.Delivered),
+		Dropped:      int(stats.Dropped),
+		Disconnected: int(stats.Disconnected),
+	}, nil
+}
+
+// Notifications is the resolver for the notifications field.
+func (r *queryResolver) Notifications(ctx context.Context, first *int, after *string, unreadOnly bool) (*model.NotificationConnection, error) {
+	return r.NotificationService.GetNotifications(ctx, first, after, unreadOnly)
+}
+
+// ModerationQueue is the resolver for the moderationQueue field.
+func (r *queryResolver) ModerationQueue(ctx context.Context, first *int, after *string) (*model.ReportConnection, error) {
+	return r.ModerationService.GetQueue(ctx, first, after)
 }
 
 // Post is the resolver for the post field.
-func (r *queryResolver) Post(ctx context.Context, postID int, limit *int, offset *int) (*model.Post, error) {
-	return r.PostService.GetPost(ctx, postID, limit, offset)
+func (r *reportResolver) Post(ctx context.Context, obj *model.Report) (*model.Post, error) {
+	if obj.PostID == nil {
+		return nil, nil
+	}
+	return loader.FromContext(ctx).Posts.Load(ctx, *obj.PostID)
+}
+
+// Comment is the resolver for the comment field.
+func (r *reportResolver) Comment(ctx context.Context, obj *model.Report) (*model.Comment, error) {
+	if obj.CommentID == nil {
+		return nil, nil
+	}
+	return loader.FromContext(ctx).Comments.Load(ctx, *obj.CommentID)
+}
+
+// Reporter is the resolver for the reporter field.
+func (r *reportResolver) Reporter(ctx context.Context, obj *model.Report) (*model.User, error) {
+	if obj.ReporterID == nil {
+		return nil, nil
+	}
+	return loader.FromContext(ctx).Users.Load(ctx, *obj.ReporterID)
+}
+
+// Node is the resolver for the node field.
+func (r *searchEdgeResolver) Node(ctx context.Context, obj *model.SearchEdge) (model.SearchResult, error) {
+	if obj.Kind == model.SearchKindComment {
+		return loader.FromContext(ctx).Comments.Load(ctx, obj.ID)
+	}
+	return loader.FromContext(ctx).Posts.Load(ctx, obj.ID)
 }
 
 // NewComment is the resolver for the newComment field.
-func (r *subscriptionResolver) NewComment(ctx context.Context, postID int) (<-chan *model.Comment, error) {
-	commentChan := r.broker.Subscribe(postID)
-	commentCh := make(chan *model.Comment, 100)
+func (r *subscriptionResolver) NewComment(ctx context.Context, postID int, since *string) (<-chan *model.Comment, error) {
+	if _, err := r.PostService.GetPost(ctx, postID); err != nil {
+		return nil, err
+	}
+	moderator, err := r.ModerationService.IsModerator(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// subscribing before reading the missed comments leaves no gap between them and the live ones,
+	// comments created in between arrive both ways and are skipped the second time
+	sub := subscribe(ctx, r.broker, postID, func(event model.Event) bool {
+		created, ok := event.(*model.CommentCreated)
+		return ok && (moderator || !created.Comment.Hidden && !created.HiddenThread)
+	})
+
+	var missed []*model.Comment
+	if since != nil {
+		if missed, err = r.CommentService.GetCommentsSince(ctx, postID, *since); err != nil {
+			r.broker.Unsubscribe(sub)
+			return nil, err
+		}
+	}
+
+	return replay(ctx, sub, missed), nil
+}
+
+// CommentEvents is the resolver for the commentEvents field.
+func (r *subscriptionResolver) CommentEvents(ctx context.Context, postID int, parentID *int) (<-chan model.CommentEvent, error) {
+	if _, err := r.PostService.GetPost(ctx, postID); err != nil {
+		return nil, err
+	}
+	moderator, err := r.ModerationService.IsModerator(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sub := subscribe(ctx, r.broker, postID, func(event model.Event) bool {
+		commentEvent, ok := event.(model.CommentEvent)
+		return ok && inThread(commentEvent, parentID)
+	})
+	return forward(ctx, sub, func(event model.Event) (model.CommentEvent, bool) {
+		return visibleEvent(event.(model.CommentEvent), moderator)
+	}), nil
+}
+
+// NewPost is the resolver for the newPost field.
+func (r *subscriptionResolver) NewPost(ctx context.Context) (<-chan *model.Post, error) {
+	moderator, err := r.ModerationService.IsModerator(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sub := subscribe(ctx, r.broker, pubsub.FeedTopic, func(event model.Event) bool {
+		created, ok := event.(*model.PostCreated)
+		return ok && (moderator || !created.Post.Hidden)
+	})
+	return forward(ctx, sub, func(event model.Event) (*model.Post, bool) {
+		return event.(*model.PostCreated).Post, true
+	}), nil
+}
+
+// PostUpdated is the resolver for the postUpdated field.
+func (r *subscriptionResolver) PostUpdated(ctx context.Context, postID int) (<-chan *model.Post, error) {
+	if _, err := r.PostService.GetPost(ctx, postID); err != nil {
+		return nil, err
+	}
+	moderator, err := r.ModerationService.IsModerator(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// a post hidden by a moderator is gone for everyone else, they get none of its updates
+	sub := subscribe(ctx, r.broker, postID, func(event model.Event) bool {
+		updated, ok := event.(*model.PostUpdated)
+		return ok && (moderator || !updated.Post.Hidden)
+	})
+	return forward(ctx, sub, func(event model.Event) (*model.Post, bool) {
+		return event.(*model.PostUpdated).Post, true
+	}), nil
+}
 
-	var mu sync.Mutex
+// Presence is the resolver for the presence field.
+func (r *subscriptionResolver) Presence(ctx context.Context, postID int) (<-chan *model.Presence, error) {
+	if _, err := r.PostService.GetPost(ctx, postID); err != nil {
+		return nil, err
+	}
 
+	// presence is not published through the broker, which only tells that the post is deleted
+	deleted := subscribe(ctx, r.broker, postID, postDeleted)
+	viewer := r.presence.Join(postID)
+	presences := make(chan *model.Presence)
 	go func() {
-		defer close(commentCh)
-		for msg := range commentChan {
-			mu.Lock()
+		defer close(presences)
+		defer r.presence.Leave(viewer)
+
+		heartbeat := time.NewTicker(r.presence.TTL() / 2)
+		defer heartbeat.Stop()
+		for {
 			select {
-			case commentCh <- msg:
-				logrus.Debugf("Sent comment for postID %v", postID)
+			case snapshot := <-viewer.Updates():
+				select {
+				case presences <- &model.Presence{Viewers: snapshot.Viewers, TypingIDs: snapshot.Typing}:
+				case <-ctx.Done():
+					return
+				}
+			case <-heartbeat.C:
+				r.presence.Heartbeat(viewer)
+			case <-deleted.Events():
+				return
 			case <-ctx.Done():
-				logrus.Debugf("Subscription canceled for postID %v", postID)
-				mu.Unlock()
 				return
 			}
-			mu.Unlock()
 		}
 	}()
 
-	go func() {
-		<-ctx.Done()
-		r.broker.Unsubscribe(postID, commentChan)
-		logrus.Debugf("Unsubscribed from postID %v", postID)
-	}()
+	return presences, nil
+}
+
+// NotificationAdded is the resolver for the notificationAdded field.
+func (r *subscriptionResolver) NotificationAdded(ctx context.Context) (<-chan *model.Notification, error) {
+	userID, err := auth.ViewerID(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-	return commentCh, nil
+	sub := subscribe(ctx, r.broker, pubsub.UserTopic(userID), func(event model.Event) bool {
+		_, ok := event.(*model.NotificationAdded)
+		return ok
+	})
+	return forward(ctx, sub, func(event model.Event) (*model.Notification, bool) {
+		return event.(*model.NotificationAdded).Notification, true
+	}), nil
 }
 
+// Comment returns CommentResolver implementation.
+func (r *Resolver) Comment() CommentResolver { return &commentResolver{r} }
+
+// CommentRevision returns CommentRevisionResolver implementation.
+func (r *Resolver) CommentRevision() CommentRevisionResolver { return &commentRevisionResolver{r} }
+
+// CommentsToggled returns CommentsToggledResolver implementation.
+func (r *Resolver) CommentsToggled() CommentsToggledResolver { return &commentsToggledResolver{r} }
+
+// Mention returns MentionResolver implementation.
+func (r *Resolver) Mention() MentionResolver { return &mentionResolver{r} }
+
 // Mutation returns MutationResolver implementation.
 func (r *Resolver) Mutation() MutationResolver { return &mutationResolver{r} }
 
+// Notification returns NotificationResolver implementation.
+func (r *Resolver) Notification() NotificationResolver { return &notificationResolver{r} }
+
+// Post returns PostResolver implementation.
+func (r *Resolver) Post() PostResolver { return &postResolver{r} }
+
+// Presence returns PresenceResolver implementation.
+func (r *Resolver) Presence() PresenceResolver { return &presenceResolver{r} }
+
 // Query returns QueryResolver implementation.
 func (r *Resolver) Query() QueryResolver { return &queryResolver{r} }
 
+// Report returns ReportResolver implementation.
+func (r *Resolver) Report() ReportResolver { return &reportResolver{r} }
+
+// SearchEdge returns SearchEdgeResolver implementation.
+func (r *Resolver) SearchEdge() SearchEdgeResolver { return &searchEdgeResolver{r} }
+
 // Subscription returns SubscriptionResolver implementation.
 func (r *Resolver) Subscription() SubscriptionResolver { return &subscriptionResolver{r} }
 
+type commentResolver struct{ *Resolver }
+type commentRevisionResolver struct{ *Resolver }
+type commentsToggledResolver struct{ *Resolver }
+type mentionResolver struct{ *Resolver }
 type mutationResolver struct{ *Resolver }
+type notificationResolver struct{ *Resolver }
+type postResolver struct{ *Resolver }
+type presenceResolver struct{ *Resolver }
 type queryResolver struct{ *Resolver }
+type reportResolver struct{ *Resolver }
+type searchEdgeResolver struct{ *Resolver }
 type subscriptionResolver struct{ *Resolver }
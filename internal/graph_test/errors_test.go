@@ -0,0 +1,75 @@
+package graph
+
+import (
+	"Commentary/internal/apperror"
+	"Commentary/internal/filter"
+	"Commentary/internal/graph"
+	"Commentary/internal/inmemory/imrepo"
+	"Commentary/internal/repo/pgdb"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		kind    apperror.Kind
+		field   string
+		message string
+	}{
+		{"pgdb not found", &pgdb.RepositoryError{Operation: "getting post", Content: "post record not found",
+			Err: pgdb.ErrPostNotFound}, apperror.NotFound, "", "post not found"},
+		{"imrepo not found", fmt.Errorf("failed to get post: %w", imrepo.ErrPostNotFound),
+			apperror.NotFound, "", "failed to get post: post not found"},
+		{"conflict", imrepo.ErrUserAlreadyExists, apperror.Conflict, "username",
+			"user with this nickname already exists"},
+		{"rejected content", &filter.RejectedError{Reason: "too long"}, apperror.Validation, "content", ""},
+		{"repository failure", &pgdb.RepositoryError{Operation: "getting posts", Content: "failed to get posts",
+			Err: pgdb.ErrGettingPosts}, apperror.Internal, "", apperror.InternalMessage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			domainErr := graph.Classify(tt.err)
+			require.NotNil(t, domainErr)
+			assert.Equal(t, tt.kind, domainErr.Kind)
+			assert.Equal(t, tt.field, domainErr.Field)
+			if tt.message != "" {
+				assert.Equal(t, tt.message, domainErr.Message)
+			}
+			assert.ErrorIs(t, domainErr, tt.err)
+		})
+	}
+
+	assert.Nil(t, graph.Classify(errors.New("unknown")))
+}
+
+func TestErrorPresenter(t *testing.T) {
+	path := ast.Path{ast.PathName("post")}
+	notFound := &pgdb.RepositoryError{Operation: "getting post", Content: "post record not found",
+		Err: pgdb.ErrPostNotFound}
+
+	presented := graph.ErrorPresenter(context.Background(), gqlerror.WrapPath(path, notFound))
+	assert.Equal(t, "post not found", presented.Message)
+	assert.Equal(t, "NOT_FOUND", presented.Extensions["code"])
+	assert.Equal(t, path, presented.Path)
+
+	presented = graph.ErrorPresenter(context.Background(), gqlerror.WrapPath(path, imrepo.ErrUserAlreadyExists))
+	assert.Equal(t, "CONFLICT", presented.Extensions["code"])
+	assert.Equal(t, "username", presented.Extensions["field"])
+
+	presented = graph.ErrorPresenter(context.Background(), gqlerror.WrapPath(path, errors.New("dial tcp: refused")))
+	assert.Equal(t, apperror.InternalMessage, presented.Message)
+	assert.Equal(t, "INTERNAL", presented.Extensions["code"])
+
+	limited := &gqlerror.Error{Message: "rate limit exceeded", Extensions: map[string]any{"code": "RATE_LIMITED"}}
+	presented = graph.ErrorPresenter(context.Background(), limited)
+	assert.Equal(t, "rate limit exceeded", presented.Message)
+	assert.Equal(t, "RATE_LIMITED", presented.Extensions["code"])
+}
@@ -0,0 +1,413 @@
+package graph
+
+import (
+	"Commentary/app"
+	"Commentary/internal/auth"
+	"Commentary/internal/config"
+	"Commentary/internal/graph"
+	"Commentary/internal/graph/model"
+	"Commentary/internal/repo"
+	"Commentary/internal/service"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// newResolver builds the resolver on the in-memory storage, subscriptions buffer 10 events
+// and overflow by the policy.
+func newResolver(t *testing.T, overflow string, blockTimeout time.Duration) (*graph.Resolver, *app.ServiceFactory) {
+	factory := app.NewServiceFactory(&config.Config{
+		Auth:      config.Auth{Secret: "a subscription secret of at least 32 bytes", AccessTTL: time.Minute},
+		Reactions: config.Reactions{Types: []string{"like"}},
+		PubSub:    config.PubSub{Backend: "memory", Buffer: 10, Overflow: overflow, BlockTimeout: blockTimeout},
+		Presence:  config.Presence{TTL: time.Second},
+		Markdown:  config.Markdown{CacheSize: 10},
+	}, nil)
+	resolver := graph.NewResolver(factory.CreatePostService(), factory.CreateCommentService(),
+		factory.CreateUserService(), factory.CreateReactionService(), factory.CreateSearchService(),
+		factory.CreateNotificationService(), factory.CreateModerationService(), factory.Broker(),
+		factory.Presence(), factory.Markdown())
+	return resolver, factory
+}
+
+// newViewer registers a user and returns a context acting on their behalf, canceled when the test ends.
+func newViewer(t *testing.T, factory *app.ServiceFactory, username string, moderator bool) context.Context {
+	user, err := factory.CreateUserService().CreateUser(context.Background(), username, "password")
+	require.NoError(t, err)
+	if moderator {
+		require.NoError(t, factory.CreateModerationService().GrantModerator(context.Background(), user.ID))
+	}
+	ctx, cancel := context.WithCancel(auth.WithUserID(context.Background(), user.ID))
+	t.Cleanup(cancel)
+	return ctx
+}
+
+func newPost(t *testing.T, ctx context.Context, factory *app.ServiceFactory) *model.Post {
+	post, err := factory.CreatePostService().CreatePost(ctx,
+		model.CreatePostInput{Title: "title", Content: "content", Commentable: true})
+	require.NoError(t, err)
+	return post
+}
+
+func newComment(t *testing.T, ctx context.Context, factory *app.ServiceFactory, postID int,
+	parentID *int) *model.Comment {
+	comment, err := factory.CreateCommentService().CreateComment(ctx,
+		model.CreateCommentInput{PostID: postID, Content: "comment", Parent: parentID})
+	require.NoError(t, err)
+	return comment
+}
+
+// receive waits for the next value of the subscription.
+func receive[T any](t *testing.T, values <-chan T) T {
+	t.Helper()
+	select {
+	case value, ok := <-values:
+		require.True(t, ok, "the subscription is closed")
+		return value
+	case <-time.After(time.Second):
+	}
+	require.FailNow(t, "no event")
+	var zero T
+	return zero
+}
+
+func TestCommentEvents(t *testing.T) {
+	resolver, factory := newResolver(t, "drop_oldest", time.Second)
+	ctx := newViewer(t, factory, "author", false)
+	post := newPost(t, ctx, factory)
+	comments := factory.CreateCommentService()
+
+	events, err := resolver.Subscription().CommentEvents(ctx, post.ID, nil)
+	require.NoError(t, err)
+
+	comment := newComment(t, ctx, factory, post.ID, nil)
+	created := receive(t, events)
+	require.IsType(t, &model.CommentCreated{}, created)
+	assert.Equal(t, comment.ID, created.(*model.CommentCreated).Comment.ID)
+
+	_, err = comments.UpdateComment(ctx, comment.ID, "edited")
+	require.NoError(t, err)
+	updated := receive(t, events)
+	require.IsType(t, &model.CommentUpdated{}, updated)
+	assert.Equal(t, "edited", updated.(*model.CommentUpdated).Comment.Content)
+
+	_, err = comments.DeleteComment(ctx, comment.ID)
+	require.NoError(t, err)
+	deleted := receive(t, events)
+	require.IsType(t, &model.CommentDeleted{}, deleted)
+	assert.Equal(t, comment.ID, deleted.(*model.CommentDeleted).CommentID)
+
+	_, err = factory.CreatePostService().ToggleComments(ctx, post.ID)
+	require.NoError(t, err)
+	toggled := receive(t, events)
+	require.IsType(t, &model.CommentsToggled{}, toggled)
+	assert.False(t, toggled.(*model.CommentsToggled).Commentable)
+
+	for i, event := range []model.CommentEvent{created, updated, deleted, toggled} {
+		assert.Equal(t, i+1, event.GetSequence())
+	}
+}
+
+func TestCommentEventsInThread(t *testing.T) {
+	resolver, factory := newResolver(t, "drop_oldest", time.Second)
+	ctx := newViewer(t, factory, "author", false)
+	post := newPost(t, ctx, factory)
+	root := newComment(t, ctx, factory, post.ID, nil)
+
+	events, err := resolver.Subscription().CommentEvents(ctx, post.ID, &root.ID)
+	require.NoError(t, err)
+
+	newComment(t, ctx, factory, post.ID, nil)
+	reply := newComment(t, ctx, factory, post.ID, &root.ID)
+	newComment(t, ctx, factory, post.ID, &reply.ID)
+	_, err = factory.CreateCommentService().UpdateComment(ctx, root.ID, "edited")
+	require.NoError(t, err)
+	_, err = factory.CreatePostService().ToggleComments(ctx, post.ID)
+	require.NoError(t, err)
+
+	created := receive(t, events)
+	require.IsType(t, &model.CommentCreated{}, created)
+	assert.Equal(t, reply.ID, created.(*model.CommentCreated).Comment.ID)
+	toggled := receive(t, events)
+	require.IsType(t, &model.CommentsToggled{}, toggled)
+
+	// events of other threads are not numbered for the subscription
+	assert.Equal(t, 1, created.GetSequence())
+	assert.Equal(t, 2, toggled.GetSequence())
+}
+
+func TestCommentEventsSequenceGaps(t *testing.T) {
+	resolver, factory := newResolver(t, "drop_oldest", time.Second)
+	ctx := newViewer(t, factory, "author", false)
+	post := newPost(t, ctx, factory)
+
+	events, err := resolver.Subscription().CommentEvents(ctx, post.ID, nil)
+	require.NoError(t, err)
+
+	// the buffer holds 10 events and one more waits to be sent, the oldest of the others are dropped
+	const published = 15
+	for i := 0; i < published; i++ {
+		newComment(t, ctx, factory, post.ID, nil)
+	}
+
+	var sequences []int
+	for len(sequences) == 0 || sequences[len(sequences)-1] < published {
+		sequences = append(sequences, receive(t, events).GetSequence())
+	}
+	assert.Less(t, len(sequences), published)
+	assert.IsIncreasing(t, sequences)
+	assert.Equal(t, int64(published-len(sequences)), factory.Broker().Stats().Dropped)
+}
+
+func TestCommentEventsOfHiddenComments(t *testing.T) {
+	resolver, factory := newResolver(t, "drop_oldest", time.Second)
+	ctx := newViewer(t, factory, "reader", false)
+	moderatorCtx := newViewer(t, factory, "moderator", true)
+	post := newPost(t, ctx, factory)
+	root := newComment(t, ctx, factory, post.ID, nil)
+
+	events, err := resolver.Subscription().CommentEvents(ctx, post.ID, nil)
+	require.NoError(t, err)
+	moderatorEvents, err := resolver.Subscription().CommentEvents(moderatorCtx, post.ID, nil)
+	require.NoError(t, err)
+
+	require.NoError(t, factory.CreateModerationService().ModerateComment(moderatorCtx, root.ID,
+		model.ModerationActionHide))
+	deleted := receive(t, events)
+	require.IsType(t, &model.CommentDeleted{}, deleted)
+	assert.Equal(t, root.ID, deleted.(*model.CommentDeleted).CommentID)
+	assert.Equal(t, 1, deleted.GetSequence())
+	updated := receive(t, moderatorEvents)
+	require.IsType(t, &model.CommentUpdated{}, updated)
+	assert.True(t, updated.(*model.CommentUpdated).Comment.Hidden)
+
+	// the reply is in a hidden thread, the reader's next event is about the new root comment
+	reply := newComment(t, moderatorCtx, factory, post.ID, &root.ID)
+	next := newComment(t, ctx, factory, post.ID, nil)
+	created := receive(t, events)
+	require.IsType(t, &model.CommentCreated{}, created)
+	assert.Equal(t, next.ID, created.(*model.CommentCreated).Comment.ID)
+	for _, id := range []int{reply.ID, next.ID} {
+		created = receive(t, moderatorEvents)
+		require.IsType(t, &model.CommentCreated{}, created)
+		assert.Equal(t, id, created.(*model.CommentCreated).Comment.ID)
+	}
+}
+
+func TestNewCommentSince(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		since func(first *model.Comment, after time.Time) string
+	}{
+		{"comment ID", func(first *model.Comment, _ time.Time) string { return strconv.Itoa(first.ID) }},
+		{"RFC 3339 time", func(_ *model.Comment, after time.Time) string { return after.Format(time.RFC3339Nano) }},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			resolver, factory := newResolver(t, "drop_oldest", time.Second)
+			ctx := newViewer(t, factory, "author", false)
+			post := newPost(t, ctx, factory)
+			first := newComment(t, ctx, factory, post.ID, nil)
+			time.Sleep(time.Millisecond)
+			after := time.Now()
+			time.Sleep(time.Millisecond)
+			missed := []*model.Comment{newComment(t, ctx, factory, post.ID, nil),
+				newComment(t, ctx, factory, post.ID, nil)}
+
+			since := tt.since(first, after)
+			comments, err := resolver.Subscription().NewComment(ctx, post.ID, &since)
+			require.NoError(t, err)
+			live := newComment(t, ctx, factory, post.ID, nil)
+
+			for _, expected := range append(missed, live) {
+				assert.Equal(t, expected.ID, receive(t, comments).ID)
+			}
+		})
+	}
+}
+
+func TestNewCommentSkipsReplayedComments(t *testing.T) {
+	resolver, factory := newResolver(t, "drop_oldest", time.Second)
+	ctx := newViewer(t, factory, "author", false)
+	post := newPost(t, ctx, factory)
+	first := newComment(t, ctx, factory, post.ID, nil)
+	missed := []*model.Comment{newComment(t, ctx, factory, post.ID, nil),
+		newComment(t, ctx, factory, post.ID, nil)}
+
+	since := strconv.Itoa(first.ID)
+	comments, err := resolver.Subscription().NewComment(ctx, post.ID, &since)
+	require.NoError(t, err)
+	// a comment created while the missed ones were read arrives live as well
+	factory.Broker().Publish(ctx, post.ID, &model.CommentCreated{Comment: missed[1]})
+	live := newComment(t, ctx, factory, post.ID, nil)
+
+	for _, expected := range append(missed, live) {
+		assert.Equal(t, expected.ID, receive(t, comments).ID)
+	}
+}
+
+func TestNewCommentQueuesLiveCommentsDuringReplay(t *testing.T) {
+	resolver, factory := newResolver(t, "block", time.Second)
+	ctx := newViewer(t, factory, "author", false)
+	post := newPost(t, ctx, factory)
+	first := newComment(t, ctx, factory, post.ID, nil)
+	expected := []*model.Comment{newComment(t, ctx, factory, post.ID, nil),
+		newComment(t, ctx, factory, post.ID, nil)}
+
+	since := strconv.Itoa(first.ID)
+	comments, err := resolver.Subscription().NewComment(ctx, post.ID, &since)
+	require.NoError(t, err)
+	// nothing is read meanwhile, more live comments than the buffer and the pending events hold
+	for i := 0; i < 25; i++ {
+		expected = append(expected, newComment(t, ctx, factory, post.ID, nil))
+		time.Sleep(time.Millisecond)
+	}
+
+	for _, comment := range expected {
+		assert.Equal(t, comment.ID, receive(t, comments).ID)
+	}
+	assert.Zero(t, factory.Broker().Stats().Dropped)
+}
+
+func TestNewCommentCapsQueuedLiveComments(t *testing.T) {
+	resolver, factory := newResolver(t, "block", 10*time.Millisecond)
+	ctx := newViewer(t, factory, "author", false)
+	post := newPost(t, ctx, factory)
+	first := newComment(t, ctx, factory, post.ID, nil)
+	missed := newComment(t, ctx, factory, post.ID, nil)
+
+	since := strconv.Itoa(first.ID)
+	comments, err := resolver.Subscription().NewComment(ctx, post.ID, &since)
+	require.NoError(t, err)
+	const published = service.MaxReplay + 30
+	for i := 1; i <= published; i++ {
+		factory.Broker().Publish(ctx, post.ID, &model.CommentCreated{Comment: &model.Comment{ID: missed.ID + i,
+			PostID: post.ID}})
+		if i%10 == 0 {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	// the live comments beyond the queue are left to the overflow policy
+	time.Sleep(200 * time.Millisecond)
+
+	var received []int
+	for done := false; !done; {
+		select {
+		case comment := <-comments:
+			received = append(received, comment.ID)
+		case <-time.After(100 * time.Millisecond):
+			done = true
+		}
+	}
+	require.Greater(t, len(received), service.MaxReplay)
+	assert.Less(t, len(received), published+1)
+	for i, id := range received[:service.MaxReplay+1] {
+		assert.Equal(t, missed.ID+i, id)
+	}
+	assert.Positive(t, factory.Broker().Stats().Dropped)
+}
+
+func TestNewPost(t *testing.T) {
+	resolver, factory := newResolver(t, "drop_oldest", time.Second)
+	ctx := newViewer(t, factory, "author", false)
+
+	posts, err := resolver.Subscription().NewPost(ctx)
+	require.NoError(t, err)
+
+	post := newPost(t, ctx, factory)
+	created := receive(t, posts)
+	assert.Equal(t, post.ID, created.ID)
+	assert.Equal(t, post.Title, created.Title)
+}
+
+func TestPostUpdated(t *testing.T) {
+	resolver, factory := newResolver(t, "drop_oldest", time.Second)
+	ctx := newViewer(t, factory, "author", false)
+	moderatorCtx := newViewer(t, factory, "moderator", true)
+	post := newPost(t, ctx, factory)
+	postService := factory.CreatePostService()
+
+	updates, err := resolver.Subscription().PostUpdated(ctx, post.ID)
+	require.NoError(t, err)
+	moderatorUpdates, err := resolver.Subscription().PostUpdated(moderatorCtx, post.ID)
+	require.NoError(t, err)
+
+	title := "edited"
+	_, err = postService.UpdatePost(ctx, post.ID, model.UpdatePostInput{Title: &title})
+	require.NoError(t, err)
+	assert.Equal(t, title, receive(t, updates).Title)
+	assert.Equal(t, title, receive(t, moderatorUpdates).Title)
+
+	_, err = postService.ToggleComments(ctx, post.ID)
+	require.NoError(t, err)
+	assert.False(t, receive(t, updates).Commentable)
+	assert.False(t, receive(t, moderatorUpdates).Commentable)
+
+	// the author does not hear of the post being hidden, only of it being restored
+	moderation := factory.CreateModerationService()
+	require.NoError(t, moderation.ModeratePost(moderatorCtx, post.ID, model.ModerationActionHide))
+	require.NoError(t, moderation.ModeratePost(moderatorCtx, post.ID, model.ModerationActionRestore))
+	assert.False(t, receive(t, updates).Hidden)
+	assert.True(t, receive(t, moderatorUpdates).Hidden)
+	assert.False(t, receive(t, moderatorUpdates).Hidden)
+
+	// a missing post is not found, nor is a hidden one by anyone but moderators
+	_, err = resolver.Subscription().PostUpdated(ctx, post.ID+1)
+	assert.ErrorIs(t, err, repo.ErrPostNotFound)
+	require.NoError(t, moderation.ModeratePost(moderatorCtx, post.ID, model.ModerationActionHide))
+	_, err = resolver.Subscription().PostUpdated(ctx, post.ID)
+	assert.ErrorIs(t, err, repo.ErrPostNotFound)
+	_, err = resolver.Subscription().PostUpdated(moderatorCtx, post.ID)
+	assert.NoError(t, err)
+}
+
+// closed waits for the subscription to end.
+func closed[T any](t *testing.T, values <-chan T) {
+	t.Helper()
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case _, ok := <-values:
+			if !ok {
+				return
+			}
+		case <-timeout:
+			require.FailNow(t, "the subscription is not closed")
+		}
+	}
+}
+
+func TestPostDeletionEndsSubscriptions(t *testing.T) {
+	resolver, factory := newResolver(t, "drop_oldest", time.Second)
+	ctx := newViewer(t, factory, "author", false)
+	moderatorCtx := newViewer(t, factory, "moderator", true)
+	subscription := resolver.Subscription()
+
+	for _, remove := range []func(postID int) error{
+		func(postID int) error {
+			_, err := factory.CreatePostService().DeletePost(ctx, postID)
+			return err
+		},
+		func(postID int) error {
+			return factory.CreateModerationService().ModeratePost(moderatorCtx, postID, model.ModerationActionDelete)
+		},
+	} {
+		post := newPost(t, ctx, factory)
+		updates, err := subscription.PostUpdated(ctx, post.ID)
+		require.NoError(t, err)
+		comments, err := subscription.NewComment(ctx, post.ID, nil)
+		require.NoError(t, err)
+		events, err := subscription.CommentEvents(ctx, post.ID, nil)
+		require.NoError(t, err)
+		presences, err := subscription.Presence(ctx, post.ID)
+		require.NoError(t, err)
+
+		require.NoError(t, remove(post.ID))
+		closed(t, updates)
+		closed(t, comments)
+		closed(t, events)
+		closed(t, presences)
+	}
+}
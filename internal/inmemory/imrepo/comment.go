@@ -2,47 +2,47 @@ package imrepo
 
 import (
 	"Commentary/internal/entity"
-	"Commentary/internal/graph/model"
-	"errors"
+	"Commentary/internal/pagination"
+	"context"
 	"github.com/sirupsen/logrus"
-	"time"
 )
 
-func (imr *InMemoryRepo) AddComment(input model.CreateCommentInput) (*entity.Comment, error) {
+func (imr *InMemoryRepo) AddComment(ctx context.Context, comment *entity.Comment) (int, error) {
 	logrus.Debug("adding comment")
 
-	if len(input.Content) > 2000 {
-		return nil, errors.New("comment content exceeds the limitation of 2000 symbols")
-	}
-
 	imr.mu.Lock()
 	defer imr.mu.Unlock()
 
-	if _, ok := imr.users[input.AuthorID]; !ok {
+	if _, ok := imr.users[comment.AuthorID]; !ok {
 		logrus.Error(ErrUserNotFound)
-		return nil, ErrUserNotFound
+		return 0, ErrUserNotFound
 	}
-	if _, ok := imr.Posts[input.PostID]; !ok {
+	if _, ok := imr.Posts[comment.PostID]; !ok {
 		logrus.Error(ErrPostNotFound)
-		return nil, ErrPostNotFound
+		return 0, ErrPostNotFound
 	}
-
-	id := len(imr.comments) + 1
-	comment := &entity.Comment{
-		ID:       id,
-		PostID:   input.PostID,
-		AuthorID: input.AuthorID,
-		Content:  input.Content,
-		Created:  time.Now(),
-		ParentID: input.Parent,
+	if comment.ParentID != nil {
+		if parent, ok := imr.comments[*comment.ParentID]; !ok || parent.PostID != comment.PostID {
+			logrus.Error(ErrParentNotFound)
+			return 0, ErrParentNotFound
+		}
 	}
 
+	imr.lastCommentID++
+	id := imr.lastCommentID
+	added := *comment
+	added.ID = id
+
 	logrus.Debug("added comment")
-	imr.comments[id] = comment
-	return comment, nil
+	imr.comments[id] = &added
+	imr.indexDocument(document{kind: entity.TargetComment, id: id}, added.Content)
+	if added.ParentID != nil {
+		imr.replies[*added.ParentID] = append(imr.replies[*added.ParentID], id)
+	}
+	return id, nil
 }
 
-func (imr *InMemoryRepo) GetComment(id int) (*entity.Comment, error) {
+func (imr *InMemoryRepo) GetCommentByID(ctx context.Context, id int) (*entity.Comment, error) {
 	logrus.Debug("getting comment")
 
 	imr.mu.RLock()
@@ -57,24 +57,229 @@ func (imr *InMemoryRepo) GetComment(id int) (*entity.Comment, error) {
 	return comment, nil
 }
 
-func (imr *InMemoryRepo) GetCommentsByPostID(postID int) ([]*entity.Comment, error) {
-	logrus.Debug("getting comments by post id")
+// GetCommentsByIDs returns the comments found, unless withHidden is set comments hidden together
+// with their post or a comment above them are left out.
+func (imr *InMemoryRepo) GetCommentsByIDs(ctx context.Context, ids []int, withHidden bool) ([]*entity.Comment, error) {
+	logrus.Debug("getting comments by ids")
 
 	imr.mu.RLock()
 	defer imr.mu.RUnlock()
 
-	if _, ok := imr.Posts[postID]; !ok {
-		logrus.Error(ErrPostNotFound)
-		return nil, ErrPostNotFound
+	comments := make([]*entity.Comment, 0, len(ids))
+	for _, id := range ids {
+		if comment, ok := imr.comments[id]; ok && (withHidden || !imr.hiddenThread(comment)) {
+			comments = append(comments, comment)
+		}
 	}
+	logrus.Debug("got comments by ids")
 
-	comments := make([]*entity.Comment, 0, len(imr.comments))
+	return comments, nil
+}
+
+// GetCommentsSince returns at most limit comments of the post created after the given point,
+// oldest first. Unless withHidden is set, comments of a hidden post or under a hidden comment are skipped.
+func (imr *InMemoryRepo) GetCommentsSince(ctx context.Context, postID int, since pagination.Since, limit int,
+	withHidden bool) ([]*entity.Comment, error) {
+	logrus.Debug("getting comments since")
+
+	imr.mu.RLock()
+	defer imr.mu.RUnlock()
+
+	var comments []*entity.Comment
 	for _, comment := range imr.comments {
-		if comment.PostID == postID {
+		if comment.PostID != postID || !withHidden && imr.hiddenThread(comment) {
+			continue
+		}
+		if since.CommentID != 0 && comment.ID > since.CommentID ||
+			since.CommentID == 0 && comment.Created.After(since.Time) {
 			comments = append(comments, comment)
 		}
 	}
-	logrus.Debug("got comments by post id")
+	sortComments(comments, pagination.Page{Sort: pagination.SortOldest})
+	if len(comments) > limit {
+		comments = comments[:limit]
+	}
+	logrus.Debug("got comments since")
 
 	return comments, nil
 }
+
+// hiddenThread tells whether the comment is hidden by moderators, itself, with its post or with
+// a comment above it. Must be called with imr.mu held.
+func (imr *InMemoryRepo) hiddenThread(comment *entity.Comment) bool {
+	if post, ok := imr.Posts[comment.PostID]; ok && post.Hidden {
+		return true
+	}
+	for comment != nil {
+		if comment.Hidden {
+			return true
+		}
+		if comment.ParentID == nil {
+			return false
+		}
+		comment = imr.comments[*comment.ParentID]
+	}
+	return false
+}
+
+// GetReplies returns one page of replies to every given comment, keyed by the comment. A reply is found
+// up to depth levels below the comment, ordered by (created, id), and a depth of 1 fetches only
+// the direct children. Unless withHidden is set, hidden replies are skipped together with the replies below them.
+func (imr *InMemoryRepo) GetReplies(ctx context.Context, parentIDs []int, depth int, page pagination.Page,
+	withHidden bool) (map[int][]*entity.Comment, error) {
+	logrus.Debug("getting replies")
+
+	imr.mu.RLock()
+	defer imr.mu.RUnlock()
+
+	result := make(map[int][]*entity.Comment, len(parentIDs))
+	for _, rootID := range parentIDs {
+		var replies []*entity.Comment
+		level := []int{rootID}
+		for d := 0; d < depth && len(level) > 0; d++ {
+			var next []int
+			for _, parentID := range level {
+				for _, childID := range imr.replies[parentID] {
+					child := imr.comments[childID]
+					if child.Hidden && !withHidden {
+						continue
+					}
+					if page.Includes(commentKey(child)) {
+						replies = append(replies, child)
+					}
+					next = append(next, childID)
+				}
+			}
+			level = next
+		}
+
+		if len(replies) > 0 {
+			sortComments(replies, page)
+			result[rootID] = limitSlice(replies, page.Limit())
+		}
+	}
+
+	logrus.Debug("got replies")
+	return result, nil
+}
+
+// GetReplyCounts counts the direct replies of the comments, hidden replies are counted only withHidden.
+func (imr *InMemoryRepo) GetReplyCounts(ctx context.Context, parentIDs []int, withHidden bool) (map[int]int, error) {
+	logrus.Debug("counting replies")
+
+	imr.mu.RLock()
+	defer imr.mu.RUnlock()
+
+	counts := make(map[int]int, len(parentIDs))
+	for _, parentID := range parentIDs {
+		for _, replyID := range imr.replies[parentID] {
+			if !imr.comments[replyID].Hidden || withHidden {
+				counts[parentID]++
+			}
+		}
+	}
+
+	logrus.Debug("counted replies")
+	return counts, nil
+}
+
+// UpdateComment saves new content of a comment and keeps the replaced one as a revision
+// of editorID. Tombstones can not be updated.
+func (imr *InMemoryRepo) UpdateComment(ctx context.Context, comment *entity.Comment, editorID int) error {
+	logrus.Debug("updating comment")
+
+	imr.mu.Lock()
+	defer imr.mu.Unlock()
+
+	stored, ok := imr.comments[comment.ID]
+	if !ok || stored.Deleted {
+		logrus.Error(ErrCommentNotFound)
+		return ErrCommentNotFound
+	}
+
+	imr.lastRevisionID++
+	imr.revisions[comment.ID] = append(imr.revisions[comment.ID], &entity.CommentRevision{
+		ID:        imr.lastRevisionID,
+		CommentID: comment.ID,
+		Content:   stored.Content,
+		EditorID:  editorID,
+		Edited:    *comment.Edited,
+	})
+
+	updated := *stored
+	updated.Content = comment.Content
+	updated.Edited = comment.Edited
+	imr.comments[comment.ID] = &updated
+	imr.unindexDocument(document{kind: entity.TargetComment, id: comment.ID}, stored.Content)
+	imr.indexDocument(document{kind: entity.TargetComment, id: comment.ID}, updated.Content)
+	logrus.Debug("updated comment")
+
+	return nil
+}
+
+// GetRevisions returns revisions of the given comments ordered by comment and then by edit time.
+func (imr *InMemoryRepo) GetRevisions(ctx context.Context, commentIDs []int) ([]*entity.CommentRevision, error) {
+	logrus.Debug("getting comment revisions")
+
+	imr.mu.RLock()
+	defer imr.mu.RUnlock()
+
+	var revisions []*entity.CommentRevision
+	for _, commentID := range commentIDs {
+		revisions = append(revisions, imr.revisions[commentID]...)
+	}
+	logrus.Debug("got comment revisions")
+
+	return revisions, nil
+}
+
+// DeleteComment removes a comment without replies. A comment that has replies is turned
+// into a tombstone instead, so the thread below it stays reachable. The returned flag
+// reports whether the comment was tombstoned.
+func (imr *InMemoryRepo) DeleteComment(ctx context.Context, id int) (bool, error) {
+	logrus.Debug("deleting comment")
+
+	imr.mu.Lock()
+	defer imr.mu.Unlock()
+
+	stored, ok := imr.comments[id]
+	if !ok {
+		logrus.Error(ErrCommentNotFound)
+		return false, ErrCommentNotFound
+	}
+	if stored.Deleted {
+		logrus.Error(ErrCommentDeleted)
+		return false, ErrCommentDeleted
+	}
+	imr.unindexDocument(document{kind: entity.TargetComment, id: id}, stored.Content)
+	delete(imr.mentions, entity.ReactionTarget{Kind: entity.TargetComment, ID: id})
+	delete(imr.revisions, id)
+
+	if len(imr.replies[id]) > 0 {
+		tombstone := *stored
+		tombstone.Content = entity.DeletedCommentContent
+		tombstone.Deleted = true
+		imr.comments[id] = &tombstone
+		logrus.Debug("tombstoned comment")
+		return true, nil
+	}
+
+	delete(imr.comments, id)
+	delete(imr.reactions, entity.ReactionTarget{Kind: entity.TargetComment, ID: id})
+	imr.dropNotifications(map[int]struct{}{id: {}})
+	imr.dropReports(func(target entity.ReactionTarget) bool {
+		return target == entity.ReactionTarget{Kind: entity.TargetComment, ID: id}
+	})
+	if stored.ParentID != nil {
+		siblings := imr.replies[*stored.ParentID]
+		for i, siblingID := range siblings {
+			if siblingID == id {
+				imr.replies[*stored.ParentID] = append(siblings[:i:i], siblings[i+1:]...)
+				break
+			}
+		}
+	}
+	logrus.Debug("deleted comment")
+
+	return false, nil
+}
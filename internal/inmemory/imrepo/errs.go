@@ -1,10 +1,13 @@
 package imrepo
 
-import "errors"
+import "Commentary/internal/repo"
 
 var (
-	ErrUserAlreadyExists = errors.New("user with this nickname already exists")
-	ErrUserNotFound      = errors.New("user not found")
-	ErrCommentNotFound   = errors.New("comment not found")
-	ErrPostNotFound      = errors.New("post not found")
+	ErrUserAlreadyExists      = repo.ErrUserAlreadyExists
+	ErrUserNotFound           = repo.ErrUserNotFound
+	ErrCommentNotFound        = repo.ErrCommentNotFound
+	ErrCommentDeleted         = repo.ErrCommentDeleted
+	ErrParentNotFound         = repo.ErrParentNotFound
+	ErrPostNotFound           = repo.ErrPostNotFound
+	ErrReactionTargetNotFound = repo.ErrReactionTargetNotFound
 )
@@ -2,24 +2,73 @@ package imrepo
 
 import (
 	"Commentary/internal/entity"
+	"Commentary/internal/pagination"
+	"Commentary/internal/repo"
 	"sync"
 )
 
 type InMemoryRepo struct {
-	Posts       map[int]*entity.Post
-	comments    map[int]*entity.Comment
+	Posts     map[int]*entity.Post
+	comments  map[int]*entity.Comment
+	replies   map[int][]int
+	revisions map[int][]*entity.CommentRevision
+	// reactions holds ids of users that left a reaction of some type on a target.
+	reactions map[entity.ReactionTarget]map[string]map[int]struct{}
+	// index maps words to the documents containing them and the number of occurrences,
+	// lengths holds the number of words in every indexed document.
+	index       map[string]map[document]int
+	lengths     map[document]int
 	users       map[int]*entity.User
-	nicknames   map[string]struct{}
+	nicknames   map[string]int
 	subscribers map[int]map[int]struct{}
-	mu          sync.RWMutex
+	// notifications holds the notifications of every user, oldest first.
+	notifications map[int][]*entity.Notification
+	mentions      map[entity.ReactionTarget][]*entity.Mention
+	// reports holds reports in the order they were made.
+	reports []*entity.Report
+	// lastPostID, lastCommentID, lastRevisionID, lastNotificationID and lastReportID keep ids unique
+	// after deletions.
+	lastPostID         int
+	lastCommentID      int
+	lastRevisionID     int
+	lastNotificationID int
+	lastReportID       int
+	ranking            pagination.Ranking
+	mu                 sync.RWMutex
 }
 
-func NewInMemoryRepo() *InMemoryRepo {
+var _ interface {
+	repo.PostRepo
+	repo.CommentRepo
+	repo.UserRepo
+	repo.ReactionRepo
+	repo.SearchRepo
+	repo.NotificationRepo
+	repo.MentionRepo
+	repo.ModerationRepo
+} = (*InMemoryRepo)(nil)
+
+func NewInMemoryRepo(ranking pagination.Ranking) *InMemoryRepo {
 	return &InMemoryRepo{
-		Posts:       make(map[int]*entity.Post),
-		comments:    make(map[int]*entity.Comment),
-		users:       make(map[int]*entity.User),
-		nicknames:   make(map[string]struct{}),
-		subscribers: make(map[int]map[int]struct{}),
+		ranking:       ranking,
+		Posts:         make(map[int]*entity.Post),
+		comments:      make(map[int]*entity.Comment),
+		replies:       make(map[int][]int),
+		revisions:     make(map[int][]*entity.CommentRevision),
+		reactions:     make(map[entity.ReactionTarget]map[string]map[int]struct{}),
+		index:         make(map[string]map[document]int),
+		lengths:       make(map[document]int),
+		users:         make(map[int]*entity.User),
+		nicknames:     make(map[string]int),
+		subscribers:   make(map[int]map[int]struct{}),
+		notifications: make(map[int][]*entity.Notification),
+		mentions:      make(map[entity.ReactionTarget][]*entity.Mention),
+	}
+}
+
+func limitSlice[T any](items []T, limit *int) []T {
+	if limit == nil || len(items) <= *limit {
+		return items
 	}
+	return items[:*limit]
 }
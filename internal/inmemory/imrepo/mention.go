@@ -0,0 +1,53 @@
+package imrepo
+
+import (
+	"Commentary/internal/entity"
+	"context"
+	"github.com/sirupsen/logrus"
+)
+
+// ReplaceMentions saves the mentions of the target's content in place of the previous ones.
+func (imr *InMemoryRepo) ReplaceMentions(ctx context.Context, target entity.ReactionTarget,
+	mentions []*entity.Mention) error {
+	logrus.Debug("replacing mentions")
+
+	imr.mu.Lock()
+	defer imr.mu.Unlock()
+
+	if !imr.targetExists(target) {
+		logrus.Error(ErrReactionTargetNotFound)
+		return ErrReactionTargetNotFound
+	}
+
+	if len(mentions) == 0 {
+		delete(imr.mentions, target)
+		return nil
+	}
+	stored := make([]*entity.Mention, 0, len(mentions))
+	for _, mention := range mentions {
+		copied := *mention
+		copied.Target = target
+		stored = append(stored, &copied)
+	}
+	imr.mentions[target] = stored
+	logrus.Debug("replaced mentions")
+
+	return nil
+}
+
+// GetMentions returns the mentions in the content of the given targets ordered by target and offset.
+func (imr *InMemoryRepo) GetMentions(ctx context.Context, kind entity.TargetKind,
+	ids []int) ([]*entity.Mention, error) {
+	logrus.Debug("getting mentions")
+
+	imr.mu.RLock()
+	defer imr.mu.RUnlock()
+
+	var mentions []*entity.Mention
+	for _, id := range ids {
+		mentions = append(mentions, imr.mentions[entity.ReactionTarget{Kind: kind, ID: id}]...)
+	}
+	logrus.Debug("got mentions")
+
+	return mentions, nil
+}
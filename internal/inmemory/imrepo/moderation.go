@@ -0,0 +1,122 @@
+package imrepo
+
+import (
+	"Commentary/internal/entity"
+	"Commentary/internal/pagination"
+	"context"
+	"github.com/sirupsen/logrus"
+)
+
+// AddReport saves the report and sets its ID. A user reporting a target again before
+// its reports are resolved keeps the first report and leaves the ID unset.
+func (imr *InMemoryRepo) AddReport(ctx context.Context, report *entity.Report) error {
+	logrus.Debug("adding report")
+
+	imr.mu.Lock()
+	defer imr.mu.Unlock()
+
+	if !imr.targetExists(report.Target) {
+		logrus.Error(ErrReactionTargetNotFound)
+		return ErrReactionTargetNotFound
+	}
+	for _, open := range imr.reports {
+		if !open.Resolved && open.Target == report.Target && sameReporter(open, report) {
+			logrus.Debug("target already reported")
+			return nil
+		}
+	}
+
+	imr.lastReportID++
+	report.ID = imr.lastReportID
+	stored := *report
+	imr.reports = append(imr.reports, &stored)
+	logrus.Debug("added report")
+
+	return nil
+}
+
+// GetOpenReports returns a page of the reports no moderator has acted on, oldest first.
+func (imr *InMemoryRepo) GetOpenReports(ctx context.Context, page pagination.Page) ([]*entity.Report, error) {
+	logrus.Debug("getting open reports")
+
+	imr.mu.RLock()
+	defer imr.mu.RUnlock()
+
+	var reports []*entity.Report
+	for _, report := range imr.reports {
+		if !report.Resolved && page.Includes(pagination.Key{Created: report.Created, ID: report.ID}) {
+			reports = append(reports, report)
+		}
+	}
+	logrus.Debug("got open reports")
+
+	return limitSlice(reports, page.Limit()), nil
+}
+
+// ResolveReports takes the open reports of the target off the queue.
+func (imr *InMemoryRepo) ResolveReports(ctx context.Context, target entity.ReactionTarget) error {
+	logrus.Debug("resolving reports")
+
+	imr.mu.Lock()
+	defer imr.mu.Unlock()
+
+	for i, report := range imr.reports {
+		if !report.Resolved && report.Target == target {
+			resolved := *report
+			resolved.Resolved = true
+			imr.reports[i] = &resolved
+		}
+	}
+	logrus.Debug("resolved reports")
+
+	return nil
+}
+
+// SetHidden hides the post or the comment from users other than moderators, or restores it.
+func (imr *InMemoryRepo) SetHidden(ctx context.Context, target entity.ReactionTarget, hidden bool) error {
+	logrus.Debug("setting hidden")
+
+	imr.mu.Lock()
+	defer imr.mu.Unlock()
+
+	if target.Kind == entity.TargetComment {
+		stored, ok := imr.comments[target.ID]
+		if !ok {
+			logrus.Error(ErrCommentNotFound)
+			return ErrCommentNotFound
+		}
+		updated := *stored
+		updated.Hidden = hidden
+		imr.comments[target.ID] = &updated
+	} else {
+		stored, ok := imr.Posts[target.ID]
+		if !ok {
+			logrus.Error(ErrPostNotFound)
+			return ErrPostNotFound
+		}
+		updated := *stored
+		updated.Hidden = hidden
+		imr.Posts[target.ID] = &updated
+	}
+	logrus.Debug("set hidden")
+
+	return nil
+}
+
+// sameReporter tells whether the reports come from the same user, reports of content filters
+// never do.
+func sameReporter(a, b *entity.Report) bool {
+	return a.ReporterID != nil && b.ReporterID != nil && *a.ReporterID == *b.ReporterID
+}
+
+// dropReports removes the reports of deleted targets.
+// Must be called with the lock held.
+func (imr *InMemoryRepo) dropReports(deleted func(target entity.ReactionTarget) bool) {
+	kept := imr.reports[:0]
+	for _, report := range imr.reports {
+		if !deleted(report.Target) {
+			kept = append(kept, report)
+		}
+	}
+	imr.reports = kept
+}
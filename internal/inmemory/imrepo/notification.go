@@ -0,0 +1,117 @@
+package imrepo
+
+import (
+	"Commentary/internal/entity"
+	"Commentary/internal/pagination"
+	"context"
+	"github.com/sirupsen/logrus"
+)
+
+func notificationKey(notification *entity.Notification) pagination.Key {
+	return pagination.Key{Created: notification.Created, ID: notification.ID}
+}
+
+// AddNotifications saves the notifications and sets their ids.
+func (imr *InMemoryRepo) AddNotifications(ctx context.Context, notifications []*entity.Notification) error {
+	logrus.Debug("adding notifications")
+
+	imr.mu.Lock()
+	defer imr.mu.Unlock()
+
+	for _, notification := range notifications {
+		if _, ok := imr.comments[notification.CommentID]; !ok {
+			logrus.Error(ErrCommentNotFound)
+			return ErrCommentNotFound
+		}
+	}
+
+	for _, notification := range notifications {
+		imr.lastNotificationID++
+		notification.ID = imr.lastNotificationID
+		stored := *notification
+		imr.notifications[stored.UserID] = append(imr.notifications[stored.UserID], &stored)
+	}
+	logrus.Debug("added notifications")
+
+	return nil
+}
+
+// GetNotifications returns a page of the user's notifications, the page sort is expected to be newest first.
+// The notifications about comments hidden from non-moderators are returned only withHidden.
+func (imr *InMemoryRepo) GetNotifications(ctx context.Context, userID int, unreadOnly bool,
+	page pagination.Page, withHidden bool) ([]*entity.Notification, error) {
+	logrus.Debug("getting notifications")
+
+	imr.mu.RLock()
+	defer imr.mu.RUnlock()
+
+	stored := imr.notifications[userID]
+	notifications := make([]*entity.Notification, 0, len(stored))
+	for i := len(stored) - 1; i >= 0; i-- {
+		notification := stored[i]
+		if unreadOnly && notification.Read {
+			continue
+		}
+		if !page.Includes(notificationKey(notification)) {
+			continue
+		}
+		if comment, ok := imr.comments[notification.CommentID]; !withHidden && (!ok || imr.hiddenThread(comment)) {
+			continue
+		}
+		copied := *notification
+		notifications = append(notifications, &copied)
+	}
+	logrus.Debug("got notifications")
+
+	return limitSlice(notifications, page.Limit()), nil
+}
+
+// MarkNotificationsRead marks the user's notifications with the given ids as read, all of them if ids is nil.
+// It returns the number of notifications that were unread.
+func (imr *InMemoryRepo) MarkNotificationsRead(ctx context.Context, userID int, ids []int) (int, error) {
+	logrus.Debug("marking notifications read")
+
+	imr.mu.Lock()
+	defer imr.mu.Unlock()
+
+	var selected map[int]struct{}
+	if ids != nil {
+		selected = make(map[int]struct{}, len(ids))
+		for _, id := range ids {
+			selected[id] = struct{}{}
+		}
+	}
+
+	marked := 0
+	for _, notification := range imr.notifications[userID] {
+		if notification.Read {
+			continue
+		}
+		if _, ok := selected[notification.ID]; selected != nil && !ok {
+			continue
+		}
+		notification.Read = true
+		marked++
+	}
+	logrus.Debug("marked notifications read")
+
+	return marked, nil
+}
+
+// dropNotifications removes the notifications about the given comments.
+// Must be called with the lock held.
+func (imr *InMemoryRepo) dropNotifications(commentIDs map[int]struct{}) {
+	for userID, notifications := range imr.notifications {
+		kept := notifications[:0]
+		for _, notification := range notifications {
+			if _, ok := commentIDs[notification.CommentID]; !ok {
+				kept = append(kept, notification)
+			}
+		}
+		if len(kept) == 0 {
+			delete(imr.notifications, userID)
+		} else {
+			imr.notifications[userID] = kept
+		}
+	}
+}
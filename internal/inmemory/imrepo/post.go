@@ -2,41 +2,35 @@ package imrepo
 
 import (
 	"Commentary/internal/entity"
-	"Commentary/internal/graph/model"
+	"Commentary/internal/pagination"
+	"context"
 	"github.com/sirupsen/logrus"
-	"sort"
-	"time"
 )
 
-func (imr *InMemoryRepo) AddPost(input model.CreatePostInput) (*entity.Post, error) {
+func (imr *InMemoryRepo) AddPost(ctx context.Context, post *entity.Post) (*entity.Post, error) {
 	logrus.Debug("adding post")
 
 	imr.mu.Lock()
 	defer imr.mu.Unlock()
 
-	if _, ok := imr.users[input.AuthorID]; !ok {
+	if _, ok := imr.users[post.AuthorID]; !ok {
 		logrus.Error(ErrUserNotFound)
 		return nil, ErrUserNotFound
 	}
 
-	postID := len(imr.Posts) + 1
-	post := &entity.Post{
-		ID:          postID,
-		AuthorID:    input.AuthorID,
-		Title:       input.Title,
-		Content:     input.Content,
-		Created:     time.Now(),
-		Commentable: input.Commentable,
-	}
+	imr.lastPostID++
+	added := *post
+	added.ID = imr.lastPostID
 
 	logrus.Debug("added post")
 
-	imr.Posts[postID] = post
+	imr.Posts[added.ID] = &added
+	imr.indexDocument(document{kind: entity.TargetPost, id: added.ID}, postText(&added))
 
-	return post, nil
+	return &added, nil
 }
 
-func (imr *InMemoryRepo) GetPost(id int) (*entity.Post, error) {
+func (imr *InMemoryRepo) GetPost(ctx context.Context, id int) (*entity.Post, error) {
 	logrus.Debug("getting post")
 
 	imr.mu.RLock()
@@ -53,7 +47,7 @@ func (imr *InMemoryRepo) GetPost(id int) (*entity.Post, error) {
 	return post, nil
 }
 
-func (imr *InMemoryRepo) ToggleComments(postID int) (*entity.Post, error) {
+func (imr *InMemoryRepo) ToggleComments(ctx context.Context, postID int) error {
 	logrus.Debug("toggling comments")
 
 	imr.mu.Lock()
@@ -62,51 +56,66 @@ func (imr *InMemoryRepo) ToggleComments(postID int) (*entity.Post, error) {
 	post, ok := imr.Posts[postID]
 	if !ok {
 		logrus.Error(ErrPostNotFound)
-		return nil, ErrPostNotFound
+		return ErrPostNotFound
 	}
 
-	imr.Posts[postID].Commentable = !imr.Posts[postID].Commentable
+	toggled := *post
+	toggled.Commentable = !post.Commentable
+	imr.Posts[postID] = &toggled
 	logrus.Debug("toggled comments")
 
-	return post, nil
+	return nil
 }
 
-func (imr *InMemoryRepo) GetPostsPag(limit, offset *int) ([]*entity.Post, error) {
+func (imr *InMemoryRepo) GetPostsPag(ctx context.Context, page pagination.Page,
+	withHidden bool) ([]*entity.Post, error) {
 	logrus.Debug("getting posts paginated")
 
 	imr.mu.RLock()
 	defer imr.mu.RUnlock()
 
-	allPosts := make([]*entity.Post, 0, len(imr.Posts))
-	for _, post := range imr.Posts {
-		allPosts = append(allPosts, post)
+	var commentCounts map[int]int
+	if page.Sort.Ranked() {
+		commentCounts = imr.commentCounts()
 	}
 
-	sort.Slice(allPosts, func(i, j int) bool {
-		return allPosts[i].Created.Before(allPosts[j].Created)
-	})
-
-	length := len(allPosts)
-	startIdx, endIdx := 0, length
-	if offset != nil {
-		startIdx = *offset
-		if startIdx < 0 || startIdx > length {
-			startIdx = 0
+	posts := make([]*entity.Post, 0, len(imr.Posts))
+	for _, post := range imr.Posts {
+		if post.Hidden && !withHidden {
+			continue
 		}
-	}
-	if limit != nil {
-		endIdx = *limit + startIdx
-		if endIdx < 0 || endIdx > length {
-			endIdx = length
+		post = imr.scoredPost(post, commentCounts, page)
+		if page.Includes(postKey(post)) {
+			posts = append(posts, post)
 		}
 	}
-	logrus.Debug("getting posts paginated")
 
-	return allPosts[startIdx:endIdx], nil
+	sortPosts(posts, page)
+	logrus.Debug("got posts paginated")
 
+	return limitSlice(posts, page.Limit()), nil
 }
 
-func (imr *InMemoryRepo) GetComments(postID int) ([]*entity.Comment, error) {
+func (imr *InMemoryRepo) GetPostsByIDs(ctx context.Context, ids []int) ([]*entity.Post, error) {
+	logrus.Debug("getting posts by ids")
+
+	imr.mu.RLock()
+	defer imr.mu.RUnlock()
+
+	posts := make([]*entity.Post, 0, len(ids))
+	for _, id := range ids {
+		if post, ok := imr.Posts[id]; ok {
+			posts = append(posts, post)
+		}
+	}
+	logrus.Debug("got posts by ids")
+
+	return posts, nil
+}
+
+// GetComments returns all comments of the post, unless withHidden is set comments hidden together
+// with their post or a comment above them are left out.
+func (imr *InMemoryRepo) GetComments(ctx context.Context, postID int, withHidden bool) ([]*entity.Comment, error) {
 	logrus.Debug("getting comments")
 
 	imr.mu.RLock()
@@ -114,7 +123,7 @@ func (imr *InMemoryRepo) GetComments(postID int) ([]*entity.Comment, error) {
 
 	var comments []*entity.Comment
 	for _, comment := range imr.comments {
-		if comment.PostID == postID {
+		if comment.PostID == postID && (withHidden || !imr.hiddenThread(comment)) {
 			comments = append(comments, comment)
 		}
 	}
@@ -123,7 +132,8 @@ func (imr *InMemoryRepo) GetComments(postID int) ([]*entity.Comment, error) {
 	return comments, nil
 }
 
-func (imr *InMemoryRepo) GetRootCommentsPag(postID int, limit, offset *int) ([]*entity.Comment, error) {
+func (imr *InMemoryRepo) GetRootCommentsPag(ctx context.Context, postID int, page pagination.Page,
+	withHidden bool) ([]*entity.Comment, error) {
 	logrus.Debug("getting root comments paginated")
 
 	imr.mu.RLock()
@@ -131,26 +141,116 @@ func (imr *InMemoryRepo) GetRootCommentsPag(postID int, limit, offset *int) ([]*
 
 	var roots []*entity.Comment
 	for _, comment := range imr.comments {
-		if comment.PostID == postID && comment.ParentID == nil {
+		if comment.PostID != postID || comment.ParentID != nil || comment.Hidden && !withHidden {
+			continue
+		}
+		comment = imr.scoredComment(comment, page)
+		if page.Includes(commentKey(comment)) {
 			roots = append(roots, comment)
 		}
 	}
 
-	length := len(roots)
-	startIdx, endIdx := 0, length
-	if offset != nil {
-		startIdx = *offset
-		if startIdx < 0 || startIdx > length {
-			startIdx = 0
+	sortComments(roots, page)
+
+	logrus.Debug("got root comments paginated")
+	return limitSlice(roots, page.Limit()), nil
+}
+
+// GetRootCommentsByPostIDs returns the first page of root comments of every given post.
+func (imr *InMemoryRepo) GetRootCommentsByPostIDs(ctx context.Context, postIDs []int, page pagination.Page,
+	withHidden bool) ([]*entity.Comment, error) {
+	logrus.Debug("getting root comments for posts")
+
+	imr.mu.RLock()
+	defer imr.mu.RUnlock()
+
+	rootsByPost := make(map[int][]*entity.Comment, len(postIDs))
+	for _, postID := range postIDs {
+		rootsByPost[postID] = nil
+	}
+	for _, comment := range imr.comments {
+		if comment.Hidden && !withHidden {
+			continue
 		}
+		if _, ok := rootsByPost[comment.PostID]; ok && comment.ParentID == nil {
+			rootsByPost[comment.PostID] = append(rootsByPost[comment.PostID], imr.scoredComment(comment, page))
+		}
+	}
+
+	var roots []*entity.Comment
+	for _, comments := range rootsByPost {
+		sortComments(comments, page)
+		roots = append(roots, limitSlice(comments, page.Limit())...)
+	}
+
+	logrus.Debug("got root comments for posts")
+	return roots, nil
+}
+
+func (imr *InMemoryRepo) UpdatePost(ctx context.Context, post *entity.Post) error {
+	logrus.Debug("updating post")
+
+	imr.mu.Lock()
+	defer imr.mu.Unlock()
+
+	stored, ok := imr.Posts[post.ID]
+	if !ok {
+		logrus.Error(ErrPostNotFound)
+		return ErrPostNotFound
 	}
-	if limit != nil {
-		endIdx = *limit + startIdx
-		if endIdx < 0 || endIdx > length {
-			endIdx = length
+
+	updated := *stored
+	updated.Title = post.Title
+	updated.Content = post.Content
+	updated.Edited = post.Edited
+	imr.Posts[post.ID] = &updated
+	imr.unindexDocument(document{kind: entity.TargetPost, id: post.ID}, postText(stored))
+	imr.indexDocument(document{kind: entity.TargetPost, id: post.ID}, postText(&updated))
+	logrus.Debug("updated post")
+
+	return nil
+}
+
+// DeletePost removes the post together with all of its comments.
+func (imr *InMemoryRepo) DeletePost(ctx context.Context, postID int) error {
+	logrus.Debug("deleting post")
+
+	imr.mu.Lock()
+	defer imr.mu.Unlock()
+
+	post, ok := imr.Posts[postID]
+	if !ok {
+		logrus.Error(ErrPostNotFound)
+		return ErrPostNotFound
+	}
+
+	removed := make(map[int]struct{})
+	for id, comment := range imr.comments {
+		if comment.PostID == postID {
+			removed[id] = struct{}{}
+			if !comment.Deleted {
+				imr.unindexDocument(document{kind: entity.TargetComment, id: id}, comment.Content)
+			}
+			delete(imr.comments, id)
+			delete(imr.replies, id)
+			delete(imr.revisions, id)
+			delete(imr.reactions, entity.ReactionTarget{Kind: entity.TargetComment, ID: id})
+			delete(imr.mentions, entity.ReactionTarget{Kind: entity.TargetComment, ID: id})
 		}
 	}
+	imr.dropNotifications(removed)
+	imr.dropReports(func(target entity.ReactionTarget) bool {
+		if target.Kind == entity.TargetComment {
+			_, ok := removed[target.ID]
+			return ok
+		}
+		return target.ID == postID
+	})
+	delete(imr.Posts, postID)
+	imr.unindexDocument(document{kind: entity.TargetPost, id: postID}, postText(post))
+	delete(imr.reactions, entity.ReactionTarget{Kind: entity.TargetPost, ID: postID})
+	delete(imr.mentions, entity.ReactionTarget{Kind: entity.TargetPost, ID: postID})
+	logrus.Debug("deleted post")
 
-	logrus.Debug("got root comments paginated")
-	return roots[startIdx:endIdx], nil
+	return nil
 }
@@ -0,0 +1,76 @@
+package imrepo
+
+import (
+	"Commentary/internal/entity"
+	"Commentary/internal/pagination"
+	"sort"
+	"time"
+)
+
+func postKey(post *entity.Post) pagination.Key {
+	return pagination.Key{Created: post.Created, ID: post.ID, Score: post.Score}
+}
+
+func commentKey(comment *entity.Comment) pagination.Key {
+	return pagination.Key{Created: comment.Created, ID: comment.ID, Score: comment.Score}
+}
+
+func sortPosts(posts []*entity.Post, page pagination.Page) {
+	sort.Slice(posts, func(i, j int) bool {
+		return page.Less(postKey(posts[i]), postKey(posts[j]))
+	})
+}
+
+func sortComments(comments []*entity.Comment, page pagination.Page) {
+	sort.Slice(comments, func(i, j int) bool {
+		return page.Less(commentKey(comments[i]), commentKey(comments[j]))
+	})
+}
+
+// score computes the ranking score of a post or a comment for the page sort.
+// Must be called with the lock held.
+func (imr *InMemoryRepo) score(target entity.ReactionTarget, replies int, created time.Time,
+	page pagination.Page) float64 {
+	var positive, negative int
+	for reactionType, users := range imr.reactions[target] {
+		if imr.ranking.IsNegative(reactionType) {
+			negative += len(users)
+		} else {
+			positive += len(users)
+		}
+	}
+	return imr.ranking.Score(page.Sort, positive, negative, replies, created, page.Now)
+}
+
+// scoredPost returns the post itself for chronological sorts and its scored copy for ranked ones.
+// Must be called with the lock held.
+func (imr *InMemoryRepo) scoredPost(post *entity.Post, commentCounts map[int]int,
+	page pagination.Page) *entity.Post {
+	if !page.Sort.Ranked() {
+		return post
+	}
+	scored := *post
+	scored.Score = imr.score(entity.ReactionTarget{Kind: entity.TargetPost, ID: post.ID},
+		commentCounts[post.ID], post.Created, page)
+	return &scored
+}
+
+// scoredComment is like scoredPost for comments. Must be called with the lock held.
+func (imr *InMemoryRepo) scoredComment(comment *entity.Comment, page pagination.Page) *entity.Comment {
+	if !page.Sort.Ranked() {
+		return comment
+	}
+	scored := *comment
+	scored.Score = imr.score(entity.ReactionTarget{Kind: entity.TargetComment, ID: comment.ID},
+		len(imr.replies[comment.ID]), comment.Created, page)
+	return &scored
+}
+
+// commentCounts counts comments of every post. Must be called with the lock held.
+func (imr *InMemoryRepo) commentCounts() map[int]int {
+	counts := make(map[int]int, len(imr.Posts))
+	for _, comment := range imr.comments {
+		counts[comment.PostID]++
+	}
+	return counts
+}
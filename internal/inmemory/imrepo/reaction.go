@@ -0,0 +1,93 @@
+package imrepo
+
+import (
+	"Commentary/internal/entity"
+	"context"
+	"github.com/sirupsen/logrus"
+	"sort"
+)
+
+// AddReaction saves the reaction, reacting twice with the same type is a no-op.
+func (imr *InMemoryRepo) AddReaction(ctx context.Context, reaction *entity.Reaction) error {
+	logrus.Debug("adding reaction")
+
+	imr.mu.Lock()
+	defer imr.mu.Unlock()
+
+	if !imr.targetExists(reaction.Target) {
+		logrus.Error(ErrReactionTargetNotFound)
+		return ErrReactionTargetNotFound
+	}
+
+	byType, ok := imr.reactions[reaction.Target]
+	if !ok {
+		byType = make(map[string]map[int]struct{})
+		imr.reactions[reaction.Target] = byType
+	}
+	if _, ok = byType[reaction.Type]; !ok {
+		byType[reaction.Type] = make(map[int]struct{})
+	}
+	byType[reaction.Type][reaction.UserID] = struct{}{}
+	logrus.Debug("added reaction")
+
+	return nil
+}
+
+func (imr *InMemoryRepo) RemoveReaction(ctx context.Context, userID int, target entity.ReactionTarget,
+	reactionType string) error {
+	logrus.Debug("removing reaction")
+
+	imr.mu.Lock()
+	defer imr.mu.Unlock()
+
+	users := imr.reactions[target][reactionType]
+	delete(users, userID)
+	if len(users) == 0 {
+		delete(imr.reactions[target], reactionType)
+	}
+	logrus.Debug("removed reaction")
+
+	return nil
+}
+
+// GetReactionCounts counts reactions of every type left on the given targets. Targets and types
+// without reactions are omitted.
+func (imr *InMemoryRepo) GetReactionCounts(ctx context.Context, kind entity.TargetKind, ids []int,
+	viewerID int) ([]*entity.ReactionCount, error) {
+	logrus.Debug("counting reactions")
+
+	imr.mu.RLock()
+	defer imr.mu.RUnlock()
+
+	var counts []*entity.ReactionCount
+	for _, id := range ids {
+		byType := imr.reactions[entity.ReactionTarget{Kind: kind, ID: id}]
+		types := make([]string, 0, len(byType))
+		for reactionType := range byType {
+			types = append(types, reactionType)
+		}
+		sort.Strings(types)
+
+		for _, reactionType := range types {
+			_, reacted := byType[reactionType][viewerID]
+			counts = append(counts, &entity.ReactionCount{
+				TargetID:      id,
+				Type:          reactionType,
+				Count:         len(byType[reactionType]),
+				ViewerReacted: reacted,
+			})
+		}
+	}
+	logrus.Debug("counted reactions")
+
+	return counts, nil
+}
+
+func (imr *InMemoryRepo) targetExists(target entity.ReactionTarget) bool {
+	if target.Kind == entity.TargetComment {
+		_, ok := imr.comments[target.ID]
+		return ok
+	}
+	_, ok := imr.Posts[target.ID]
+	return ok
+}
@@ -0,0 +1,171 @@
+package imrepo
+
+import (
+	"Commentary/internal/entity"
+	"Commentary/internal/pagination"
+	"context"
+	"github.com/sirupsen/logrus"
+	"html"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	// snippetWords is the maximum length of a snippet, snippetLead is the number
+	// of words kept before the first match.
+	snippetWords = 35
+	snippetLead  = 5
+)
+
+// document is a post or a comment in the search index.
+type document struct {
+	kind entity.TargetKind
+	id   int
+}
+
+// words splits the text into lowercase words, the same way for documents and queries.
+func words(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func postText(post *entity.Post) string {
+	return post.Title + " " + post.Content
+}
+
+// indexDocument must be called with lock held.
+func (imr *InMemoryRepo) indexDocument(doc document, text string) {
+	docWords := words(text)
+	for _, word := range docWords {
+		if imr.index[word] == nil {
+			imr.index[word] = make(map[document]int)
+		}
+		imr.index[word][doc]++
+	}
+	imr.lengths[doc] = len(docWords)
+}
+
+// unindexDocument removes the document indexed with the given text.
+// Must be called with lock held.
+func (imr *InMemoryRepo) unindexDocument(doc document, text string) {
+	for _, word := range words(text) {
+		delete(imr.index[word], doc)
+		if len(imr.index[word]) == 0 {
+			delete(imr.index, word)
+		}
+	}
+	delete(imr.lengths, doc)
+}
+
+// Search finds posts and comments of the given kinds containing all words of the text.
+// Hits are ranked by the share of their words matching the query. Hidden posts and comments,
+// with the comments of hidden posts and under hidden comments, are found only withHidden.
+func (imr *InMemoryRepo) Search(ctx context.Context, text string, kinds []entity.TargetKind,
+	page pagination.SearchPage, withHidden bool) ([]*entity.SearchHit, error) {
+	logrus.WithField("kinds", kinds).Debug("searching")
+
+	imr.mu.RLock()
+	defer imr.mu.RUnlock()
+
+	terms := make(map[string]struct{})
+	for _, word := range words(text) {
+		terms[word] = struct{}{}
+	}
+	if len(terms) == 0 {
+		return nil, nil
+	}
+
+	wanted := make(map[entity.TargetKind]bool, len(kinds))
+	for _, kind := range kinds {
+		wanted[kind] = true
+	}
+
+	matches := make(map[document]int)
+	first := true
+	for term := range terms {
+		next := make(map[document]int)
+		for doc, count := range imr.index[term] {
+			if _, ok := matches[doc]; first || ok {
+				next[doc] = matches[doc] + count
+			}
+		}
+		matches, first = next, false
+	}
+
+	var hits []*entity.SearchHit
+	for doc, count := range matches {
+		if !withHidden && imr.hiddenDocument(doc) {
+			continue
+		}
+		hit := &entity.SearchHit{
+			Kind: doc.kind,
+			ID:   doc.id,
+			Rank: float64(count) / float64(imr.lengths[doc]),
+		}
+		if wanted[doc.kind] && page.Includes(searchKey(hit)) {
+			hits = append(hits, hit)
+		}
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		return pagination.SearchLess(searchKey(hits[i]), searchKey(hits[j]))
+	})
+	hits = limitSlice(hits, page.Limit())
+
+	for _, hit := range hits {
+		if hit.Kind == entity.TargetPost {
+			hit.Snippet = snippet(postText(imr.Posts[hit.ID]), terms)
+		} else {
+			hit.Snippet = snippet(imr.comments[hit.ID].Content, terms)
+		}
+	}
+	logrus.WithField("count", len(hits)).Debug("searched")
+
+	return hits, nil
+}
+
+// hiddenDocument must be called with lock held.
+func (imr *InMemoryRepo) hiddenDocument(doc document) bool {
+	if doc.kind == entity.TargetPost {
+		return imr.Posts[doc.id].Hidden
+	}
+	return imr.hiddenThread(imr.comments[doc.id])
+}
+
+func searchKey(hit *entity.SearchHit) pagination.SearchCursor {
+	return pagination.SearchCursor{Rank: hit.Rank, Kind: string(hit.Kind), ID: hit.ID}
+}
+
+// snippet cuts a fragment of the text around its first match, escapes it and highlights matched words.
+func snippet(text string, terms map[string]struct{}) string {
+	fields := strings.Fields(text)
+	matched := func(field string) bool {
+		for _, word := range words(field) {
+			if _, ok := terms[word]; ok {
+				return true
+			}
+		}
+		return false
+	}
+
+	start := 0
+	for i, field := range fields {
+		if matched(field) {
+			start = max(0, i-snippetLead)
+			break
+		}
+	}
+	end := min(len(fields), start+snippetWords)
+
+	fragment := make([]string, 0, end-start)
+	for _, field := range fields[start:end] {
+		escaped := html.EscapeString(field)
+		if matched(field) {
+			escaped = entity.SnippetStart + escaped + entity.SnippetStop
+		}
+		fragment = append(fragment, escaped)
+	}
+	return strings.Join(fragment, " ")
+}
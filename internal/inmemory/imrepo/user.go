@@ -3,10 +3,11 @@ package imrepo
 import (
 	"Commentary/internal/entity"
 	"Commentary/internal/graph/model"
+	"context"
 	"github.com/sirupsen/logrus"
 )
 
-func (imr *InMemoryRepo) AddUser(username string) (*entity.User, error) {
+func (imr *InMemoryRepo) AddUser(ctx context.Context, username, passwordHash string) (*model.User, error) {
 	logrus.Debug("adding user")
 
 	imr.mu.Lock()
@@ -18,33 +19,50 @@ func (imr *InMemoryRepo) AddUser(username string) (*entity.User, error) {
 
 	id := len(imr.users) + 1
 	user := &entity.User{
-		ID:       id,
-		Username: username,
+		ID:           id,
+		Username:     username,
+		PasswordHash: passwordHash,
+		Role:         entity.RoleUser,
 	}
 
 	imr.users[id] = user
-	imr.nicknames[username] = struct{}{}
+	imr.nicknames[username] = id
 
 	logrus.Debug("added user")
 
-	return user, nil
+	return &model.User{ID: id, Username: username}, nil
 }
 
-func (imr *InMemoryRepo) GetUser(userID int) (*entity.User, error) {
+func (imr *InMemoryRepo) GetUserByID(ctx context.Context, userID int) (*model.User, error) {
 	logrus.Debug("getting user")
 
 	imr.mu.RLock()
 	defer imr.mu.RUnlock()
 
 	if user, ok := imr.users[userID]; ok {
-		return user, nil
+		return &model.User{ID: user.ID, Username: user.Username}, nil
 	}
 	logrus.Debug("got user")
 
 	return nil, ErrUserNotFound
 }
 
-func (imr *InMemoryRepo) GetUsersByIDs(ids []int) (map[int]*model.User, error) {
+func (imr *InMemoryRepo) GetUserByUsername(ctx context.Context, username string) (*entity.User, error) {
+	logrus.Debug("getting user by username")
+
+	imr.mu.RLock()
+	defer imr.mu.RUnlock()
+
+	id, ok := imr.nicknames[username]
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	logrus.Debug("got user by username")
+
+	return imr.users[id], nil
+}
+
+func (imr *InMemoryRepo) GetUsersByIDs(ctx context.Context, ids []int) (map[int]*model.User, error) {
 	logrus.Debug("getting users by ids")
 
 	imr.mu.RLock()
@@ -63,3 +81,51 @@ func (imr *InMemoryRepo) GetUsersByIDs(ids []int) (map[int]*model.User, error) {
 
 	return users, nil
 }
+
+// GetUsersByUsernames returns the users with the given usernames, unknown usernames are skipped.
+func (imr *InMemoryRepo) GetUsersByUsernames(ctx context.Context, usernames []string) ([]*model.User, error) {
+	logrus.Debug("getting users by usernames")
+
+	imr.mu.RLock()
+	defer imr.mu.RUnlock()
+
+	var users []*model.User
+	for _, username := range usernames {
+		if id, ok := imr.nicknames[username]; ok {
+			users = append(users, &model.User{ID: id, Username: username})
+		}
+	}
+	logrus.Debug("got users by usernames")
+
+	return users, nil
+}
+
+func (imr *InMemoryRepo) SetRole(ctx context.Context, userID int, role entity.Role) error {
+	logrus.Debug("setting user role")
+
+	imr.mu.Lock()
+	defer imr.mu.Unlock()
+
+	user, ok := imr.users[userID]
+	if !ok {
+		return ErrUserNotFound
+	}
+	updated := *user
+	updated.Role = role
+	imr.users[userID] = &updated
+	logrus.Debug("set user role")
+
+	return nil
+}
+
+// GetRole returns the role of the user.
+func (imr *InMemoryRepo) GetRole(ctx context.Context, userID int) (entity.Role, error) {
+	imr.mu.RLock()
+	defer imr.mu.RUnlock()
+
+	user, ok := imr.users[userID]
+	if !ok {
+		return "", ErrUserNotFound
+	}
+	return user.Role, nil
+}
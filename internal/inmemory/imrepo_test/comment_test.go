@@ -2,25 +2,30 @@ package imrepo
 
 import (
 	"Commentary/internal/entity"
-	"Commentary/internal/graph/model"
 	"Commentary/internal/inmemory/imrepo"
+	"Commentary/internal/pagination"
+	"context"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"testing"
+	"time"
 )
 
 func TestAddComment(t *testing.T) {
-	repo := imrepo.NewInMemoryRepo()
-	user, _ := repo.AddUser("user1")
+	repo := imrepo.NewInMemoryRepo(pagination.Ranking{})
+	ctx := context.Background()
+	user, _ := repo.AddUser(ctx, "user1", "hash")
 	repo.Posts[1] = &entity.Post{ID: 1, Title: "Post1"}
 
-	input := model.CreateCommentInput{
+	input := &entity.Comment{
 		PostID:   1,
 		AuthorID: user.ID,
 		Content:  "Comment1",
 	}
 
-	comment, err := repo.AddComment(input)
+	id, err := repo.AddComment(ctx, input)
+	require.NoError(t, err)
+	comment, err := repo.GetCommentByID(ctx, id)
 	require.NoError(t, err)
 	assert.NotNil(t, comment)
 	assert.Equal(t, input.Content, comment.Content)
@@ -28,28 +33,154 @@ func TestAddComment(t *testing.T) {
 }
 
 func TestGetComment(t *testing.T) {
-	repo := imrepo.NewInMemoryRepo()
+	repo := imrepo.NewInMemoryRepo(pagination.Ranking{})
+	ctx := context.Background()
 
-	_, err := repo.GetComment(5)
+	_, err := repo.GetCommentByID(ctx, 5)
 	assert.Error(t, err)
 	assert.Equal(t, imrepo.ErrCommentNotFound, err)
 }
 
 func TestGetCommentsByPostID(t *testing.T) {
-	repo := imrepo.NewInMemoryRepo()
-	user, _ := repo.AddUser("testuser")
+	repo := imrepo.NewInMemoryRepo(pagination.Ranking{})
+	ctx := context.Background()
+	user, _ := repo.AddUser(ctx, "testuser", "hash")
 	repo.Posts[1] = &entity.Post{ID: 1, Title: "Post1"}
 
-	input := model.CreateCommentInput{
+	input := &entity.Comment{
 		PostID:   1,
 		AuthorID: user.ID,
 		Content:  "Comment1",
-		Parent:   nil,
+		ParentID: nil,
 	}
-	comment, _ := repo.AddComment(input)
+	_, _ = repo.AddComment(ctx, input)
 
-	comments, err := repo.GetCommentsByPostID(1)
+	comments, err := repo.GetComments(ctx, 1, false)
 	require.NoError(t, err)
 	assert.Len(t, comments, 1)
-	assert.Equal(t, comment.Content, comments[0].Content)
+	assert.Equal(t, input.Content, comments[0].Content)
+}
+
+func TestGetReplies(t *testing.T) {
+	repo := imrepo.NewInMemoryRepo(pagination.Ranking{})
+	ctx := context.Background()
+	user, _ := repo.AddUser(ctx, "testuser", "hash")
+	repo.Posts[1] = &entity.Post{ID: 1, Title: "Post1"}
+
+	rootID, _ := repo.AddComment(ctx, &entity.Comment{PostID: 1, AuthorID: user.ID, Content: "root"})
+	replyID, _ := repo.AddComment(ctx, &entity.Comment{PostID: 1, AuthorID: user.ID, Content: "reply", ParentID: &rootID})
+	_, _ = repo.AddComment(ctx, &entity.Comment{PostID: 1, AuthorID: user.ID, Content: "nested", ParentID: &replyID})
+
+	replies, err := repo.GetReplies(ctx, []int{rootID}, 1, pagination.Page{}, false)
+	require.NoError(t, err)
+	require.Len(t, replies[rootID], 1)
+	assert.Equal(t, "reply", replies[rootID][0].Content)
+
+	replies, err = repo.GetReplies(ctx, []int{rootID}, 2, pagination.Page{}, false)
+	require.NoError(t, err)
+	assert.Len(t, replies[rootID], 2)
+
+	first := 1
+	replies, err = repo.GetReplies(ctx, []int{rootID, replyID}, 2, pagination.Page{First: &first}, false)
+	require.NoError(t, err)
+	require.Len(t, replies[rootID], 2)
+	require.Len(t, replies[replyID], 1)
+	assert.Equal(t, "nested", replies[replyID][0].Content)
+
+	counts, err := repo.GetReplyCounts(ctx, []int{rootID, replyID}, false)
+	require.NoError(t, err)
+	assert.Equal(t, 1, counts[rootID])
+	assert.Equal(t, 1, counts[replyID])
+
+	require.NoError(t, repo.SetHidden(ctx, entity.ReactionTarget{Kind: entity.TargetComment, ID: replyID}, true))
+	counts, err = repo.GetReplyCounts(ctx, []int{rootID}, false)
+	require.NoError(t, err)
+	assert.Zero(t, counts[rootID])
+	counts, err = repo.GetReplyCounts(ctx, []int{rootID}, true)
+	require.NoError(t, err)
+	assert.Equal(t, 1, counts[rootID])
+}
+
+func TestDeleteComment(t *testing.T) {
+	repo := imrepo.NewInMemoryRepo(pagination.Ranking{})
+	ctx := context.Background()
+	user, _ := repo.AddUser(ctx, "user1", "hash")
+	repo.Posts[1] = &entity.Post{ID: 1, Title: "Post1"}
+
+	rootID, _ := repo.AddComment(ctx, &entity.Comment{PostID: 1, AuthorID: user.ID, Content: "root"})
+	replyID, _ := repo.AddComment(ctx, &entity.Comment{PostID: 1, AuthorID: user.ID, Content: "reply", ParentID: &rootID})
+
+	tombstoned, err := repo.DeleteComment(ctx, rootID)
+	require.NoError(t, err)
+	assert.True(t, tombstoned)
+
+	stored, err := repo.GetCommentByID(ctx, rootID)
+	require.NoError(t, err)
+	assert.True(t, stored.Deleted)
+	assert.Equal(t, entity.DeletedCommentContent, stored.Content)
+
+	tombstoned, err = repo.DeleteComment(ctx, replyID)
+	require.NoError(t, err)
+	assert.False(t, tombstoned)
+
+	_, err = repo.GetCommentByID(ctx, replyID)
+	assert.ErrorIs(t, err, imrepo.ErrCommentNotFound)
+	counts, _ := repo.GetReplyCounts(ctx, []int{rootID}, false)
+	assert.Zero(t, counts[rootID])
+
+	nextID, _ := repo.AddComment(ctx, &entity.Comment{PostID: 1, AuthorID: user.ID, Content: "next"})
+	assert.Greater(t, nextID, replyID)
+}
+
+func TestUpdateCommentKeepsRevisions(t *testing.T) {
+	repo := imrepo.NewInMemoryRepo(pagination.Ranking{})
+	ctx := context.Background()
+	user, _ := repo.AddUser(ctx, "user1", "hash")
+	repo.Posts[1] = &entity.Post{ID: 1, Title: "Post1"}
+
+	commentID, _ := repo.AddComment(ctx, &entity.Comment{PostID: 1, AuthorID: user.ID, Content: "v1"})
+	comment, _ := repo.GetCommentByID(ctx, commentID)
+	for _, content := range []string{"v2", "v3"} {
+		edited := time.Now()
+		updated := *comment
+		updated.Content = content
+		updated.Edited = &edited
+		require.NoError(t, repo.UpdateComment(ctx, &updated, user.ID))
+	}
+
+	stored, _ := repo.GetCommentByID(ctx, commentID)
+	assert.Equal(t, "v3", stored.Content)
+
+	revisions, err := repo.GetRevisions(ctx, []int{commentID})
+	require.NoError(t, err)
+	require.Len(t, revisions, 2)
+	assert.Equal(t, "v1", revisions[0].Content)
+	assert.Equal(t, "v2", revisions[1].Content)
+	assert.Equal(t, user.ID, revisions[0].EditorID)
+}
+
+func TestGetCommentsSince(t *testing.T) {
+	repo := imrepo.NewInMemoryRepo(pagination.Ranking{})
+	ctx := context.Background()
+	user, _ := repo.AddUser(ctx, "testuser", "hash")
+	repo.Posts[1] = &entity.Post{ID: 1, Title: "Post1", Commentable: true}
+	repo.Posts[2] = &entity.Post{ID: 2, Title: "Post2", Commentable: true}
+
+	var ids []int
+	for _, postID := range []int{1, 2, 1, 1} {
+		commentID, err := repo.AddComment(ctx, &entity.Comment{PostID: postID, AuthorID: user.ID, Content: "comment",
+			Created: time.Now()})
+		require.NoError(t, err)
+		ids = append(ids, commentID)
+	}
+
+	comments, err := repo.GetCommentsSince(ctx, 1, pagination.Since{CommentID: ids[0]}, 10, false)
+	require.NoError(t, err)
+	require.Len(t, comments, 2)
+	assert.Equal(t, []int{ids[2], ids[3]}, []int{comments[0].ID, comments[1].ID})
+
+	comments, err = repo.GetCommentsSince(ctx, 1, pagination.Since{}, 2, false)
+	require.NoError(t, err)
+	require.Len(t, comments, 2)
+	assert.Equal(t, []int{ids[0], ids[2]}, []int{comments[0].ID, comments[1].ID})
 }
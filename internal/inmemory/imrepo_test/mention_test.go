@@ -0,0 +1,41 @@
+package imrepo
+
+import (
+	"Commentary/internal/entity"
+	"Commentary/internal/inmemory/imrepo"
+	"Commentary/internal/pagination"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestReplaceMentions(t *testing.T) {
+	repo := imrepo.NewInMemoryRepo(pagination.Ranking{})
+	ctx := context.Background()
+	author, _ := repo.AddUser(ctx, "author", "hash")
+	reader, _ := repo.AddUser(ctx, "reader", "hash")
+	post, _ := repo.AddPost(ctx, &entity.Post{AuthorID: author.ID, Title: "Post1", Commentable: true})
+	commentID, _ := repo.AddComment(ctx, &entity.Comment{PostID: post.ID, AuthorID: author.ID, Content: "@reader @author"})
+
+	target := entity.ReactionTarget{Kind: entity.TargetComment, ID: commentID}
+	require.NoError(t, repo.ReplaceMentions(ctx, target, []*entity.Mention{
+		{UserID: reader.ID, Offset: 0, Length: 7},
+		{UserID: author.ID, Offset: 8, Length: 7},
+	}))
+	require.NoError(t, repo.ReplaceMentions(ctx, target, []*entity.Mention{{UserID: reader.ID, Offset: 0, Length: 7}}))
+
+	mentions, err := repo.GetMentions(ctx, entity.TargetComment, []int{commentID})
+	require.NoError(t, err)
+	require.Len(t, mentions, 1)
+	assert.Equal(t, target, mentions[0].Target)
+
+	_, err = repo.DeleteComment(ctx, commentID)
+	require.NoError(t, err)
+	mentions, err = repo.GetMentions(ctx, entity.TargetComment, []int{commentID})
+	require.NoError(t, err)
+	assert.Empty(t, mentions)
+
+	err = repo.ReplaceMentions(ctx, entity.ReactionTarget{Kind: entity.TargetPost, ID: 100}, nil)
+	assert.ErrorIs(t, err, imrepo.ErrReactionTargetNotFound)
+}
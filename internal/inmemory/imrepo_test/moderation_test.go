@@ -0,0 +1,97 @@
+package imrepo
+
+import (
+	"Commentary/internal/entity"
+	"Commentary/internal/inmemory/imrepo"
+	"Commentary/internal/pagination"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestAddReport(t *testing.T) {
+	repo := imrepo.NewInMemoryRepo(pagination.Ranking{})
+	ctx := context.Background()
+	user, _ := repo.AddUser(ctx, "user1", "hash")
+	post, _ := repo.AddPost(ctx, &entity.Post{AuthorID: user.ID, Title: "Post1", Content: "Post1"})
+	target := entity.ReactionTarget{Kind: entity.TargetPost, ID: post.ID}
+
+	first := &entity.Report{Target: target, ReporterID: &user.ID, Reason: "spam", Created: time.Now()}
+	require.NoError(t, repo.AddReport(ctx, first))
+	assert.NotZero(t, first.ID)
+
+	again := &entity.Report{Target: target, ReporterID: &user.ID, Reason: "still spam", Created: time.Now()}
+	require.NoError(t, repo.AddReport(ctx, again))
+	assert.Zero(t, again.ID)
+
+	missing := &entity.Report{Target: entity.ReactionTarget{Kind: entity.TargetComment, ID: 9}, ReporterID: &user.ID,
+		Reason: "spam", Created: time.Now()}
+	assert.Equal(t, imrepo.ErrReactionTargetNotFound, repo.AddReport(ctx, missing))
+
+	reports, err := repo.GetOpenReports(ctx, pagination.Page{})
+	require.NoError(t, err)
+	require.Len(t, reports, 1)
+	assert.Equal(t, "spam", reports[0].Reason)
+
+	require.NoError(t, repo.ResolveReports(ctx, target))
+	reports, err = repo.GetOpenReports(ctx, pagination.Page{})
+	require.NoError(t, err)
+	assert.Empty(t, reports)
+}
+
+func TestSetHiddenSkipsReplies(t *testing.T) {
+	repo := imrepo.NewInMemoryRepo(pagination.Ranking{})
+	ctx := context.Background()
+	user, _ := repo.AddUser(ctx, "user1", "hash")
+	repo.Posts[1] = &entity.Post{ID: 1, Title: "Post1"}
+
+	rootID, _ := repo.AddComment(ctx, &entity.Comment{PostID: 1, AuthorID: user.ID, Content: "root"})
+	replyID, _ := repo.AddComment(ctx, &entity.Comment{PostID: 1, AuthorID: user.ID, Content: "reply", ParentID: &rootID})
+	_, _ = repo.AddComment(ctx, &entity.Comment{PostID: 1, AuthorID: user.ID, Content: "nested", ParentID: &replyID})
+	reply, _ := repo.GetCommentByID(ctx, replyID)
+
+	require.NoError(t, repo.SetHidden(ctx, entity.ReactionTarget{Kind: entity.TargetComment, ID: replyID}, true))
+	assert.False(t, reply.Hidden)
+
+	replies, err := repo.GetReplies(ctx, []int{rootID}, 2, pagination.Page{}, false)
+	require.NoError(t, err)
+	assert.Empty(t, replies)
+
+	replies, err = repo.GetReplies(ctx, []int{rootID}, 2, pagination.Page{}, true)
+	require.NoError(t, err)
+	assert.Len(t, replies[rootID], 2)
+}
+
+func TestDeletePostDropsReports(t *testing.T) {
+	repo := imrepo.NewInMemoryRepo(pagination.Ranking{})
+	ctx := context.Background()
+	user, _ := repo.AddUser(ctx, "user1", "hash")
+	post, _ := repo.AddPost(ctx, &entity.Post{AuthorID: user.ID, Title: "Post1", Content: "Post1"})
+
+	report := &entity.Report{Target: entity.ReactionTarget{Kind: entity.TargetPost, ID: post.ID},
+		ReporterID: &user.ID, Reason: "spam", Created: time.Now()}
+	require.NoError(t, repo.AddReport(ctx, report))
+	require.NoError(t, repo.DeletePost(ctx, post.ID))
+
+	reports, err := repo.GetOpenReports(ctx, pagination.Page{})
+	require.NoError(t, err)
+	assert.Empty(t, reports)
+}
+
+func TestAddFlagReports(t *testing.T) {
+	repo := imrepo.NewInMemoryRepo(pagination.Ranking{})
+	ctx := context.Background()
+	user, _ := repo.AddUser(ctx, "user1", "hash")
+	post, _ := repo.AddPost(ctx, &entity.Post{AuthorID: user.ID, Title: "Post1", Content: "Post1"})
+	target := entity.ReactionTarget{Kind: entity.TargetPost, ID: post.ID}
+
+	require.NoError(t, repo.AddReport(ctx, &entity.Report{Target: target, Reason: "links", Created: time.Now()}))
+	require.NoError(t, repo.AddReport(ctx, &entity.Report{Target: target, Reason: "words", Created: time.Now()}))
+
+	reports, err := repo.GetOpenReports(ctx, pagination.Page{})
+	require.NoError(t, err)
+	require.Len(t, reports, 2)
+	assert.Nil(t, reports[0].ReporterID)
+}
@@ -0,0 +1,58 @@
+package imrepo
+
+import (
+	"Commentary/internal/entity"
+	"Commentary/internal/inmemory/imrepo"
+	"Commentary/internal/pagination"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestNotifications(t *testing.T) {
+	repo := imrepo.NewInMemoryRepo(pagination.Ranking{})
+	ctx := context.Background()
+	author, _ := repo.AddUser(ctx, "author", "hash")
+	reader, _ := repo.AddUser(ctx, "reader", "hash")
+	post, _ := repo.AddPost(ctx, &entity.Post{AuthorID: author.ID, Title: "Post1", Commentable: true})
+
+	var comments []*entity.Comment
+	for i := 0; i < 3; i++ {
+		comment := &entity.Comment{PostID: post.ID, AuthorID: author.ID, Content: "@reader", Created: time.Now()}
+		id, err := repo.AddComment(ctx, comment)
+		require.NoError(t, err)
+		comment.ID = id
+		comments = append(comments, comment)
+		require.NoError(t, repo.AddNotifications(ctx, []*entity.Notification{{UserID: reader.ID,
+			Kind: entity.NotificationMention, CommentID: comment.ID, ActorID: author.ID, Created: comment.Created}}))
+	}
+
+	first := 2
+	page := pagination.Page{First: &first, Sort: pagination.SortNewest}
+	notifications, err := repo.GetNotifications(ctx, reader.ID, false, page, false)
+	require.NoError(t, err)
+	require.Len(t, notifications, 3)
+	assert.Equal(t, comments[2].ID, notifications[0].CommentID)
+
+	marked, err := repo.MarkNotificationsRead(ctx, reader.ID, []int{notifications[0].ID})
+	require.NoError(t, err)
+	assert.Equal(t, 1, marked)
+
+	page.After = &pagination.Cursor{Created: notifications[0].Created, ID: notifications[0].ID}
+	unread, err := repo.GetNotifications(ctx, reader.ID, true, page, false)
+	require.NoError(t, err)
+	require.Len(t, unread, 2)
+	assert.Equal(t, comments[1].ID, unread[0].CommentID)
+
+	_, err = repo.DeleteComment(ctx, comments[1].ID)
+	require.NoError(t, err)
+	marked, err = repo.MarkNotificationsRead(ctx, reader.ID, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1, marked)
+
+	empty, err := repo.GetNotifications(ctx, author.ID, false, pagination.Page{Sort: pagination.SortNewest}, false)
+	require.NoError(t, err)
+	assert.Empty(t, empty)
+}
@@ -2,25 +2,27 @@ package imrepo
 
 import (
 	"Commentary/internal/entity"
-	"Commentary/internal/graph/model"
 	"Commentary/internal/inmemory/imrepo"
+	"Commentary/internal/pagination"
+	"context"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"testing"
 )
 
 func TestAddPost(t *testing.T) {
-	repo := imrepo.NewInMemoryRepo()
-	user, _ := repo.AddUser("user1")
+	repo := imrepo.NewInMemoryRepo(pagination.Ranking{})
+	ctx := context.Background()
+	user, _ := repo.AddUser(ctx, "user1", "hash")
 
-	input := model.CreatePostInput{
+	input := &entity.Post{
 		AuthorID:    user.ID,
 		Title:       "Post1",
 		Content:     "Post1",
 		Commentable: true,
 	}
 
-	post, err := repo.AddPost(input)
+	post, err := repo.AddPost(ctx, input)
 	require.NoError(t, err)
 	assert.NotNil(t, post)
 	assert.Equal(t, input.Title, post.Title)
@@ -29,18 +31,121 @@ func TestAddPost(t *testing.T) {
 }
 
 func TestGetPost(t *testing.T) {
-	repo := imrepo.NewInMemoryRepo()
-	_, err := repo.GetPost(5)
+	repo := imrepo.NewInMemoryRepo(pagination.Ranking{})
+	ctx := context.Background()
+	_, err := repo.GetPost(ctx, 5)
 	assert.Error(t, err)
 	assert.Equal(t, imrepo.ErrPostNotFound, err)
 }
 
 func TestToggleComments(t *testing.T) {
-	repo := imrepo.NewInMemoryRepo()
-	user, _ := repo.AddUser("user1")
+	repo := imrepo.NewInMemoryRepo(pagination.Ranking{})
+	ctx := context.Background()
+	user, _ := repo.AddUser(ctx, "user1", "hash")
 	repo.Posts[1] = &entity.Post{ID: 1, AuthorID: user.ID, Title: "Post1", Commentable: true}
 
-	post, err := repo.ToggleComments(1)
+	require.NoError(t, repo.ToggleComments(ctx, 1))
+	post, err := repo.GetPost(ctx, 1)
 	require.NoError(t, err)
 	assert.False(t, post.Commentable)
 }
+
+func TestGetPostsPag(t *testing.T) {
+	repo := imrepo.NewInMemoryRepo(pagination.Ranking{})
+	ctx := context.Background()
+	user, _ := repo.AddUser(ctx, "user1", "hash")
+	for i := 0; i < 3; i++ {
+		_, err := repo.AddPost(ctx, &entity.Post{AuthorID: user.ID, Title: "Post", Content: "Post"})
+		require.NoError(t, err)
+	}
+
+	first := 1
+	posts, err := repo.GetPostsPag(ctx, pagination.Page{First: &first}, false)
+	require.NoError(t, err)
+	require.Len(t, posts, 2)
+	assert.Equal(t, 1, posts[0].ID)
+	assert.Equal(t, 2, posts[1].ID)
+
+	after := &pagination.Cursor{Created: posts[0].Created, ID: posts[0].ID}
+	posts, err = repo.GetPostsPag(ctx, pagination.Page{After: after}, false)
+	require.NoError(t, err)
+	require.Len(t, posts, 2)
+	assert.Equal(t, 2, posts[0].ID)
+	assert.Equal(t, 3, posts[1].ID)
+}
+
+func TestGetRootCommentsByPostIDs(t *testing.T) {
+	repo := imrepo.NewInMemoryRepo(pagination.Ranking{})
+	ctx := context.Background()
+	user, _ := repo.AddUser(ctx, "user1", "hash")
+	repo.Posts[1] = &entity.Post{ID: 1, AuthorID: user.ID, Title: "Post1"}
+	repo.Posts[2] = &entity.Post{ID: 2, AuthorID: user.ID, Title: "Post2"}
+
+	for _, postID := range []int{1, 1, 1, 2} {
+		_, err := repo.AddComment(ctx, &entity.Comment{PostID: postID, AuthorID: user.ID, Content: "comment"})
+		require.NoError(t, err)
+	}
+
+	first := 1
+	roots, err := repo.GetRootCommentsByPostIDs(ctx, []int{1, 2}, pagination.Page{First: &first}, false)
+	require.NoError(t, err)
+	assert.Len(t, roots, 3)
+}
+
+func TestDeletePost(t *testing.T) {
+	repo := imrepo.NewInMemoryRepo(pagination.Ranking{})
+	ctx := context.Background()
+	user, _ := repo.AddUser(ctx, "user1", "hash")
+
+	post, _ := repo.AddPost(ctx, &entity.Post{AuthorID: user.ID, Title: "Post1", Commentable: true})
+	commentID, _ := repo.AddComment(ctx, &entity.Comment{PostID: post.ID, AuthorID: user.ID, Content: "comment"})
+
+	require.NoError(t, repo.DeletePost(ctx, post.ID))
+
+	_, err := repo.GetPost(ctx, post.ID)
+	assert.ErrorIs(t, err, imrepo.ErrPostNotFound)
+	_, err = repo.GetCommentByID(ctx, commentID)
+	assert.ErrorIs(t, err, imrepo.ErrCommentNotFound)
+	assert.ErrorIs(t, repo.DeletePost(ctx, post.ID), imrepo.ErrPostNotFound)
+}
+
+func TestGetPostsPagSorted(t *testing.T) {
+	repo := imrepo.NewInMemoryRepo(pagination.Ranking{Negative: []string{"dislike"}})
+	ctx := context.Background()
+	user, _ := repo.AddUser(ctx, "user1", "hash")
+
+	var ids []int
+	for _, title := range []string{"Post1", "Post2", "Post3"} {
+		post, err := repo.AddPost(ctx, &entity.Post{AuthorID: user.ID, Title: title, Commentable: true})
+		require.NoError(t, err)
+		ids = append(ids, post.ID)
+	}
+
+	react := func(postID, userID int, reactionType string) {
+		require.NoError(t, repo.AddReaction(ctx, &entity.Reaction{UserID: userID,
+			Target: entity.ReactionTarget{Kind: entity.TargetPost, ID: postID}, Type: reactionType}))
+	}
+	react(ids[0], 1, "like")
+	react(ids[0], 2, "dislike")
+	react(ids[1], 1, "like")
+	react(ids[1], 2, "like")
+
+	newest, err := repo.GetPostsPag(ctx, pagination.Page{Sort: pagination.SortNewest}, false)
+	require.NoError(t, err)
+	assert.Equal(t, ids[2], newest[0].ID)
+
+	top, err := repo.GetPostsPag(ctx, pagination.Page{Sort: pagination.SortTop}, false)
+	require.NoError(t, err)
+	require.Len(t, top, 3)
+	assert.Equal(t, []int{ids[1], ids[2], ids[0]}, []int{top[0].ID, top[1].ID, top[2].ID})
+
+	controversial, err := repo.GetPostsPag(ctx, pagination.Page{Sort: pagination.SortControversial}, false)
+	require.NoError(t, err)
+	assert.Equal(t, ids[0], controversial[0].ID)
+
+	score := top[0].Score
+	next, err := repo.GetPostsPag(ctx, pagination.Page{Sort: pagination.SortTop,
+		After: &pagination.Cursor{ID: top[0].ID, Score: &score}}, false)
+	require.NoError(t, err)
+	assert.Len(t, next, 2)
+}
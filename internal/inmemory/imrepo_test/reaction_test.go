@@ -0,0 +1,39 @@
+package imrepo
+
+import (
+	"Commentary/internal/entity"
+	"Commentary/internal/inmemory/imrepo"
+	"Commentary/internal/pagination"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestReactions(t *testing.T) {
+	repo := imrepo.NewInMemoryRepo(pagination.Ranking{})
+	ctx := context.Background()
+	repo.Posts[1] = &entity.Post{ID: 1, Title: "Post1"}
+	target := entity.ReactionTarget{Kind: entity.TargetPost, ID: 1}
+
+	for _, userID := range []int{1, 2, 2} {
+		require.NoError(t, repo.AddReaction(ctx, &entity.Reaction{UserID: userID, Target: target, Type: "like"}))
+	}
+	require.NoError(t, repo.AddReaction(ctx, &entity.Reaction{UserID: 2, Target: target, Type: "heart"}))
+
+	counts, err := repo.GetReactionCounts(ctx, entity.TargetPost, []int{1}, 1)
+	require.NoError(t, err)
+	require.Len(t, counts, 2)
+	assert.Equal(t, "heart", counts[0].Type)
+	assert.False(t, counts[0].ViewerReacted)
+	assert.Equal(t, 2, counts[1].Count)
+	assert.True(t, counts[1].ViewerReacted)
+
+	require.NoError(t, repo.RemoveReaction(ctx, 2, target, "heart"))
+	counts, _ = repo.GetReactionCounts(ctx, entity.TargetPost, []int{1}, 1)
+	assert.Len(t, counts, 1)
+
+	err = repo.AddReaction(ctx, &entity.Reaction{UserID: 1,
+		Target: entity.ReactionTarget{Kind: entity.TargetComment, ID: 9}, Type: "like"})
+	assert.ErrorIs(t, err, imrepo.ErrReactionTargetNotFound)
+}
@@ -0,0 +1,134 @@
+package imrepo
+
+import (
+	"Commentary/internal/entity"
+	"Commentary/internal/inmemory/imrepo"
+	"Commentary/internal/pagination"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+var allKinds = []entity.TargetKind{entity.TargetPost, entity.TargetComment}
+
+func TestSearch(t *testing.T) {
+	repo := imrepo.NewInMemoryRepo(pagination.Ranking{})
+	ctx := context.Background()
+	user, _ := repo.AddUser(ctx, "user1", "hash")
+
+	post, _ := repo.AddPost(ctx, &entity.Post{AuthorID: user.ID, Title: "Deploy", Content: "Postgres migration, again"})
+	commentID, _ := repo.AddComment(ctx, &entity.Comment{PostID: post.ID, AuthorID: user.ID, Content: "Migration done"})
+	_, _ = repo.AddComment(ctx, &entity.Comment{PostID: post.ID, AuthorID: user.ID, Content: "Postgres is down"})
+
+	hits, err := repo.Search(ctx, "MIGRATION postgres", allKinds, pagination.SearchPage{}, false)
+	require.NoError(t, err)
+	require.Len(t, hits, 1)
+	assert.Equal(t, entity.TargetPost, hits[0].Kind)
+	assert.Equal(t, "Deploy <mark>Postgres</mark> <mark>migration,</mark> again", hits[0].Snippet)
+
+	hits, err = repo.Search(ctx, "migration", allKinds, pagination.SearchPage{}, false)
+	require.NoError(t, err)
+	require.Len(t, hits, 2)
+	assert.Equal(t, entity.TargetComment, hits[0].Kind)
+	assert.Equal(t, commentID, hits[0].ID)
+
+	hits, err = repo.Search(ctx, "migration", []entity.TargetKind{entity.TargetPost}, pagination.SearchPage{}, false)
+	require.NoError(t, err)
+	require.Len(t, hits, 1)
+	assert.Equal(t, entity.TargetPost, hits[0].Kind)
+}
+
+func TestSearchPaginated(t *testing.T) {
+	repo := imrepo.NewInMemoryRepo(pagination.Ranking{})
+	ctx := context.Background()
+	user, _ := repo.AddUser(ctx, "user1", "hash")
+
+	post, _ := repo.AddPost(ctx, &entity.Post{AuthorID: user.ID, Title: "word", Content: "word"})
+	for i := 0; i < 2; i++ {
+		_, _ = repo.AddComment(ctx, &entity.Comment{PostID: post.ID, AuthorID: user.ID, Content: "word"})
+	}
+
+	first := 1
+	var seen []entity.SearchHit
+	page := pagination.SearchPage{First: &first}
+	for {
+		hits, err := repo.Search(ctx, "word", allKinds, page, false)
+		require.NoError(t, err)
+		if len(hits) == 0 {
+			break
+		}
+		seen = append(seen, *hits[0])
+		page.After = &pagination.SearchCursor{Rank: hits[0].Rank, Kind: string(hits[0].Kind), ID: hits[0].ID}
+	}
+
+	require.Len(t, seen, 3)
+	assert.Equal(t, entity.TargetComment, seen[0].Kind)
+	assert.Equal(t, 2, seen[0].ID)
+	assert.Equal(t, 1, seen[1].ID)
+	assert.Equal(t, entity.TargetPost, seen[2].Kind)
+}
+
+func TestSearchFollowsUpdates(t *testing.T) {
+	repo := imrepo.NewInMemoryRepo(pagination.Ranking{})
+	ctx := context.Background()
+	user, _ := repo.AddUser(ctx, "user1", "hash")
+
+	post, _ := repo.AddPost(ctx, &entity.Post{AuthorID: user.ID, Title: "Post", Content: "content"})
+	commentID, _ := repo.AddComment(ctx, &entity.Comment{PostID: post.ID, AuthorID: user.ID, Content: "old text"})
+
+	edited := time.Now()
+	require.NoError(t, repo.UpdateComment(ctx, &entity.Comment{ID: commentID, Content: "new text", Edited: &edited},
+		user.ID))
+
+	hits, err := repo.Search(ctx, "old", allKinds, pagination.SearchPage{}, false)
+	require.NoError(t, err)
+	assert.Empty(t, hits)
+	hits, err = repo.Search(ctx, "new", allKinds, pagination.SearchPage{}, false)
+	require.NoError(t, err)
+	assert.Len(t, hits, 1)
+
+	_, err = repo.DeleteComment(ctx, commentID)
+	require.NoError(t, err)
+	hits, err = repo.Search(ctx, "text", allKinds, pagination.SearchPage{}, false)
+	require.NoError(t, err)
+	assert.Empty(t, hits)
+
+	require.NoError(t, repo.DeletePost(ctx, post.ID))
+	hits, err = repo.Search(ctx, "content", allKinds, pagination.SearchPage{}, false)
+	require.NoError(t, err)
+	assert.Empty(t, hits)
+}
+
+func TestSearchSkipsHidden(t *testing.T) {
+	repo := imrepo.NewInMemoryRepo(pagination.Ranking{})
+	ctx := context.Background()
+	user, _ := repo.AddUser(ctx, "user1", "hash")
+
+	post, _ := repo.AddPost(ctx, &entity.Post{AuthorID: user.ID, Title: "Secret", Content: "secret"})
+	commentID, _ := repo.AddComment(ctx, &entity.Comment{PostID: post.ID, AuthorID: user.ID, Content: "secret"})
+	require.NoError(t, repo.SetHidden(ctx, entity.ReactionTarget{Kind: entity.TargetPost, ID: post.ID}, true))
+	require.NoError(t, repo.SetHidden(ctx, entity.ReactionTarget{Kind: entity.TargetComment, ID: commentID}, true))
+
+	hits, err := repo.Search(ctx, "secret", allKinds, pagination.SearchPage{}, false)
+	require.NoError(t, err)
+	assert.Empty(t, hits)
+
+	hits, err = repo.Search(ctx, "secret", allKinds, pagination.SearchPage{}, true)
+	require.NoError(t, err)
+	assert.Len(t, hits, 2)
+}
+
+func TestSearchEscapesSnippet(t *testing.T) {
+	repo := imrepo.NewInMemoryRepo(pagination.Ranking{})
+	ctx := context.Background()
+	user, _ := repo.AddUser(ctx, "user1", "hash")
+
+	_, _ = repo.AddPost(ctx, &entity.Post{AuthorID: user.ID, Title: "Title", Content: `<img src=x onerror="alert(1)">`})
+
+	hits, err := repo.Search(ctx, "alert", allKinds, pagination.SearchPage{}, false)
+	require.NoError(t, err)
+	require.Len(t, hits, 1)
+	assert.Equal(t, `Title &lt;img src=x <mark>onerror=&#34;alert(1)&#34;&gt;</mark>`, hits[0].Snippet)
+}
@@ -2,6 +2,8 @@ package imrepo_test
 
 import (
 	"Commentary/internal/inmemory/imrepo"
+	"Commentary/internal/pagination"
+	"context"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -10,19 +12,20 @@ import (
 )
 
 func TestAddUser(t *testing.T) {
-	repo := imrepo.NewInMemoryRepo()
+	repo := imrepo.NewInMemoryRepo(pagination.Ranking{})
+	ctx := context.Background()
 
-	user, err := repo.AddUser("user1")
+	user, err := repo.AddUser(ctx, "user1", "hash")
 	require.NoError(t, err)
 	assert.NotNil(t, user)
 	assert.Greater(t, user.ID, 0)
 	assert.Equal(t, "user1", user.Username)
 
-	usr, err := repo.GetUser(user.ID)
+	usr, err := repo.GetUserByID(ctx, user.ID)
 	require.NoError(t, err)
 	assert.Equal(t, user, usr)
 
-	_, err = repo.AddUser("user1")
+	_, err = repo.AddUser(ctx, "user1", "hash")
 	assert.Error(t, err)
 	assert.Equal(t, imrepo.ErrUserAlreadyExists, err)
 
@@ -37,35 +40,52 @@ func TestAddUser(t *testing.T) {
 		wg.Add(1)
 		go func(username string) {
 			defer wg.Done()
-			_, _ = repo.AddUser(username)
+			_, _ = repo.AddUser(ctx, username, "hash")
 		}(username)
 	}
 	wg.Wait()
 
 	for _, username := range usernames {
-		user, err = repo.AddUser(username)
+		user, err = repo.AddUser(ctx, username, "hash")
 		assert.Error(t, err)
 		assert.Equal(t, imrepo.ErrUserAlreadyExists, err)
 	}
 }
 
 func TestGetUser(t *testing.T) {
-	repo := imrepo.NewInMemoryRepo()
+	repo := imrepo.NewInMemoryRepo(pagination.Ranking{})
+	ctx := context.Background()
 
-	_, err := repo.GetUser(111)
+	_, err := repo.GetUserByID(ctx, 111)
 	assert.Error(t, err)
 	assert.Equal(t, imrepo.ErrUserNotFound, err)
 
-	user1, err := repo.AddUser("user1")
+	user1, err := repo.AddUser(ctx, "user1", "hash")
 	require.NoError(t, err)
-	user2, err := repo.AddUser("user2")
+	user2, err := repo.AddUser(ctx, "user2", "hash")
 	require.NoError(t, err)
 
-	retrievedUser1, err := repo.GetUser(user1.ID)
+	retrievedUser1, err := repo.GetUserByID(ctx, user1.ID)
 	require.NoError(t, err)
 	assert.Equal(t, user1, retrievedUser1)
 
-	retrievedUser2, err := repo.GetUser(user2.ID)
+	retrievedUser2, err := repo.GetUserByID(ctx, user2.ID)
 	require.NoError(t, err)
 	assert.Equal(t, user2, retrievedUser2)
 }
+
+func TestGetUserByUsername(t *testing.T) {
+	repo := imrepo.NewInMemoryRepo(pagination.Ranking{})
+	ctx := context.Background()
+
+	_, err := repo.GetUserByUsername(ctx, "user1")
+	assert.Equal(t, imrepo.ErrUserNotFound, err)
+
+	added, err := repo.AddUser(ctx, "user1", "hash")
+	require.NoError(t, err)
+
+	user, err := repo.GetUserByUsername(ctx, "user1")
+	require.NoError(t, err)
+	assert.Equal(t, added.ID, user.ID)
+	assert.Equal(t, "hash", user.PasswordHash)
+}
@@ -0,0 +1,185 @@
+package loader
+
+import (
+	"Commentary/internal/common"
+	"Commentary/internal/graph/model"
+	"Commentary/internal/service"
+	"context"
+	"github.com/99designs/gqlgen/graphql"
+)
+
+type ctxKey struct{}
+
+// PostCommentsKey identifies the first page of root comments of a post.
+type PostCommentsKey struct {
+	PostID   int
+	HasFirst bool
+	First    int
+	Sort     model.SortOrder
+}
+
+func NewPostCommentsKey(postID int, first *int, sort model.SortOrder) PostCommentsKey {
+	if first == nil {
+		return PostCommentsKey{PostID: postID, Sort: sort}
+	}
+	return PostCommentsKey{PostID: postID, HasFirst: true, First: *first, Sort: sort}
+}
+
+// RepliesKey identifies a page of replies to a comment.
+type RepliesKey struct {
+	ParentID int
+	HasFirst bool
+	First    int
+	HasAfter bool
+	After    string
+	Depth    int
+}
+
+func NewRepliesKey(parentID int, first *int, after *string, depth int) RepliesKey {
+	key := RepliesKey{ParentID: parentID, Depth: depth}
+	if first != nil {
+		key.HasFirst, key.First = true, *first
+	}
+	if after != nil {
+		key.HasAfter, key.After = true, *after
+	}
+	return key
+}
+
+type Loaders struct {
+	Users            *Loader[int, *model.User]
+	Posts            *Loader[int, *model.Post]
+	Comments         *Loader[int, *model.Comment]
+	ReplyCounts      *Loader[int, int]
+	Revisions        *Loader[int, []*model.CommentRevision]
+	PostReactions    *Loader[int, []*model.ReactionCount]
+	CommentReactions *Loader[int, []*model.ReactionCount]
+	PostMentions     *Loader[int, []*model.Mention]
+	CommentMentions  *Loader[int, []*model.Mention]
+	PostComments     *Loader[PostCommentsKey, *model.CommentConnection]
+	Replies          *Loader[RepliesKey, *model.CommentConnection]
+}
+
+func NewLoaders(postService common.PostService, commentService common.CommentService,
+	userService common.UserService, reactionService common.ReactionService,
+	mentionService common.MentionService) *Loaders {
+	return &Loaders{
+		Users:            NewLoader(userService.GetUsersByIDs),
+		Posts:            NewLoader(postService.GetPostsByIDs),
+		Comments:         NewLoader(commentService.GetCommentsByIDs),
+		ReplyCounts:      NewLoader(commentService.GetReplyCounts),
+		Revisions:        NewLoader(commentService.GetRevisions),
+		PostReactions:    NewLoader(reactionService.GetPostReactions),
+		CommentReactions: NewLoader(reactionService.GetCommentReactions),
+		PostMentions:     NewLoader(mentionService.GetPostMentions),
+		CommentMentions:  NewLoader(mentionService.GetCommentMentions),
+		PostComments: NewLoader(func(ctx context.Context, keys []PostCommentsKey) (
+			map[PostCommentsKey]*model.CommentConnection, error) {
+			return postComments(ctx, commentService, keys)
+		}),
+		Replies: NewLoader(func(ctx context.Context, keys []RepliesKey) (
+			map[RepliesKey]*model.CommentConnection, error) {
+			return replies(ctx, commentService, keys)
+		}),
+	}
+}
+
+// postComments groups keys by page size and sort, so that every distinct pair of them costs one query.
+func postComments(ctx context.Context, commentService common.CommentService,
+	keys []PostCommentsKey) (map[PostCommentsKey]*model.CommentConnection, error) {
+	groups := make(map[PostCommentsKey][]int)
+	for _, key := range keys {
+		group := PostCommentsKey{HasFirst: key.HasFirst, First: key.First, Sort: key.Sort}
+		groups[group] = append(groups[group], key.PostID)
+	}
+
+	result := make(map[PostCommentsKey]*model.CommentConnection, len(keys))
+	for group, postIDs := range groups {
+		var first *int
+		if group.HasFirst {
+			first = &group.First
+		}
+
+		connections, err := commentService.GetCommentsByPostIDs(ctx, postIDs, first, group.Sort)
+		if err != nil {
+			return nil, err
+		}
+		for postID, connection := range connections {
+			key := group
+			key.PostID = postID
+			result[key] = connection
+		}
+	}
+	return result, nil
+}
+
+// replies groups keys by page and depth, so that every distinct combination of them costs one query.
+func replies(ctx context.Context, commentService common.CommentService,
+	keys []RepliesKey) (map[RepliesKey]*model.CommentConnection, error) {
+	groups := make(map[RepliesKey][]int)
+	for _, key := range keys {
+		group := key
+		group.ParentID = 0
+		groups[group] = append(groups[group], key.ParentID)
+	}
+
+	result := make(map[RepliesKey]*model.CommentConnection, len(keys))
+	for group, parentIDs := range groups {
+		var first *int
+		if group.HasFirst {
+			first = &group.First
+		}
+		var after *string
+		if group.HasAfter {
+			after = &group.After
+		}
+
+		connections, err := commentService.GetReplies(ctx, parentIDs, first, after, group.Depth)
+		if err != nil {
+			return nil, err
+		}
+		for parentID, connection := range connections {
+			key := group
+			key.ParentID = parentID
+			result[key] = connection
+		}
+	}
+	return result, nil
+}
+
+func NewContext(ctx context.Context, loaders *Loaders) context.Context {
+	return context.WithValue(ctx, ctxKey{}, loaders)
+}
+
+func FromContext(ctx context.Context) *Loaders {
+	return ctx.Value(ctxKey{}).(*Loaders)
+}
+
+// Extension attaches fresh loaders and a fresh viewer role to every GraphQL response. Subscriptions get
+// new loaders for each event, so they never serve values cached by the previous ones.
+type Extension struct {
+	PostService     common.PostService
+	CommentService  common.CommentService
+	UserService     common.UserService
+	ReactionService common.ReactionService
+	MentionService  common.MentionService
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.ResponseInterceptor
+} = Extension{}
+
+func (e Extension) ExtensionName() string {
+	return "Loaders"
+}
+
+func (e Extension) Validate(graphql.ExecutableSchema) error {
+	return nil
+}
+
+func (e Extension) InterceptResponse(ctx context.Context, next graphql.ResponseHandler) *graphql.Response {
+	ctx = service.WithViewerRole(ctx)
+	return next(NewContext(ctx, NewLoaders(e.PostService, e.CommentService, e.UserService, e.ReactionService,
+		e.MentionService)))
+}
@@ -0,0 +1,45 @@
+package loader
+
+import (
+	"Commentary/internal/loader"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestLoadBatchesKeys(t *testing.T) {
+	var calls atomic.Int32
+	l := loader.NewLoader(func(ctx context.Context, keys []int) (map[int]string, error) {
+		calls.Add(1)
+		values := make(map[int]string, len(keys))
+		for _, key := range keys {
+			if key != 3 {
+				values[key] = "value"
+			}
+		}
+		return values, nil
+	})
+
+	var wg sync.WaitGroup
+	errs := make([]error, 4)
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(key int) {
+			defer wg.Done()
+			_, errs[key] = l.Load(context.Background(), key)
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), calls.Load())
+	assert.NoError(t, errs[0])
+	assert.ErrorIs(t, errs[3], loader.ErrNotFound)
+
+	value, err := l.Load(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, "value", value)
+	assert.Equal(t, int32(1), calls.Load())
+}
@@ -0,0 +1,52 @@
+package markdown
+
+import (
+	"crypto/sha256"
+	"github.com/hashicorp/golang-lru/v2"
+	"github.com/russross/blackfriday/v2"
+	"github.com/sirupsen/logrus"
+	"strings"
+)
+
+// extensions is the Markdown subset: emphasis, strikethrough, inline and fenced code, quotes,
+// lists, links and autolinks. Tables and definition lists are not parsed, headings, images and
+// rules are removed by Sanitize, raw HTML is never passed through.
+const extensions = blackfriday.NoIntraEmphasis | blackfriday.FencedCode | blackfriday.Autolink |
+	blackfriday.Strikethrough
+
+const flags = blackfriday.SkipHTML | blackfriday.SkipImages | blackfriday.Safelink
+
+// Renderer turns Markdown content into sanitized HTML. Rendered content is cached by its hash,
+// so an edit gets a new entry and repeated reads of the same version are not rendered again.
+type Renderer struct {
+	cache *lru.Cache[[sha256.Size]byte, string]
+}
+
+func NewRenderer(cacheSize int) (*Renderer, error) {
+	cache, err := lru.New[[sha256.Size]byte, string](cacheSize)
+	if err != nil {
+		return nil, err
+	}
+	return &Renderer{cache: cache}, nil
+}
+
+func (r *Renderer) Render(content string) string {
+	key := sha256.Sum256([]byte(content))
+	if rendered, ok := r.cache.Get(key); ok {
+		return rendered
+	}
+
+	rendered := Render(content)
+	r.cache.Add(key, rendered)
+	logrus.WithField("length", len(content)).Debug("rendered content")
+
+	return rendered
+}
+
+// Render converts the content without caching.
+func Render(content string) string {
+	renderer := blackfriday.NewHTMLRenderer(blackfriday.HTMLRendererParameters{Flags: flags})
+	output := blackfriday.Run([]byte(content), blackfriday.WithExtensions(extensions),
+		blackfriday.WithRenderer(renderer))
+	return strings.TrimSpace(Sanitize(string(output)))
+}
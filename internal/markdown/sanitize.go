@@ -0,0 +1,121 @@
+package markdown
+
+import (
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"net/url"
+	"strings"
+)
+
+// allowed lists the tags that survive sanitizing together with the attributes they may keep.
+var allowed = map[atom.Atom][]string{
+	atom.P:          nil,
+	atom.Br:         nil,
+	atom.Em:         nil,
+	atom.Strong:     nil,
+	atom.Del:        nil,
+	atom.Code:       nil,
+	atom.Pre:        nil,
+	atom.Blockquote: nil,
+	atom.Ul:         nil,
+	atom.Ol:         nil,
+	atom.Li:         nil,
+	atom.A:          {"href"},
+}
+
+// dropped are the tags removed together with their content, the others lose only the tags.
+var dropped = map[atom.Atom]bool{
+	atom.Script:   true,
+	atom.Style:    true,
+	atom.Iframe:   true,
+	atom.Object:   true,
+	atom.Textarea: true,
+	atom.Title:    true,
+}
+
+var schemes = map[string]bool{"http": true, "https": true, "mailto": true}
+
+// Sanitize keeps only the allowlisted tags and attributes of the HTML. Links must point to
+// http, https or mailto URLs and get rel="nofollow noopener", the other tags are removed,
+// leaving their text escaped.
+func Sanitize(input string) string {
+	var out strings.Builder
+	tokenizer := html.NewTokenizer(strings.NewReader(input))
+	// links tells for every open a tag whether it was kept, so that its end tag matches
+	var links []bool
+	skipping := 0
+
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return out.String()
+		case html.TextToken:
+			if skipping == 0 {
+				out.WriteString(html.EscapeString(string(tokenizer.Text())))
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+			if dropped[token.DataAtom] {
+				if token.Type == html.StartTagToken {
+					skipping++
+				}
+				continue
+			}
+			if skipping > 0 {
+				continue
+			}
+			if token.DataAtom == atom.A {
+				href, ok := safeLink(token)
+				if token.Type == html.StartTagToken {
+					links = append(links, ok)
+				}
+				if ok {
+					out.WriteString(`<a href="` + html.EscapeString(href) + `" rel="nofollow noopener">`)
+				}
+				continue
+			}
+			if _, ok := allowed[token.DataAtom]; ok {
+				out.WriteString("<" + token.DataAtom.String() + ">")
+			}
+		case html.EndTagToken:
+			token := tokenizer.Token()
+			if dropped[token.DataAtom] {
+				if skipping > 0 {
+					skipping--
+				}
+				continue
+			}
+			if skipping > 0 {
+				continue
+			}
+			if token.DataAtom == atom.A {
+				if len(links) > 0 {
+					kept := links[len(links)-1]
+					links = links[:len(links)-1]
+					if kept {
+						out.WriteString("</a>")
+					}
+				}
+				continue
+			}
+			if _, ok := allowed[token.DataAtom]; ok && token.DataAtom != atom.Br {
+				out.WriteString("</" + token.DataAtom.String() + ">")
+			}
+		}
+	}
+}
+
+// safeLink returns the href of the link if its scheme is allowed.
+func safeLink(token html.Token) (string, bool) {
+	for _, attr := range token.Attr {
+		if attr.Key != "href" {
+			continue
+		}
+		link, err := url.Parse(strings.TrimSpace(attr.Val))
+		if err != nil || !schemes[strings.ToLower(link.Scheme)] {
+			return "", false
+		}
+		return link.String(), true
+	}
+	return "", false
+}
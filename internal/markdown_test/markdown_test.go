@@ -0,0 +1,53 @@
+package markdown
+
+import (
+	"Commentary/internal/markdown"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestRenderSubset(t *testing.T) {
+	cases := map[string]string{
+		"**bold** and *em* and ~~gone~~":    "<p><strong>bold</strong> and <em>em</em> and <del>gone</del></p>",
+		"line  \nbreak":                     "<p>line<br>\nbreak</p>",
+		"`a < b`":                           "<p><code>a &lt; b</code></p>",
+		"- one\n- two":                      "<ul>\n<li>one</li>\n<li>two</li>\n</ul>",
+		"> quote":                           "<blockquote>\n<p>quote</p>\n</blockquote>",
+		"# Title":                           "Title",
+		"[site](https://example.com)":       `<p><a href="https://example.com" rel="nofollow noopener">site</a></p>`,
+		"![alt](https://example.com/x.png)": "<p></p>",
+	}
+	for input, expected := range cases {
+		assert.Equal(t, expected, markdown.Render(input), input)
+	}
+}
+
+func TestRenderEscapesHTML(t *testing.T) {
+	cases := map[string]string{
+		"<script>alert(1)</script>":             "<p>alert(1)</p>",
+		"hi <b onclick=\"x()\">there</b>":       "<p>hi there</p>",
+		"[x](javascript:void)":                  "<p>x</p>",
+		"<a href=\"javascript:alert(1)\">x</a>": "<p>x</p>",
+	}
+	for input, expected := range cases {
+		assert.Equal(t, expected, markdown.Render(input), input)
+	}
+}
+
+func TestSanitize(t *testing.T) {
+	assert.Equal(t, `<p>x<a href="https://a.b/?q=1&amp;r=2" rel="nofollow noopener">y</a></p>`,
+		markdown.Sanitize(`<p class="c">x<a href="https://a.b/?q=1&amp;r=2" onclick="z">y</a></p>`))
+	assert.Equal(t, "<p>&lt;x&gt; y</p>", markdown.Sanitize(`<p>&lt;x&gt; <img src=x onerror=alert(1)>y</p>`))
+	assert.Equal(t, "text", markdown.Sanitize(`<a href="javascript:alert(1)">text</a>`))
+	assert.Equal(t, "ab", markdown.Sanitize(`a<style>p{}</style><script>alert(1)</script>b`))
+}
+
+func TestRendererCachesByContent(t *testing.T) {
+	renderer, err := markdown.NewRenderer(2)
+	require.NoError(t, err)
+
+	assert.Equal(t, "<p><em>a</em></p>", renderer.Render("*a*"))
+	assert.Equal(t, "<p><em>a</em></p>", renderer.Render("*a*"))
+	assert.Equal(t, "<p><strong>a</strong></p>", renderer.Render("**a**"))
+}
@@ -0,0 +1,46 @@
+package mention
+
+import (
+	"regexp"
+	"unicode/utf8"
+)
+
+// pattern matches @username made of letters, digits and underscores not preceded by one of them,
+// so that e-mail addresses are not mentions.
+var pattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_@])(@([\p{L}\p{N}_]+))`)
+
+// Match is an @username in the content. Offset and Length count characters, not bytes,
+// and cover the @ sign.
+type Match struct {
+	Username string
+	Offset   int
+	Length   int
+}
+
+// Parse returns every @username in the content in order of appearance.
+func Parse(content string) []Match {
+	var matches []Match
+	for _, loc := range pattern.FindAllStringSubmatchIndex(content, -1) {
+		start, end := loc[2], loc[3]
+		matches = append(matches, Match{
+			Username: content[loc[4]:loc[5]],
+			Offset:   utf8.RuneCountInString(content[:start]),
+			Length:   utf8.RuneCountInString(content[start:end]),
+		})
+	}
+	return matches
+}
+
+// Usernames returns the users mentioned in the content, each once, in order of appearance.
+func Usernames(content string) []string {
+	var usernames []string
+	seen := make(map[string]struct{})
+	for _, match := range Parse(content) {
+		if _, ok := seen[match.Username]; ok {
+			continue
+		}
+		seen[match.Username] = struct{}{}
+		usernames = append(usernames, match.Username)
+	}
+	return usernames
+}
@@ -0,0 +1,21 @@
+package mention
+
+import (
+	"Commentary/internal/mention"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestUsernames(t *testing.T) {
+	assert.Equal(t, []string{"bob", "alice_1"},
+		mention.Usernames("@bob, have you met @alice_1? (@bob again) mail me at me@example.com, @@weird"))
+	assert.Empty(t, mention.Usernames("no mentions here"))
+}
+
+func TestParseCountsCharacters(t *testing.T) {
+	matches := mention.Parse("привет @боб и @bob")
+	assert.Equal(t, []mention.Match{
+		{Username: "боб", Offset: 7, Length: 4},
+		{Username: "bob", Offset: 14, Length: 4},
+	}, matches)
+}
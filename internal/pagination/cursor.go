@@ -0,0 +1,81 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidCursor = errors.New("invalid cursor")
+	ErrInvalidFirst  = errors.New("first must be between 1 and 100")
+)
+
+// Cursor points to the last item of a page. Cursors of ranked sorts carry the score of the item
+// and the time the ranking was computed at, so that the following pages are ranked the same way.
+type Cursor struct {
+	Created  time.Time
+	ID       int
+	Score    *float64
+	RankedAt time.Time
+}
+
+// rankedPrefix marks cursors of ranked sorts.
+const rankedPrefix = "r"
+
+func EncodeCursor(created time.Time, id int) string {
+	raw := fmt.Sprintf("%d:%d", created.UnixNano(), id)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func EncodeRankedCursor(score float64, id int, rankedAt time.Time) string {
+	raw := fmt.Sprintf("%s:%d:%s:%d", rankedPrefix, rankedAt.UnixNano(),
+		strconv.FormatFloat(score, 'g', -1, 64), id)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func DecodeCursor(cursor string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	parts := strings.Split(string(raw), ":")
+	if len(parts) == 4 && parts[0] == rankedPrefix {
+		return decodeRankedCursor(parts[1:])
+	}
+	if len(parts) != 2 {
+		return nil, ErrInvalidCursor
+	}
+
+	nanos, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	id, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return &Cursor{Created: time.Unix(0, nanos).UTC(), ID: id}, nil
+}
+
+func decodeRankedCursor(parts []string) (*Cursor, error) {
+	nanos, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	score, err := strconv.ParseFloat(parts[1], 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	id, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return &Cursor{ID: id, Score: &score, RankedAt: time.Unix(0, nanos).UTC()}, nil
+}
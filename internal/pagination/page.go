@@ -0,0 +1,117 @@
+package pagination
+
+import "time"
+
+const (
+	// DefaultFirst is the size of pages requested without first.
+	DefaultFirst = 20
+	// MaxFirst is the largest page a client may request.
+	MaxFirst = 100
+)
+
+type Page struct {
+	First *int
+	After *Cursor
+	Sort  Sort
+	// Now is the time ranked sorts are computed at.
+	Now time.Time
+}
+
+func NewPage(first *int, after *string) (Page, error) {
+	return NewSortedPage(first, after, SortOldest)
+}
+
+func NewSortedPage(first *int, after *string, sort Sort) (Page, error) {
+	size, err := pageSize(first)
+	if err != nil {
+		return Page{}, err
+	}
+
+	page := Page{First: size, Sort: sort, Now: time.Now()}
+	if after != nil {
+		cursor, err := DecodeCursor(*after)
+		if err != nil {
+			return Page{}, err
+		}
+		if (cursor.Score != nil) != sort.Ranked() {
+			return Page{}, ErrInvalidCursor
+		}
+		if cursor.Score != nil {
+			page.Now = cursor.RankedAt
+		}
+		page.After = cursor
+	}
+	return page, nil
+}
+
+// pageSize checks the requested page size, pages requested without one get DefaultFirst items.
+func pageSize(first *int) (*int, error) {
+	size := DefaultFirst
+	if first != nil {
+		if *first <= 0 || *first > MaxFirst {
+			return nil, ErrInvalidFirst
+		}
+		size = *first
+	}
+	return &size, nil
+}
+
+// Limit returns the number of rows to fetch: one more than requested,
+// so that the caller can tell whether there is a next page.
+func (p Page) Limit() *int {
+	if p.First == nil {
+		return nil
+	}
+	limit := *p.First + 1
+	return &limit
+}
+
+// Cursor encodes the position of an item for this page's sort.
+func (p Page) Cursor(key Key) string {
+	if p.Sort.Ranked() {
+		return EncodeRankedCursor(key.Score, key.ID, p.Now)
+	}
+	return EncodeCursor(key.Created, key.ID)
+}
+
+// Less reports whether the item a goes before b. Ties are broken by id,
+// ascending for the oldest first order and descending for all others.
+func (p Page) Less(a, b Key) bool {
+	switch {
+	case p.Sort.Ranked():
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		return a.ID > b.ID
+	case p.Sort == SortNewest:
+		if !a.Created.Equal(b.Created) {
+			return a.Created.After(b.Created)
+		}
+		return a.ID > b.ID
+	default:
+		if !a.Created.Equal(b.Created) {
+			return a.Created.Before(b.Created)
+		}
+		return a.ID < b.ID
+	}
+}
+
+// Includes reports whether the item goes after the page cursor.
+func (p Page) Includes(key Key) bool {
+	if p.After == nil {
+		return true
+	}
+	score := 0.0
+	if p.After.Score != nil {
+		score = *p.After.Score
+	}
+	return p.Less(Key{Created: p.After.Created, ID: p.After.ID, Score: score}, key)
+}
+
+// Trim cuts the extra row fetched by Limit and reports whether it was there.
+func Trim[T any](items []T, page Page) ([]T, bool) {
+	if page.First == nil || len(items) <= *page.First {
+		return items, false
+	}
+	return items[:*page.First], true
+}
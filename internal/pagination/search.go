@@ -0,0 +1,91 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SearchCursor points to the last hit of a search results page. Hits are ordered by rank,
+// ties are broken by kind and then by id, since posts and comments share id values.
+type SearchCursor struct {
+	Rank float64
+	Kind string
+	ID   int
+}
+
+// searchPrefix marks cursors of search results.
+const searchPrefix = "s"
+
+func EncodeSearchCursor(rank float64, kind string, id int) string {
+	raw := fmt.Sprintf("%s:%s:%s:%d", searchPrefix, strconv.FormatFloat(rank, 'g', -1, 64), kind, id)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func DecodeSearchCursor(cursor string) (*SearchCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	parts := strings.Split(string(raw), ":")
+	if len(parts) != 4 || parts[0] != searchPrefix {
+		return nil, ErrInvalidCursor
+	}
+
+	rank, err := strconv.ParseFloat(parts[1], 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	id, err := strconv.Atoi(parts[3])
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return &SearchCursor{Rank: rank, Kind: parts[2], ID: id}, nil
+}
+
+type SearchPage struct {
+	First *int
+	After *SearchCursor
+}
+
+func NewSearchPage(first *int, after *string) (SearchPage, error) {
+	size, err := pageSize(first)
+	if err != nil {
+		return SearchPage{}, err
+	}
+
+	page := SearchPage{First: size}
+	if after != nil {
+		cursor, err := DecodeSearchCursor(*after)
+		if err != nil {
+			return SearchPage{}, err
+		}
+		page.After = cursor
+	}
+	return page, nil
+}
+
+// Limit returns the number of hits to fetch, one more than requested like Page.Limit does.
+func (p SearchPage) Limit() *int {
+	return Page{First: p.First}.Limit()
+}
+
+// Includes reports whether the hit goes after the page cursor.
+func (p SearchPage) Includes(hit SearchCursor) bool {
+	return p.After == nil || SearchLess(*p.After, hit)
+}
+
+// SearchLess reports whether the hit a goes before b: higher rank first, then by kind
+// and by id descending.
+func SearchLess(a, b SearchCursor) bool {
+	if a.Rank != b.Rank {
+		return a.Rank > b.Rank
+	}
+	if a.Kind != b.Kind {
+		return a.Kind < b.Kind
+	}
+	return a.ID > b.ID
+}
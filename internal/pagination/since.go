@@ -0,0 +1,32 @@
+package pagination
+
+import (
+	"errors"
+	"strconv"
+	"time"
+)
+
+var ErrInvalidSince = errors.New("since must be a comment id or an RFC 3339 timestamp")
+
+// Since is the point a resumed subscription replays comments from: the comment with CommentID
+// or, if CommentID is zero, the moment Time. The point itself is not replayed.
+type Since struct {
+	CommentID int
+	Time      time.Time
+}
+
+func ParseSince(since string) (Since, error) {
+	if id, err := strconv.Atoi(since); err == nil {
+		if id <= 0 {
+			return Since{}, ErrInvalidSince
+		}
+		return Since{CommentID: id}, nil
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, since)
+	if err != nil {
+		return Since{}, ErrInvalidSince
+	}
+	// comments are stored with the local wall clock, the offset of the timestamp would be dropped
+	return Since{Time: t.In(time.Local)}, nil
+}
@@ -0,0 +1,69 @@
+package pagination
+
+import (
+	"math"
+	"time"
+)
+
+type Sort string
+
+const (
+	SortNewest        Sort = "NEWEST"
+	SortOldest        Sort = "OLDEST"
+	SortTop           Sort = "TOP"
+	SortControversial Sort = "CONTROVERSIAL"
+	SortHot           Sort = "HOT"
+)
+
+// Ranked reports whether items are ordered by a computed score rather than by creation time.
+func (s Sort) Ranked() bool {
+	return s == SortTop || s == SortControversial || s == SortHot
+}
+
+// Key is the position of an item in a sorted list. Score is only used by ranked sorts.
+type Key struct {
+	Created time.Time
+	ID      int
+	Score   float64
+}
+
+// Ranking scores items for ranked sorts. Reactions of the Negative types count against an item,
+// all other reaction types count for it.
+type Ranking struct {
+	Negative []string
+}
+
+func (r Ranking) IsNegative(reactionType string) bool {
+	for _, negative := range r.Negative {
+		if negative == reactionType {
+			return true
+		}
+	}
+	return false
+}
+
+// hotGravity controls how fast hot items sink with age.
+const hotGravity = 1.5
+
+// Score computes the ranking score of an item with the given reaction and reply counts.
+// Chronological sorts always score zero.
+func (r Ranking) Score(sort Sort, positive, negative, replies int, created, now time.Time) float64 {
+	switch sort {
+	case SortTop:
+		return TopScore(positive, negative, replies)
+	case SortControversial:
+		if positive == 0 || negative == 0 {
+			return 0
+		}
+		return float64(positive+negative) * float64(min(positive, negative)) / float64(max(positive, negative))
+	case SortHot:
+		age := math.Max(now.Sub(created).Hours(), 0)
+		return TopScore(positive, negative, replies) / math.Pow(age+2, hotGravity)
+	default:
+		return 0
+	}
+}
+
+func TopScore(positive, negative, replies int) float64 {
+	return float64(positive - negative + replies)
+}
@@ -0,0 +1,92 @@
+package pagination
+
+import (
+	"Commentary/internal/pagination"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestRankedCursorRoundTrip(t *testing.T) {
+	rankedAt := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	encoded := pagination.EncodeRankedCursor(0.1+0.2, 42, rankedAt)
+
+	page, err := pagination.NewSortedPage(nil, &encoded, pagination.SortHot)
+	require.NoError(t, err)
+	require.NotNil(t, page.After.Score)
+	assert.Equal(t, 0.1+0.2, *page.After.Score)
+	assert.Equal(t, 42, page.After.ID)
+	assert.True(t, page.Now.Equal(rankedAt))
+}
+
+func TestPageSize(t *testing.T) {
+	page, err := pagination.NewPage(nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, pagination.DefaultFirst, *page.First)
+
+	for _, first := range []int{0, pagination.MaxFirst + 1} {
+		_, err = pagination.NewPage(&first, nil)
+		assert.ErrorIs(t, err, pagination.ErrInvalidFirst)
+		_, err = pagination.NewSearchPage(&first, nil)
+		assert.ErrorIs(t, err, pagination.ErrInvalidFirst)
+	}
+
+	first := pagination.MaxFirst
+	page, err = pagination.NewPage(&first, nil)
+	require.NoError(t, err)
+	assert.Equal(t, first+1, *page.Limit())
+}
+
+func TestCursorMustMatchSort(t *testing.T) {
+	chronological := pagination.EncodeCursor(time.Now(), 1)
+	_, err := pagination.NewSortedPage(nil, &chronological, pagination.SortTop)
+	assert.ErrorIs(t, err, pagination.ErrInvalidCursor)
+
+	ranked := pagination.EncodeRankedCursor(1, 1, time.Now())
+	_, err = pagination.NewSortedPage(nil, &ranked, pagination.SortNewest)
+	assert.ErrorIs(t, err, pagination.ErrInvalidCursor)
+}
+
+func TestLessBreaksTiesByID(t *testing.T) {
+	created := time.Now()
+	a := pagination.Key{Created: created, ID: 1, Score: 3}
+	b := pagination.Key{Created: created, ID: 2, Score: 3}
+
+	assert.True(t, pagination.Page{Sort: pagination.SortOldest}.Less(a, b))
+	assert.True(t, pagination.Page{Sort: pagination.SortNewest}.Less(b, a))
+	assert.True(t, pagination.Page{Sort: pagination.SortTop}.Less(b, a))
+}
+
+func TestSearchCursorRoundTrip(t *testing.T) {
+	encoded := pagination.EncodeSearchCursor(0.25, "comment", 7)
+
+	page, err := pagination.NewSearchPage(nil, &encoded)
+	require.NoError(t, err)
+	assert.Equal(t, &pagination.SearchCursor{Rank: 0.25, Kind: "comment", ID: 7}, page.After)
+
+	assert.True(t, page.Includes(pagination.SearchCursor{Rank: 0.25, Kind: "comment", ID: 6}))
+	assert.True(t, page.Includes(pagination.SearchCursor{Rank: 0.25, Kind: "post", ID: 9}))
+	assert.False(t, page.Includes(pagination.SearchCursor{Rank: 0.5, Kind: "post", ID: 1}))
+
+	chronological := pagination.EncodeCursor(time.Now(), 1)
+	_, err = pagination.NewSearchPage(nil, &chronological)
+	assert.ErrorIs(t, err, pagination.ErrInvalidCursor)
+}
+
+func TestParseSince(t *testing.T) {
+	since, err := pagination.ParseSince("42")
+	require.NoError(t, err)
+	assert.Equal(t, pagination.Since{CommentID: 42}, since)
+
+	since, err = pagination.ParseSince("2025-01-01T12:00:00.5+03:00")
+	require.NoError(t, err)
+	assert.Zero(t, since.CommentID)
+	assert.True(t, since.Time.Equal(time.Date(2025, 1, 1, 9, 0, 0, 500000000, time.UTC)))
+	assert.Equal(t, time.Local, since.Time.Location())
+
+	for _, invalid := range []string{"", "0", "-1", "yesterday"} {
+		_, err = pagination.ParseSince(invalid)
+		assert.ErrorIs(t, err, pagination.ErrInvalidSince, invalid)
+	}
+}
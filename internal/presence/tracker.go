@@ -0,0 +1,414 @@
+package presence
+
+import (
+	"Commentary/internal/graph/model"
+	"Commentary/internal/pubsub"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"github.com/sirupsen/logrus"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Snapshot is the presence of a post: the number of viewers and the ids of users typing a comment.
+type Snapshot struct {
+	PostID  int
+	Viewers int
+	Typing  []int
+}
+
+// Viewer is a client watching the presence of a post. It counts as a viewer until it leaves
+// or misses heartbeats for longer than the TTL.
+type Viewer struct {
+	postID  int
+	updates chan Snapshot
+}
+
+// Updates receives the latest snapshot of the post, snapshots the viewer had no time to read
+// are replaced by newer ones. The channel is closed when the viewer leaves.
+func (v *Viewer) Updates() <-chan Snapshot {
+	return v.updates
+}
+
+type post struct {
+	watchers map[*Viewer]struct{}
+	// viewers and typing hold the expiry time of each entry
+	viewers map[*Viewer]time.Time
+	typing  map[int]time.Time
+	// shares hold the presence shared by the other replicas, by replica, while the post has watchers
+	shares map[string]*share
+	// sub receives the shares of the other replicas while the post has watchers
+	sub *pubsub.Subscription
+}
+
+// share is the presence of the viewers of another replica.
+type share struct {
+	viewers int
+	typing  []int
+	expiry  time.Time
+}
+
+func (p *post) empty() bool {
+	return len(p.watchers) == 0 && len(p.viewers) == 0 && len(p.typing) == 0
+}
+
+// Tracker keeps the presence of posts in memory. With a broker shared by several replicas, every
+// replica publishes the presence of its own viewers to the post and counts in the presence
+// published by the others, which expires like its own entries when the heartbeats stop.
+type Tracker struct {
+	ttl     time.Duration
+	broker  pubsub.Broker
+	replica string
+	posts   map[int]*post
+	// changed holds the posts whose presence is to be shared with the other replicas
+	changed map[int]struct{}
+	shared  chan struct{}
+	mu      sync.Mutex
+	stop    chan struct{}
+}
+
+// NewTracker starts a tracker expiring entries not refreshed within ttl. The broker shares the
+// presence between replicas, it is nil when there is a single one.
+func NewTracker(ttl time.Duration, broker pubsub.Broker) *Tracker {
+	t := &Tracker{
+		ttl:     ttl,
+		broker:  broker,
+		replica: newReplicaID(),
+		posts:   make(map[int]*post),
+		changed: make(map[int]struct{}),
+		shared:  make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+	}
+	go t.expireLoop()
+	if broker != nil {
+		go t.shareLoop()
+	}
+	return t
+}
+
+func newReplicaID() string {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		logrus.Fatalf("failed to generate presence replica id: %v", err)
+	}
+	return hex.EncodeToString(id)
+}
+
+// TTL is how long an entry lives without a heartbeat.
+func (t *Tracker) TTL() time.Duration {
+	return t.ttl
+}
+
+// Stop ends the expiry and the sharing of entries.
+func (t *Tracker) Stop() {
+	close(t.stop)
+}
+
+// Join registers a viewer of the post and sends the new snapshot to every viewer of it.
+func (t *Tracker) Join(postID int) *Viewer {
+	logrus.Debugf("joining presence of post %v", postID)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	viewer := &Viewer{postID: postID, updates: make(chan Snapshot, 1)}
+	p := t.post(postID)
+	p.watchers[viewer] = struct{}{}
+	p.viewers[viewer] = time.Now().Add(t.ttl)
+	if t.broker != nil && p.sub == nil {
+		t.follow(postID, p)
+	}
+	t.notify(postID, p)
+	t.share(postID)
+
+	return viewer
+}
+
+// Heartbeat keeps the viewer counted for another TTL.
+func (t *Tracker) Heartbeat(viewer *Viewer) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p, ok := t.posts[viewer.postID]
+	if !ok {
+		return
+	}
+	if _, watching := p.watchers[viewer]; !watching {
+		return
+	}
+	_, counted := p.viewers[viewer]
+	p.viewers[viewer] = time.Now().Add(t.ttl)
+	if !counted {
+		t.notify(viewer.postID, p)
+		t.share(viewer.postID)
+	}
+}
+
+// Leave removes the viewer and closes its updates.
+func (t *Tracker) Leave(viewer *Viewer) {
+	logrus.Debugf("leaving presence of post %v", viewer.postID)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p, ok := t.posts[viewer.postID]
+	if !ok {
+		return
+	}
+	if _, watching := p.watchers[viewer]; !watching {
+		return
+	}
+	delete(p.watchers, viewer)
+	delete(p.viewers, viewer)
+	close(viewer.updates)
+	t.notify(viewer.postID, p)
+	t.share(viewer.postID)
+	t.release(viewer.postID, p)
+}
+
+// SetTyping marks the user as typing a comment to the post for the TTL, calling it again
+// while the user keeps typing works as a heartbeat.
+func (t *Tracker) SetTyping(postID, userID int, typing bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p := t.post(postID)
+	_, wasTyping := p.typing[userID]
+	if typing {
+		p.typing[userID] = time.Now().Add(t.ttl)
+	} else {
+		delete(p.typing, userID)
+	}
+	if wasTyping != typing {
+		t.notify(postID, p)
+		t.share(postID)
+	}
+	t.release(postID, p)
+}
+
+// Snapshot returns the current presence of the post. The presence of the other replicas
+// is known only while the post has watchers here.
+func (t *Tracker) Snapshot(postID int) Snapshot {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p, ok := t.posts[postID]
+	if !ok {
+		return Snapshot{PostID: postID, Typing: []int{}}
+	}
+	return snapshot(postID, p)
+}
+
+func (t *Tracker) expireLoop() {
+	ticker := time.NewTicker(t.ttl / 4)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			t.expire(now)
+		case <-t.stop:
+			return
+		}
+	}
+}
+
+func (t *Tracker) expire(now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for postID, p := range t.posts {
+		changed := false
+		for viewer, expiry := range p.viewers {
+			if now.After(expiry) {
+				delete(p.viewers, viewer)
+				changed = true
+			}
+		}
+		for userID, expiry := range p.typing {
+			if now.After(expiry) {
+				delete(p.typing, userID)
+				changed = true
+			}
+		}
+		// the local entries are shared on every tick as the heartbeat of this replica
+		if changed || len(p.viewers) > 0 || len(p.typing) > 0 {
+			t.share(postID)
+		}
+		for replica, s := range p.shares {
+			if now.After(s.expiry) {
+				delete(p.shares, replica)
+				changed = true
+			}
+		}
+		if changed {
+			logrus.Debugf("expired presence entries of post %v", postID)
+			t.notify(postID, p)
+		}
+		t.release(postID, p)
+	}
+}
+
+// follow subscribes to the presence the other replicas share for the post.
+// Must be called with t.mu held.
+func (t *Tracker) follow(postID int, p *post) {
+	sub := t.broker.Subscribe(postID, func(event model.Event) bool {
+		shared, ok := event.(*model.PresenceShared)
+		return ok && shared.Replica != t.replica
+	})
+	p.sub = sub
+	go t.receive(postID, sub)
+}
+
+// receive counts in the shares of the other replicas until the subscription is closed. The broker
+// may close it for falling behind, then it is opened again while the post has watchers.
+func (t *Tracker) receive(postID int, sub *pubsub.Subscription) {
+	for event := range sub.Events() {
+		t.receiveShare(postID, sub, event.(*model.PresenceShared))
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if p, ok := t.posts[postID]; ok && p.sub == sub {
+		p.sub = nil
+		if len(p.watchers) > 0 {
+			t.follow(postID, p)
+		}
+	}
+}
+
+func (t *Tracker) receiveShare(postID int, sub *pubsub.Subscription, shared *model.PresenceShared) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p, ok := t.posts[postID]
+	if !ok || p.sub != sub {
+		return
+	}
+
+	previous, known := p.shares[shared.Replica]
+	if shared.Viewers == 0 && len(shared.Typing) == 0 {
+		if !known {
+			return
+		}
+		delete(p.shares, shared.Replica)
+	} else {
+		p.shares[shared.Replica] = &share{
+			viewers: shared.Viewers,
+			typing:  shared.Typing,
+			expiry:  time.Now().Add(t.ttl),
+		}
+		// a heartbeat only keeps the share for another TTL
+		if known && previous.viewers == shared.Viewers && slices.Equal(previous.typing, shared.Typing) {
+			return
+		}
+	}
+	t.notify(postID, p)
+}
+
+// share queues the local presence of the post to be published to the other replicas.
+// Must be called with t.mu held.
+func (t *Tracker) share(postID int) {
+	if t.broker == nil {
+		return
+	}
+	t.changed[postID] = struct{}{}
+	select {
+	case t.shared <- struct{}{}:
+	default:
+	}
+}
+
+// shareLoop publishes the queued presence, outside the lock since publishing may reach the database.
+func (t *Tracker) shareLoop() {
+	for {
+		select {
+		case <-t.shared:
+		case <-t.stop:
+			return
+		}
+
+		t.mu.Lock()
+		shares := make(map[int]*model.PresenceShared, len(t.changed))
+		for postID := range t.changed {
+			shares[postID] = t.localShare(postID)
+		}
+		clear(t.changed)
+		t.mu.Unlock()
+
+		for postID, shared := range shares {
+			t.broker.Publish(context.Background(), postID, shared)
+		}
+	}
+}
+
+// localShare is the presence of the viewers of this replica, must be called with t.mu held.
+func (t *Tracker) localShare(postID int) *model.PresenceShared {
+	shared := &model.PresenceShared{Replica: t.replica, Typing: []int{}}
+	if p, ok := t.posts[postID]; ok {
+		shared.Viewers = len(p.viewers)
+		for userID := range p.typing {
+			shared.Typing = append(shared.Typing, userID)
+		}
+		sort.Ints(shared.Typing)
+	}
+	return shared
+}
+
+// post must be called with t.mu held.
+func (t *Tracker) post(postID int) *post {
+	p, ok := t.posts[postID]
+	if !ok {
+		p = &post{
+			watchers: make(map[*Viewer]struct{}),
+			viewers:  make(map[*Viewer]time.Time),
+			typing:   make(map[int]time.Time),
+			shares:   make(map[string]*share),
+		}
+		t.posts[postID] = p
+	}
+	return p
+}
+
+// release must be called with t.mu held.
+func (t *Tracker) release(postID int, p *post) {
+	if len(p.watchers) == 0 && p.sub != nil {
+		t.broker.Unsubscribe(p.sub)
+		p.sub = nil
+		clear(p.shares)
+	}
+	if p.empty() {
+		delete(t.posts, postID)
+	}
+}
+
+// notify replaces the unread snapshot of every watcher with the current one. It never blocks,
+// since the tracker is the only sender and does it with t.mu held.
+func (t *Tracker) notify(postID int, p *post) {
+	current := snapshot(postID, p)
+	for viewer := range p.watchers {
+		select {
+		case <-viewer.updates:
+		default:
+		}
+		viewer.updates <- current
+	}
+}
+
+func snapshot(postID int, p *post) Snapshot {
+	viewers := len(p.viewers)
+	typing := make([]int, 0, len(p.typing))
+	for userID := range p.typing {
+		typing = append(typing, userID)
+	}
+	for _, s := range p.shares {
+		viewers += s.viewers
+		typing = append(typing, s.typing...)
+	}
+	sort.Ints(typing)
+	return Snapshot{PostID: postID, Viewers: viewers, Typing: slices.Compact(typing)}
+}
@@ -0,0 +1,131 @@
+package presence
+
+import (
+	"Commentary/internal/presence"
+	"Commentary/internal/pubsub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"slices"
+	"testing"
+	"time"
+)
+
+const ttl = 100 * time.Millisecond
+
+func latest(t *testing.T, viewer *presence.Viewer) presence.Snapshot {
+	select {
+	case snapshot := <-viewer.Updates():
+		return snapshot
+	case <-time.After(time.Second):
+		require.FailNow(t, "no presence update")
+		return presence.Snapshot{}
+	}
+}
+
+func TestJoinAndLeave(t *testing.T) {
+	tracker := presence.NewTracker(ttl, nil)
+	defer tracker.Stop()
+
+	first := tracker.Join(1)
+	assert.Equal(t, 1, latest(t, first).Viewers)
+	second := tracker.Join(1)
+	assert.Equal(t, 2, latest(t, first).Viewers)
+	assert.Equal(t, 2, latest(t, second).Viewers)
+	other := tracker.Join(2)
+	assert.Equal(t, 1, latest(t, other).Viewers)
+
+	tracker.Leave(second)
+	assert.Equal(t, 1, latest(t, first).Viewers)
+	_, open := <-second.Updates()
+	assert.False(t, open)
+	assert.Equal(t, 1, tracker.Snapshot(1).Viewers)
+}
+
+func TestTyping(t *testing.T) {
+	tracker := presence.NewTracker(ttl, nil)
+	defer tracker.Stop()
+	viewer := tracker.Join(1)
+	latest(t, viewer)
+
+	tracker.SetTyping(1, 7, true)
+	tracker.SetTyping(1, 3, true)
+	assert.Equal(t, []int{3, 7}, latest(t, viewer).Typing)
+
+	tracker.SetTyping(1, 7, false)
+	assert.Equal(t, []int{3}, latest(t, viewer).Typing)
+}
+
+func TestEntriesExpire(t *testing.T) {
+	tracker := presence.NewTracker(ttl, nil)
+	defer tracker.Stop()
+	kept := tracker.Join(1)
+	expired := tracker.Join(1)
+	tracker.SetTyping(1, 7, true)
+
+	deadline := time.After(3 * ttl)
+	heartbeat := time.NewTicker(ttl / 2)
+	defer heartbeat.Stop()
+	for done := false; !done; {
+		select {
+		case <-heartbeat.C:
+			tracker.Heartbeat(kept)
+		case <-deadline:
+			done = true
+		}
+	}
+
+	snapshot := tracker.Snapshot(1)
+	assert.Equal(t, 1, snapshot.Viewers)
+	assert.Empty(t, snapshot.Typing)
+
+	tracker.Heartbeat(expired)
+	assert.Equal(t, 2, tracker.Snapshot(1).Viewers)
+}
+
+// keepAlive sends heartbeats of the viewer the way the presence subscription does.
+func keepAlive(t *testing.T, tracker *presence.Tracker, viewer *presence.Viewer) {
+	done := make(chan struct{})
+	t.Cleanup(func() { close(done) })
+	go func() {
+		heartbeat := time.NewTicker(ttl / 2)
+		defer heartbeat.Stop()
+		for {
+			select {
+			case <-heartbeat.C:
+				tracker.Heartbeat(viewer)
+			case <-done:
+				return
+			}
+		}
+	}()
+}
+
+func TestReplicasSharePresence(t *testing.T) {
+	broker := pubsub.NewLocalBroker(pubsub.Options{Buffer: 10, Policy: pubsub.DropOldest})
+	first, second, third := presence.NewTracker(ttl, broker), presence.NewTracker(ttl, broker),
+		presence.NewTracker(ttl, broker)
+	defer first.Stop()
+	defer second.Stop()
+
+	keepAlive(t, first, first.Join(1))
+	other := second.Join(1)
+	keepAlive(t, second, other)
+	second.SetTyping(1, 7, true)
+	require.Eventually(t, func() bool {
+		snapshot := first.Snapshot(1)
+		return snapshot.Viewers == 2 && slices.Equal(snapshot.Typing, []int{7})
+	}, time.Second, ttl/10)
+
+	second.Leave(other)
+	second.SetTyping(1, 7, false)
+	require.Eventually(t, func() bool {
+		snapshot := first.Snapshot(1)
+		return snapshot.Viewers == 1 && len(snapshot.Typing) == 0
+	}, time.Second, ttl/10)
+
+	// a replica that stops sharing is forgotten after the TTL
+	third.Join(1)
+	require.Eventually(t, func() bool { return first.Snapshot(1).Viewers == 2 }, time.Second, ttl/10)
+	third.Stop()
+	require.Eventually(t, func() bool { return first.Snapshot(1).Viewers == 1 }, time.Second, ttl/10)
+}
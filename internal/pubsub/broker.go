@@ -2,64 +2,87 @@ package pubsub
 
 import (
 	"Commentary/internal/graph/model"
-	"github.com/sirupsen/logrus"
-	"sync"
+	"context"
+	"errors"
+	"fmt"
+	"time"
 )
 
-type Broker struct {
-	subs map[int][]chan *model.Comment
-	mu   sync.RWMutex
-}
-
-func NewBroker() *Broker {
-	return &Broker{
-		subs: make(map[int][]chan *model.Comment),
-	}
-}
+const (
+	// BackendMemory delivers events within a single process.
+	BackendMemory = "memory"
+	// BackendPostgres delivers events to every replica sharing the database.
+	BackendPostgres = "postgres"
+)
 
-func (b *Broker) Subscribe(postID int) <-chan *model.Comment {
-	logrus.Debugf("subscribing to post %v", postID)
+// Policy decides what happens to an event when a subscriber's buffer is full.
+type Policy string
 
-	b.mu.Lock()
+const (
+	// DropOldest discards the oldest buffered event to make room for the new one.
+	DropOldest Policy = "drop_oldest"
+	// Disconnect closes the subscription with ErrSlowConsumer.
+	Disconnect Policy = "disconnect"
+	// Block waits for the subscriber up to Options.BlockTimeout and drops the event after it. The events
+	// wait in a queue of another Buffer events, so publishers are never held up.
+	Block Policy = "block"
+)
 
-	defer b.mu.Unlock()
+var (
+	ErrSlowConsumer  = errors.New("subscription closed because the client does not keep up with events")
+	ErrUnknownPolicy = errors.New("unknown backpressure policy")
+	ErrInvalidBuffer = errors.New("subscriber buffer must hold at least one event")
+	ErrInvalidWait   = errors.New("block timeout must be positive for the block policy")
+)
 
-	ch := make(chan *model.Comment, 10)
-	b.subs[postID] = append(b.subs[postID], ch)
-	logrus.Debugf("subscribed to post %v", postID)
+type Options struct {
+	// Buffer is the number of events kept for a subscriber that falls behind.
+	Buffer       int
+	Policy       Policy
+	BlockTimeout time.Duration
+}
 
-	return ch
+func (o Options) Validate() error {
+	switch o.Policy {
+	case DropOldest, Disconnect, Block:
+	default:
+		return fmt.Errorf("%w %q", ErrUnknownPolicy, o.Policy)
+	}
+	if o.Buffer < 1 {
+		return fmt.Errorf("%w, got %d", ErrInvalidBuffer, o.Buffer)
+	}
+	if o.Policy == Block && o.BlockTimeout <= 0 {
+		return fmt.Errorf("%w, got %v", ErrInvalidWait, o.BlockTimeout)
+	}
+	return nil
 }
 
-func (b *Broker) Unsubscribe(postID int, ch <-chan *model.Comment) {
-	logrus.Debugf("unsubscribing from post %v", postID)
+// Stats counts events handled by a broker since it was started.
+type Stats struct {
+	Subscribers  int
+	Published    int64
+	Delivered    int64
+	Dropped      int64
+	Disconnected int64
+}
 
-	b.mu.Lock()
+// Filter selects events a subscription is interested in, nil accepts every event.
+type Filter func(event model.Event) bool
 
-	defer b.mu.Unlock()
-	defer logrus.Debugf("unsubscribed from post %v", postID)
+// FeedTopic is subscribed to instead of a post for events concerning all posts,
+// it can not clash with a post since posts are numbered from 1.
+const FeedTopic = 0
 
-	subs := b.subs[postID]
-	for i, sub := range subs {
-		if sub == ch {
-			close(sub)
-			b.subs[postID] = append(subs[:i], subs[i+1:]...)
-			break
-		}
-	}
+// UserTopic is subscribed to for events addressed to the user, user topics are negative
+// so that they do not clash with posts either.
+func UserTopic(userID int) int {
+	return -userID
 }
 
-func (b *Broker) Publish(postID int, comment *model.Comment) {
-	logrus.Debugf("publishing comment %s to post %v", comment.Content, postID)
-
-	b.mu.RLock()
-	defer b.mu.RUnlock()
-	for _, ch := range b.subs[postID] {
-		select {
-		case ch <- comment:
-			logrus.Debugf("published comment %s to post %v", comment.Content, postID)
-		default:
-			logrus.Errorf("failed to publish comment %s to post %v", comment.Content, postID)
-		}
-	}
+// Broker delivers events to subscribers of a post.
+type Broker interface {
+	Subscribe(postID int, filter Filter) *Subscription
+	Unsubscribe(sub *Subscription)
+	Publish(ctx context.Context, postID int, event model.Event)
+	Stats() Stats
 }
@@ -0,0 +1,233 @@
+package pubsub
+
+import (
+	"Commentary/internal/graph/model"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrUnknownEvent = errors.New("unknown event")
+
+const (
+	eventCreated = "created"
+	eventUpdated = "updated"
+	eventDeleted = "deleted"
+	eventToggled = "toggled"
+
+	eventPostCreated = "post_created"
+	eventPostUpdated = "post_updated"
+	eventPostDeleted = "post_deleted"
+
+	eventNotificationAdded = "notification_added"
+
+	eventPresenceShared = "presence_shared"
+)
+
+// message is the encoded form of an event. It has its own comment, post and notification types,
+// since the models hide their ids from JSON.
+type message struct {
+	PostID        int           `json:"postID"`
+	Type          string        `json:"type"`
+	Comment       *comment      `json:"comment,omitempty"`
+	CommentID     int           `json:"commentID,omitempty"`
+	ParentID      *int          `json:"parentID,omitempty"`
+	Commentable   bool          `json:"commentable,omitempty"`
+	Post          *post         `json:"post,omitempty"`
+	DeletedPostID int           `json:"deletedPostID,omitempty"`
+	Notification  *notification `json:"notification,omitempty"`
+	Replica       string        `json:"replica,omitempty"`
+	Viewers       int           `json:"viewers,omitempty"`
+	Typing        []int         `json:"typing,omitempty"`
+	HiddenThread  bool          `json:"hiddenThread,omitempty"`
+}
+
+type comment struct {
+	ID       int        `json:"id"`
+	PostID   int        `json:"postID"`
+	AuthorID int        `json:"authorID"`
+	Content  string     `json:"content"`
+	Created  time.Time  `json:"created"`
+	ParentID *int       `json:"parentID,omitempty"`
+	EditedAt *time.Time `json:"editedAt,omitempty"`
+	Deleted  bool       `json:"deleted,omitempty"`
+	Hidden   bool       `json:"hidden,omitempty"`
+}
+
+func encodeComment(c *model.Comment) *comment {
+	if c == nil {
+		return nil
+	}
+	return &comment{
+		ID:       c.ID,
+		PostID:   c.PostID,
+		AuthorID: c.AuthorID,
+		Content:  c.Content,
+		Created:  c.Created,
+		ParentID: c.ParentID,
+		EditedAt: c.EditedAt,
+		Deleted:  c.Deleted,
+		Hidden:   c.Hidden,
+	}
+}
+
+func (c *comment) model() *model.Comment {
+	if c == nil {
+		return nil
+	}
+	return &model.Comment{
+		ID:       c.ID,
+		PostID:   c.PostID,
+		AuthorID: c.AuthorID,
+		Content:  c.Content,
+		Created:  c.Created,
+		ParentID: c.ParentID,
+		EditedAt: c.EditedAt,
+		Deleted:  c.Deleted,
+		Hidden:   c.Hidden,
+	}
+}
+
+type post struct {
+	ID          int        `json:"id"`
+	AuthorID    int        `json:"authorID"`
+	Title       string     `json:"title"`
+	Content     string     `json:"content"`
+	Created     time.Time  `json:"created"`
+	Commentable bool       `json:"commentable,omitempty"`
+	EditedAt    *time.Time `json:"editedAt,omitempty"`
+	Hidden      bool       `json:"hidden,omitempty"`
+}
+
+func encodePost(p *model.Post) *post {
+	return &post{
+		ID:          p.ID,
+		AuthorID:    p.AuthorID,
+		Title:       p.Title,
+		Content:     p.Content,
+		Created:     p.Created,
+		Commentable: p.Commentable,
+		EditedAt:    p.EditedAt,
+		Hidden:      p.Hidden,
+	}
+}
+
+func (p *post) model() *model.Post {
+	if p == nil {
+		return nil
+	}
+	return &model.Post{
+		ID:          p.ID,
+		AuthorID:    p.AuthorID,
+		Title:       p.Title,
+		Content:     p.Content,
+		Created:     p.Created,
+		Commentable: p.Commentable,
+		EditedAt:    p.EditedAt,
+		Hidden:      p.Hidden,
+	}
+}
+
+type notification struct {
+	ID        int       `json:"id"`
+	UserID    int       `json:"userID"`
+	Kind      string    `json:"kind"`
+	CommentID int       `json:"commentID"`
+	ActorID   int       `json:"actorID"`
+	Created   time.Time `json:"created"`
+	Read      bool      `json:"read,omitempty"`
+}
+
+func encodeNotification(n *model.Notification) *notification {
+	return &notification{
+		ID:        n.ID,
+		UserID:    n.UserID,
+		Kind:      string(n.Kind),
+		CommentID: n.CommentID,
+		ActorID:   n.ActorID,
+		Created:   n.Created,
+		Read:      n.Read,
+	}
+}
+
+func (n *notification) model() *model.Notification {
+	if n == nil {
+		return nil
+	}
+	return &model.Notification{
+		ID:        n.ID,
+		UserID:    n.UserID,
+		Kind:      model.NotificationKind(n.Kind),
+		CommentID: n.CommentID,
+		ActorID:   n.ActorID,
+		Created:   n.Created,
+		Read:      n.Read,
+	}
+}
+
+// EncodeEvent serializes an event published to the post for brokers that send events between processes.
+func EncodeEvent(postID int, event model.Event) ([]byte, error) {
+	msg := message{PostID: postID}
+	switch e := event.(type) {
+	case *model.CommentCreated:
+		msg.Type, msg.Comment, msg.HiddenThread = eventCreated, encodeComment(e.Comment), e.HiddenThread
+	case *model.CommentUpdated:
+		msg.Type, msg.Comment, msg.HiddenThread = eventUpdated, encodeComment(e.Comment), e.HiddenThread
+	case *model.CommentDeleted:
+		msg.Type, msg.Comment, msg.CommentID, msg.ParentID = eventDeleted, encodeComment(e.Tombstone), e.CommentID,
+			e.ParentID
+		msg.HiddenThread = e.HiddenThread
+	case *model.CommentsToggled:
+		msg.Type, msg.Commentable = eventToggled, e.Commentable
+	case *model.PostCreated:
+		msg.Type, msg.Post = eventPostCreated, encodePost(e.Post)
+	case *model.PostUpdated:
+		msg.Type, msg.Post = eventPostUpdated, encodePost(e.Post)
+	case *model.PostDeleted:
+		msg.Type, msg.DeletedPostID = eventPostDeleted, e.PostID
+	case *model.NotificationAdded:
+		msg.Type, msg.Notification = eventNotificationAdded, encodeNotification(e.Notification)
+	case *model.PresenceShared:
+		msg.Type, msg.Replica, msg.Viewers, msg.Typing = eventPresenceShared, e.Replica, e.Viewers, e.Typing
+	default:
+		return nil, fmt.Errorf("%w: %T", ErrUnknownEvent, event)
+	}
+	return json.Marshal(msg)
+}
+
+// DecodeEvent restores an event encoded by EncodeEvent together with the id of its post.
+func DecodeEvent(payload []byte) (int, model.Event, error) {
+	var msg message
+	if err := json.Unmarshal(payload, &msg); err != nil {
+		return 0, nil, err
+	}
+
+	switch msg.Type {
+	case eventCreated:
+		return msg.PostID, &model.CommentCreated{Comment: msg.Comment.model(), HiddenThread: msg.HiddenThread}, nil
+	case eventUpdated:
+		return msg.PostID, &model.CommentUpdated{Comment: msg.Comment.model(), HiddenThread: msg.HiddenThread}, nil
+	case eventDeleted:
+		return msg.PostID, &model.CommentDeleted{
+			CommentID:    msg.CommentID,
+			ParentID:     msg.ParentID,
+			Tombstone:    msg.Comment.model(),
+			HiddenThread: msg.HiddenThread,
+		}, nil
+	case eventToggled:
+		return msg.PostID, &model.CommentsToggled{PostID: msg.PostID, Commentable: msg.Commentable}, nil
+	case eventPostCreated:
+		return msg.PostID, &model.PostCreated{Post: msg.Post.model()}, nil
+	case eventPostUpdated:
+		return msg.PostID, &model.PostUpdated{Post: msg.Post.model()}, nil
+	case eventPostDeleted:
+		return msg.PostID, &model.PostDeleted{PostID: msg.DeletedPostID}, nil
+	case eventNotificationAdded:
+		return msg.PostID, &model.NotificationAdded{Notification: msg.Notification.model()}, nil
+	case eventPresenceShared:
+		return msg.PostID, &model.PresenceShared{Replica: msg.Replica, Viewers: msg.Viewers, Typing: msg.Typing}, nil
+	default:
+		return 0, nil, fmt.Errorf("%w: %q", ErrUnknownEvent, msg.Type)
+	}
+}
@@ -0,0 +1,70 @@
+package pubsub
+
+import (
+	"context"
+	"github.com/99designs/gqlgen/graphql"
+	"sync"
+)
+
+type ctxKey struct{}
+
+// tracked holds the subscription serving the operation.
+type tracked struct {
+	mu  sync.Mutex
+	sub *Subscription
+}
+
+// Track remembers the subscription serving the operation, so that Extension can report
+// the error it was closed with.
+func Track(ctx context.Context, sub *Subscription) {
+	if t, ok := ctx.Value(ctxKey{}).(*tracked); ok {
+		t.mu.Lock()
+		t.sub = sub
+		t.mu.Unlock()
+	}
+}
+
+// Extension ends a subscription closed by the broker, e.g. with ErrSlowConsumer, with an error
+// response instead of completing it silently. The error goes through the error presenter
+// of the server like the errors of resolvers.
+type Extension struct{}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.OperationInterceptor
+	graphql.ResponseInterceptor
+} = Extension{}
+
+func (e Extension) ExtensionName() string {
+	return "SubscriptionErrors"
+}
+
+func (e Extension) Validate(graphql.ExecutableSchema) error {
+	return nil
+}
+
+func (e Extension) InterceptOperation(ctx context.Context, next graphql.OperationHandler) graphql.ResponseHandler {
+	return next(context.WithValue(ctx, ctxKey{}, &tracked{}))
+}
+
+func (e Extension) InterceptResponse(ctx context.Context, next graphql.ResponseHandler) *graphql.Response {
+	response := next(ctx)
+	if response != nil {
+		return response
+	}
+
+	t, ok := ctx.Value(ctxKey{}).(*tracked)
+	if !ok {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.sub == nil || t.sub.Err() == nil {
+		return nil
+	}
+	err := t.sub.Err()
+	t.sub = nil
+	graphql.AddError(ctx, err)
+	return &graphql.Response{Errors: graphql.GetErrors(ctx)}
+}
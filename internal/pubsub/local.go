@@ -0,0 +1,103 @@
+package pubsub
+
+import (
+	"Commentary/internal/graph/model"
+	"context"
+	"github.com/sirupsen/logrus"
+	"sync"
+	"sync/atomic"
+)
+
+type counters struct {
+	published    atomic.Int64
+	delivered    atomic.Int64
+	dropped      atomic.Int64
+	disconnected atomic.Int64
+}
+
+// localBroker keeps subscribers in memory, events reach only subscribers of the same process.
+type localBroker struct {
+	subs     map[int]map[*Subscription]struct{}
+	options  Options
+	counters counters
+	mu       sync.RWMutex
+}
+
+func NewLocalBroker(options Options) Broker {
+	return newLocalBroker(options)
+}
+
+func newLocalBroker(options Options) *localBroker {
+	return &localBroker{
+		subs:    make(map[int]map[*Subscription]struct{}),
+		options: options,
+	}
+}
+
+func (b *localBroker) Subscribe(postID int, filter Filter) *Subscription {
+	logrus.Debugf("subscribing to post %v", postID)
+
+	b.mu.Lock()
+
+	defer b.mu.Unlock()
+
+	sub := newSubscription(postID, filter, b.options, &b.counters)
+	if b.subs[postID] == nil {
+		b.subs[postID] = make(map[*Subscription]struct{})
+	}
+	b.subs[postID][sub] = struct{}{}
+	logrus.Debugf("subscribed to post %v", postID)
+
+	return sub
+}
+
+func (b *localBroker) Unsubscribe(sub *Subscription) {
+	logrus.Debugf("unsubscribing from post %v", sub.postID)
+
+	b.mu.Lock()
+	delete(b.subs[sub.postID], sub)
+	if len(b.subs[sub.postID]) == 0 {
+		delete(b.subs, sub.postID)
+	}
+	b.mu.Unlock()
+
+	sub.mu.Lock()
+	sub.close()
+	sub.mu.Unlock()
+	logrus.Debugf("unsubscribed from post %v", sub.postID)
+}
+
+// Publish hands the event to every subscriber of the post without waiting for any of them.
+func (b *localBroker) Publish(_ context.Context, postID int, event model.Event) {
+	logrus.Debugf("publishing %T to post %v", event, postID)
+
+	b.mu.RLock()
+	subs := make([]*Subscription, 0, len(b.subs[postID]))
+	for sub := range b.subs[postID] {
+		subs = append(subs, sub)
+	}
+	b.mu.RUnlock()
+
+	b.counters.published.Add(1)
+	for _, sub := range subs {
+		sub.deliver(event)
+	}
+	logrus.Debugf("published %T to post %v", event, postID)
+}
+
+func (b *localBroker) Stats() Stats {
+	b.mu.RLock()
+	subscribers := 0
+	for _, subs := range b.subs {
+		subscribers += len(subs)
+	}
+	b.mu.RUnlock()
+
+	return Stats{
+		Subscribers:  subscribers,
+		Published:    b.counters.published.Load(),
+		Delivered:    b.counters.delivered.Load(),
+		Dropped:      b.counters.dropped.Load(),
+		Disconnected: b.counters.disconnected.Load(),
+	}
+}
@@ -0,0 +1,137 @@
+package pubsub
+
+import (
+	"Commentary/internal/graph/model"
+	"context"
+	"database/sql"
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+	"time"
+)
+
+const (
+	// notifyChannel is the PostgreSQL channel events of all posts are sent to.
+	notifyChannel = "comment_events"
+	// storedChannel gets the ids of events too large for a NOTIFY payload, which are kept in
+	// the pubsub_events table for storedRetention.
+	storedChannel   = "comment_events_stored"
+	storedRetention = "1 minute"
+	// cleanupInterval is how often stored events past their retention are deleted.
+	cleanupInterval = time.Minute
+	// publishTimeout bounds the notification sent for a mutation, so a slow database does not hold up its response.
+	publishTimeout = 5 * time.Second
+	// maxNotifyPayload is the largest payload NOTIFY accepts, in bytes.
+	maxNotifyPayload = 7999
+)
+
+// postgresBroker sends events through PostgreSQL NOTIFY. Every replica listens on the channels
+// and hands the events to its own subscribers, so a subscriber gets events published on any replica,
+// including the ones published by its own replica.
+type postgresBroker struct {
+	*localBroker
+	db       *sql.DB
+	listener *pq.Listener
+}
+
+func NewPostgresBroker(db *sql.DB, dsn string, options Options) (Broker, error) {
+	listener := pq.NewListener(dsn, 100*time.Millisecond, time.Minute,
+		func(event pq.ListenerEventType, err error) {
+			if err != nil {
+				logrus.WithError(err).Error("pubsub listener connection error")
+			}
+		})
+	for _, channel := range []string{notifyChannel, storedChannel} {
+		if err := listener.Listen(channel); err != nil {
+			logrus.WithError(err).Error("failed to listen for events")
+			return nil, err
+		}
+	}
+
+	b := &postgresBroker{localBroker: newLocalBroker(options), db: db, listener: listener}
+	go b.listen()
+	go b.cleanup()
+	return b, nil
+}
+
+// Publish notifies the event within publishTimeout. The event is sent once its mutation is done,
+// so a request cancelled meanwhile does not cancel the notification.
+func (b *postgresBroker) Publish(ctx context.Context, postID int, event model.Event) {
+	logrus.Debugf("notifying %T to post %v", event, postID)
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), publishTimeout)
+	defer cancel()
+
+	payload, err := EncodeEvent(postID, event)
+	if err != nil {
+		logrus.WithError(err).Error("failed to encode event")
+		return
+	}
+
+	if len(payload) <= maxNotifyPayload {
+		_, err = b.db.ExecContext(ctx, "SELECT pg_notify($1, $2)", notifyChannel, string(payload))
+	} else {
+		err = b.notifyStored(ctx, payload)
+	}
+	// Without the notification even the subscribers of this replica would miss the event.
+	if err != nil {
+		logrus.WithError(err).Errorf("failed to notify %T to post %v, delivering it to local subscribers only",
+			event, postID)
+		b.localBroker.Publish(ctx, postID, event)
+	}
+}
+
+// notifyStored saves an event too large for NOTIFY and notifies its id. Notifications are sent
+// on commit, so listeners always find the saved event.
+func (b *postgresBroker) notifyStored(ctx context.Context, payload []byte) error {
+	_, err := b.db.ExecContext(ctx, `WITH stored AS (INSERT INTO pubsub_events (payload) VALUES ($2) RETURNING id)
+		SELECT pg_notify($1, id::TEXT) FROM stored`, storedChannel, string(payload))
+	return err
+}
+
+// cleanup periodically deletes stored events every listener has had storedRetention to load.
+func (b *postgresBroker) cleanup() {
+	ticker := time.NewTicker(cleanupInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		ctx, cancel := context.WithTimeout(context.Background(), publishTimeout)
+		if _, err := b.db.ExecContext(ctx, "DELETE FROM pubsub_events WHERE created < NOW() - $1::INTERVAL",
+			storedRetention); err != nil {
+			logrus.WithError(err).Warn("failed to delete old stored events")
+		}
+		cancel()
+	}
+}
+
+// payload returns the encoded event of the notification, loading stored ones.
+func (b *postgresBroker) payload(notification *pq.Notification) ([]byte, error) {
+	if notification.Channel != storedChannel {
+		return []byte(notification.Extra), nil
+	}
+
+	var payload string
+	err := b.db.QueryRow("SELECT payload FROM pubsub_events WHERE id = $1", notification.Extra).Scan(&payload)
+	return []byte(payload), err
+}
+
+func (b *postgresBroker) listen() {
+	for notification := range b.listener.Notify {
+		// A nil notification follows a reconnect, events sent in between are lost.
+		if notification == nil {
+			logrus.Warn("pubsub listener reconnected, some events may be lost")
+			continue
+		}
+
+		payload, err := b.payload(notification)
+		if err != nil {
+			logrus.WithError(err).Error("failed to load stored event")
+			continue
+		}
+		postID, event, err := DecodeEvent(payload)
+		if err != nil {
+			logrus.WithError(err).Error("failed to decode event")
+			continue
+		}
+		b.localBroker.Publish(context.Background(), postID, event)
+	}
+}
@@ -0,0 +1,179 @@
+package pubsub
+
+import (
+	"Commentary/internal/graph/model"
+	"github.com/sirupsen/logrus"
+	"sync"
+	"time"
+)
+
+// Subscription receives events of a post accepted by its filter. Accepted events are numbered
+// from 1, so events lost to the backpressure policy show up as gaps in the sequence.
+type Subscription struct {
+	postID   int
+	filter   Filter
+	events   chan model.Event
+	options  Options
+	counters *counters
+
+	mu       sync.Mutex
+	sequence int
+	dropped  int
+	closed   bool
+	err      error
+	// pending are the events waiting for a subscriber with the Block policy, they are sent
+	// by a goroutine running while pumping is set. It closes events itself if the subscription
+	// is closed meanwhile, done tells it to stop waiting.
+	pending []model.Event
+	pumping bool
+	done    chan struct{}
+}
+
+func newSubscription(postID int, filter Filter, options Options, counters *counters) *Subscription {
+	return &Subscription{
+		postID:   postID,
+		filter:   filter,
+		events:   make(chan model.Event, options.Buffer),
+		options:  options,
+		counters: counters,
+		done:     make(chan struct{}),
+	}
+}
+
+// Events is closed when the subscription ends.
+func (s *Subscription) Events() <-chan model.Event {
+	return s.events
+}
+
+// Err returns the error the broker closed the subscription with, if any.
+func (s *Subscription) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// Dropped returns the number of events the subscriber lost.
+func (s *Subscription) Dropped() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.dropped
+}
+
+// deliver numbers the event and hands it over according to the backpressure policy.
+// Numbering and sending happen under the lock, so events are received in the order of their numbers.
+// It never waits for the subscriber, events of the Block policy wait in the pending queue.
+func (s *Subscription) deliver(event model.Event) {
+	if s.filter != nil && !s.filter(event) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return
+	}
+	s.sequence++
+	event = event.WithSequence(s.sequence)
+
+	// events must not overtake the pending ones
+	if s.pumping {
+		s.enqueue(event)
+		return
+	}
+	select {
+	case s.events <- event:
+		s.counters.delivered.Add(1)
+		return
+	default:
+	}
+
+	switch s.options.Policy {
+	case DropOldest:
+		for {
+			select {
+			case s.events <- event:
+				s.counters.delivered.Add(1)
+				return
+			default:
+			}
+			select {
+			case <-s.events:
+				s.drop()
+			default:
+			}
+		}
+	case Disconnect:
+		s.err = ErrSlowConsumer
+		s.close()
+		s.counters.disconnected.Add(1)
+	default:
+		s.enqueue(event)
+	}
+}
+
+// enqueue adds the event to the pending ones, dropping it if as many events as the buffer holds
+// are already waiting. Must be called with s.mu held.
+func (s *Subscription) enqueue(event model.Event) {
+	if len(s.pending) >= s.options.Buffer {
+		s.drop()
+		return
+	}
+	s.pending = append(s.pending, event)
+	if !s.pumping {
+		s.pumping = true
+		go s.pump()
+	}
+}
+
+// pump sends the pending events one by one, waiting for the subscriber up to the block timeout
+// for each of them, so that publishers never wait.
+func (s *Subscription) pump() {
+	for {
+		s.mu.Lock()
+		if s.closed {
+			close(s.events)
+			s.mu.Unlock()
+			return
+		}
+		if len(s.pending) == 0 {
+			s.pumping = false
+			s.mu.Unlock()
+			return
+		}
+		event := s.pending[0]
+		s.pending = s.pending[1:]
+		s.mu.Unlock()
+
+		timer := time.NewTimer(s.options.BlockTimeout)
+		select {
+		case s.events <- event:
+			s.counters.delivered.Add(1)
+		case <-timer.C:
+			s.mu.Lock()
+			s.drop()
+			s.mu.Unlock()
+		case <-s.done:
+		}
+		timer.Stop()
+	}
+}
+
+// drop must be called with s.mu held.
+func (s *Subscription) drop() {
+	s.dropped++
+	s.counters.dropped.Add(1)
+	logrus.Warnf("dropped event for a slow subscriber of post %v", s.postID)
+}
+
+// close must be called with s.mu held. While pending events are sent, the pump closes events
+// as soon as it stops waiting.
+func (s *Subscription) close() {
+	if !s.closed {
+		s.closed = true
+		close(s.done)
+		if !s.pumping {
+			close(s.events)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package pubsub
+
+import (
+	"Commentary/internal/graph/model"
+	"Commentary/internal/pubsub"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func created(id int) model.CommentEvent {
+	return &model.CommentCreated{Comment: &model.Comment{ID: id, PostID: 1}}
+}
+
+func newBroker(policy pubsub.Policy) pubsub.Broker {
+	return pubsub.NewLocalBroker(pubsub.Options{Buffer: 2, Policy: policy, BlockTimeout: 100 * time.Millisecond})
+}
+
+func TestPublishNumbersEvents(t *testing.T) {
+	broker := newBroker(pubsub.DropOldest)
+	sub := broker.Subscribe(1, nil)
+	other := broker.Subscribe(2, nil)
+
+	broker.Publish(context.Background(), 1, created(1))
+	broker.Publish(context.Background(), 1, &model.CommentDeleted{CommentID: 1})
+
+	first := <-sub.Events()
+	require.IsType(t, &model.CommentCreated{}, first)
+	assert.Equal(t, 1, first.GetSequence())
+	second := <-sub.Events()
+	require.IsType(t, &model.CommentDeleted{}, second)
+	assert.Equal(t, 2, second.GetSequence())
+	assert.Empty(t, other.Events())
+}
+
+func TestFilterKeepsSequenceDense(t *testing.T) {
+	broker := newBroker(pubsub.DropOldest)
+	sub := broker.Subscribe(1, func(event model.Event) bool {
+		_, ok := event.(*model.CommentCreated)
+		return ok
+	})
+
+	broker.Publish(context.Background(), 1, &model.CommentsToggled{PostID: 1})
+	broker.Publish(context.Background(), 1, &model.PostUpdated{Post: &model.Post{ID: 1}})
+	broker.Publish(context.Background(), 1, created(1))
+
+	event := <-sub.Events()
+	assert.Equal(t, 1, event.GetSequence())
+}
+
+func TestDropOldest(t *testing.T) {
+	broker := newBroker(pubsub.DropOldest)
+	sub := broker.Subscribe(1, nil)
+
+	for id := 1; id <= 3; id++ {
+		broker.Publish(context.Background(), 1, created(id))
+	}
+
+	assert.Equal(t, 2, (<-sub.Events()).GetSequence())
+	assert.Equal(t, 3, (<-sub.Events()).GetSequence())
+	assert.Equal(t, 1, sub.Dropped())
+	assert.Equal(t, int64(1), broker.Stats().Dropped)
+}
+
+func TestDisconnectSlowConsumer(t *testing.T) {
+	broker := newBroker(pubsub.Disconnect)
+	sub := broker.Subscribe(1, nil)
+
+	for id := 1; id <= 3; id++ {
+		broker.Publish(context.Background(), 1, created(id))
+	}
+
+	var received int
+	for range sub.Events() {
+		received++
+	}
+	assert.Equal(t, 2, received)
+	assert.ErrorIs(t, sub.Err(), pubsub.ErrSlowConsumer)
+	assert.Equal(t, int64(1), broker.Stats().Disconnected)
+
+	broker.Unsubscribe(sub)
+	assert.Equal(t, 0, broker.Stats().Subscribers)
+}
+
+func TestBlockWaitsForSubscriber(t *testing.T) {
+	broker := newBroker(pubsub.Block)
+	sub := broker.Subscribe(1, nil)
+
+	start := time.Now()
+	for i := 1; i <= 4; i++ {
+		broker.Publish(context.Background(), 1, created(i))
+	}
+	assert.Less(t, time.Since(start), 50*time.Millisecond)
+
+	for i := 1; i <= 4; i++ {
+		assert.Equal(t, i, (<-sub.Events()).GetSequence())
+	}
+	assert.Equal(t, 0, sub.Dropped())
+}
+
+func TestBlockDropsAfterTimeout(t *testing.T) {
+	broker := newBroker(pubsub.Block)
+	sub := broker.Subscribe(1, nil)
+
+	for i := 1; i <= 5; i++ {
+		broker.Publish(context.Background(), 1, created(i))
+	}
+	assert.Equal(t, 1, sub.Dropped())
+
+	time.Sleep(250 * time.Millisecond)
+	assert.Equal(t, 3, sub.Dropped())
+	assert.Equal(t, 1, (<-sub.Events()).GetSequence())
+	assert.Equal(t, 2, (<-sub.Events()).GetSequence())
+}
+
+func TestUnsubscribeWhileBlocked(t *testing.T) {
+	broker := newBroker(pubsub.Block)
+	sub := broker.Subscribe(1, nil)
+
+	for i := 1; i <= 3; i++ {
+		broker.Publish(context.Background(), 1, created(i))
+	}
+	broker.Unsubscribe(sub)
+
+	var received []int
+	for event := range sub.Events() {
+		received = append(received, event.GetSequence())
+	}
+	assert.Equal(t, []int{1, 2}, received)
+}
+
+func TestUnsubscribeClosesChannel(t *testing.T) {
+	broker := newBroker(pubsub.DropOldest)
+	sub := broker.Subscribe(1, nil)
+
+	broker.Unsubscribe(sub)
+	broker.Publish(context.Background(), 1, &model.CommentsToggled{PostID: 1})
+
+	_, ok := <-sub.Events()
+	assert.False(t, ok)
+	assert.NoError(t, sub.Err())
+}
+
+func TestUnknownPolicy(t *testing.T) {
+	assert.ErrorIs(t, pubsub.Options{Policy: "retry"}.Validate(), pubsub.ErrUnknownPolicy)
+}
+
+func TestValidateOptions(t *testing.T) {
+	assert.NoError(t, pubsub.Options{Buffer: 1, Policy: pubsub.DropOldest}.Validate())
+	assert.NoError(t, pubsub.Options{Buffer: 1, Policy: pubsub.Block, BlockTimeout: time.Millisecond}.Validate())
+	assert.ErrorIs(t, pubsub.Options{Buffer: 0, Policy: pubsub.Disconnect}.Validate(), pubsub.ErrInvalidBuffer)
+	assert.ErrorIs(t, pubsub.Options{Buffer: -1, Policy: pubsub.DropOldest}.Validate(), pubsub.ErrInvalidBuffer)
+	assert.ErrorIs(t, pubsub.Options{Buffer: 1, Policy: pubsub.Block}.Validate(), pubsub.ErrInvalidWait)
+}
@@ -0,0 +1,49 @@
+package pubsub
+
+import (
+	"Commentary/internal/graph/model"
+	"Commentary/internal/pubsub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestEventRoundTrip(t *testing.T) {
+	parentID := 1
+	created := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	comment := &model.Comment{ID: 2, PostID: 3, AuthorID: 4, Content: "<b>hi</b>", Created: created,
+		ParentID: &parentID, Hidden: true}
+
+	post := &model.Post{ID: 3, AuthorID: 4, Title: "title", Content: "content", Created: created, EditedAt: &created}
+
+	events := []model.Event{
+		&model.CommentCreated{Comment: comment},
+		&model.CommentUpdated{Comment: comment},
+		&model.CommentUpdated{Comment: comment, HiddenThread: true},
+		&model.CommentDeleted{CommentID: 2, ParentID: &parentID},
+		&model.CommentDeleted{CommentID: 2, ParentID: &parentID, HiddenThread: true},
+		&model.CommentDeleted{CommentID: 2, ParentID: &parentID, Tombstone: comment},
+		&model.CommentsToggled{PostID: 3, Commentable: true},
+		&model.PostCreated{Post: post},
+		&model.PostUpdated{Post: post},
+		&model.PostDeleted{PostID: 3},
+		&model.NotificationAdded{Notification: &model.Notification{ID: 5, UserID: 4, Kind: model.NotificationKindMention,
+			CommentID: 2, ActorID: 1, Created: created}},
+		&model.PresenceShared{Replica: "replica", Viewers: 2, Typing: []int{4}},
+	}
+	for _, event := range events {
+		payload, err := pubsub.EncodeEvent(3, event)
+		require.NoError(t, err)
+
+		postID, decoded, err := pubsub.DecodeEvent(payload)
+		require.NoError(t, err)
+		assert.Equal(t, 3, postID)
+		assert.Equal(t, event, decoded)
+	}
+}
+
+func TestDecodeUnknownEvent(t *testing.T) {
+	_, _, err := pubsub.DecodeEvent([]byte(`{"postID":1,"type":"renamed"}`))
+	assert.ErrorIs(t, err, pubsub.ErrUnknownEvent)
+}
@@ -0,0 +1,36 @@
+package pubsub
+
+import (
+	"Commentary/internal/graph"
+	"Commentary/internal/pubsub"
+	"context"
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestExtensionPresentsClosedSubscription(t *testing.T) {
+	broker := newBroker(pubsub.Disconnect)
+	ext := pubsub.Extension{}
+
+	var ctx context.Context
+	ext.InterceptOperation(context.Background(), func(opCtx context.Context) graphql.ResponseHandler {
+		ctx = opCtx
+		return nil
+	})
+	sub := broker.Subscribe(1, nil)
+	pubsub.Track(ctx, sub)
+	for id := 1; id <= 3; id++ {
+		broker.Publish(context.Background(), 1, created(id))
+	}
+	for range sub.Events() {
+	}
+
+	ctx = graphql.WithResponseContext(ctx, graph.ErrorPresenter, nil)
+	response := ext.InterceptResponse(ctx, func(context.Context) *graphql.Response { return nil })
+	require.NotNil(t, response)
+	require.Len(t, response.Errors, 1)
+	assert.Equal(t, pubsub.ErrSlowConsumer.Error(), response.Errors[0].Message)
+	assert.Equal(t, "SLOW_CONSUMER", response.Errors[0].Extensions["code"])
+}
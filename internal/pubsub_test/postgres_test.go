@@ -0,0 +1,61 @@
+package pubsub
+
+import (
+	"Commentary/internal/graph/model"
+	"Commentary/internal/pubsub"
+	"context"
+	"database/sql"
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestPostgresBrokerBetweenReplicas needs a running PostgreSQL with the migrations applied, for example
+// PUBSUB_TEST_DSN="host=localhost user=postgres password=admin dbname=commentary sslmode=disable".
+func TestPostgresBrokerBetweenReplicas(t *testing.T) {
+	dsn := os.Getenv("PUBSUB_TEST_DSN")
+	if dsn == "" {
+		t.Skip("PUBSUB_TEST_DSN is not set")
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	require.NoError(t, err)
+	defer db.Close()
+
+	options := pubsub.Options{Buffer: 10, Policy: pubsub.DropOldest}
+	replicaA, err := pubsub.NewPostgresBroker(db, dsn, options)
+	require.NoError(t, err)
+	replicaB, err := pubsub.NewPostgresBroker(db, dsn, options)
+	require.NoError(t, err)
+
+	sub := replicaB.Subscribe(1, nil)
+	defer replicaB.Unsubscribe(sub)
+
+	replicaA.Publish(context.Background(), 2, &model.CommentsToggled{PostID: 2})
+	replicaA.Publish(context.Background(), 1, &model.CommentCreated{Comment: &model.Comment{ID: 5, PostID: 1, Content: "hi"}})
+
+	select {
+	case event := <-sub.Events():
+		require.IsType(t, &model.CommentCreated{}, event)
+		assert.Equal(t, 1, event.GetSequence())
+		assert.Equal(t, 5, event.(*model.CommentCreated).Comment.ID)
+	case <-time.After(5 * time.Second):
+		t.Fatal("event was not delivered")
+	}
+
+	// the payload of this event does not fit into NOTIFY
+	content := strings.Repeat("я", 5000)
+	replicaA.Publish(context.Background(), 1, &model.CommentCreated{Comment: &model.Comment{ID: 6, PostID: 1, Content: content}})
+
+	select {
+	case event := <-sub.Events():
+		require.IsType(t, &model.CommentCreated{}, event)
+		assert.Equal(t, content, event.(*model.CommentCreated).Comment.Content)
+	case <-time.After(5 * time.Second):
+		t.Fatal("large event was not delivered")
+	}
+}
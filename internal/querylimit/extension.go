@@ -0,0 +1,109 @@
+package querylimit
+
+import (
+	"context"
+	"errors"
+	"github.com/99designs/gqlgen/complexity"
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/errcode"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+	"strings"
+)
+
+const statsKey = "QueryLimit"
+
+// Cost of an operation, reported in the "cost" response extension.
+type Cost struct {
+	Complexity    int `json:"complexity"`
+	Depth         int `json:"depth"`
+	MaxComplexity int `json:"maxComplexity"`
+	MaxDepth      int `json:"maxDepth"`
+}
+
+// Extension rejects operations whose complexity or depth exceeds the limits, zero disables
+// a limit. Introspection fields are not counted in the depth, so that tools can load the schema.
+type Extension struct {
+	MaxComplexity int
+	MaxDepth      int
+
+	es graphql.ExecutableSchema
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.OperationContextMutator
+	graphql.ResponseInterceptor
+} = &Extension{}
+
+func (e *Extension) ExtensionName() string {
+	return statsKey
+}
+
+func (e *Extension) Validate(schema graphql.ExecutableSchema) error {
+	if e.MaxComplexity < 0 || e.MaxDepth < 0 {
+		return errors.New("query limits can not be negative")
+	}
+	e.es = schema
+	return nil
+}
+
+func (e *Extension) MutateOperationContext(ctx context.Context, opCtx *graphql.OperationContext) *gqlerror.Error {
+	op := opCtx.Doc.Operations.ForName(opCtx.OperationName)
+	cost := &Cost{
+		Complexity:    complexity.Calculate(e.es, op, opCtx.Variables),
+		Depth:         Depth(op.SelectionSet),
+		MaxComplexity: e.MaxComplexity,
+		MaxDepth:      e.MaxDepth,
+	}
+	opCtx.Stats.SetExtension(statsKey, cost)
+
+	if e.MaxDepth > 0 && cost.Depth > e.MaxDepth {
+		err := gqlerror.Errorf("operation has depth %d, which exceeds the limit of %d", cost.Depth, e.MaxDepth)
+		errcode.Set(err, "DEPTH_LIMIT_EXCEEDED")
+		return err
+	}
+	if e.MaxComplexity > 0 && cost.Complexity > e.MaxComplexity {
+		err := gqlerror.Errorf("operation has complexity %d, which exceeds the limit of %d",
+			cost.Complexity, e.MaxComplexity)
+		errcode.Set(err, "COMPLEXITY_LIMIT_EXCEEDED")
+		return err
+	}
+	return nil
+}
+
+func (e *Extension) InterceptResponse(ctx context.Context, next graphql.ResponseHandler) *graphql.Response {
+	response := next(ctx)
+	if response == nil {
+		return nil
+	}
+
+	if cost, ok := graphql.GetOperationContext(ctx).Stats.GetExtension(statsKey).(*Cost); ok {
+		if response.Extensions == nil {
+			response.Extensions = make(map[string]any)
+		}
+		response.Extensions["cost"] = cost
+	}
+	return response
+}
+
+// Depth returns how deeply the fields of the selection set are nested, with fragments expanded.
+func Depth(selections ast.SelectionSet) int {
+	depth := 0
+	for _, selection := range selections {
+		switch s := selection.(type) {
+		case *ast.Field:
+			if strings.HasPrefix(s.Name, "__") {
+				continue
+			}
+			depth = max(depth, 1+Depth(s.SelectionSet))
+		case *ast.InlineFragment:
+			depth = max(depth, Depth(s.SelectionSet))
+		case *ast.FragmentSpread:
+			if s.Definition != nil {
+				depth = max(depth, Depth(s.Definition.SelectionSet))
+			}
+		}
+	}
+	return depth
+}
@@ -0,0 +1,77 @@
+package querylimit
+
+import (
+	"Commentary/internal/graph"
+	"Commentary/internal/pagination"
+	"Commentary/internal/querylimit"
+	"context"
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vektah/gqlparser/v2"
+	"testing"
+)
+
+const nestedQuery = `query {
+	posts(first: 10) {
+		edges { node { comments(first: 5) { edges { node { id post { id } } } } } }
+	}
+	__schema { types { fields { type { name } } } }
+}`
+
+func operationContext(t *testing.T, es graphql.ExecutableSchema, query string) *graphql.OperationContext {
+	doc, err := gqlparser.LoadQuery(es.Schema(), query)
+	require.Nil(t, err)
+	return &graphql.OperationContext{Doc: doc, Variables: map[string]any{}}
+}
+
+func TestDepthSkipsIntrospection(t *testing.T) {
+	es := graph.NewExecutableSchema(graph.Config{Complexity: graph.NewComplexity()})
+	opCtx := operationContext(t, es, nestedQuery)
+
+	assert.Equal(t, 8, querylimit.Depth(opCtx.Doc.Operations[0].SelectionSet))
+}
+
+func TestExtensionReportsCost(t *testing.T) {
+	es := graph.NewExecutableSchema(graph.Config{Complexity: graph.NewComplexity()})
+	ext := &querylimit.Extension{MaxComplexity: 1000, MaxDepth: 10}
+	require.NoError(t, ext.Validate(es))
+	opCtx := operationContext(t, es, `{ posts(first: 10) { edges { node { id } } } }`)
+
+	require.Nil(t, ext.MutateOperationContext(context.Background(), opCtx))
+	cost, ok := opCtx.Stats.GetExtension("QueryLimit").(*querylimit.Cost)
+	require.True(t, ok)
+	assert.Equal(t, 4, cost.Depth)
+	assert.Equal(t, 1+10*3, cost.Complexity)
+
+	opCtx = operationContext(t, es, `{ posts { edges { node { id } } } }`)
+	require.Nil(t, ext.MutateOperationContext(context.Background(), opCtx))
+	cost = opCtx.Stats.GetExtension("QueryLimit").(*querylimit.Cost)
+	assert.Equal(t, 1+pagination.DefaultFirst*3, cost.Complexity)
+}
+
+func TestExtensionRejectsComplexQuery(t *testing.T) {
+	es := graph.NewExecutableSchema(graph.Config{Complexity: graph.NewComplexity()})
+	ext := &querylimit.Extension{MaxComplexity: 100}
+	require.NoError(t, ext.Validate(es))
+
+	err := ext.MutateOperationContext(context.Background(), operationContext(t, es, nestedQuery))
+	require.NotNil(t, err)
+	assert.Equal(t, "COMPLEXITY_LIMIT_EXCEEDED", err.Extensions["code"])
+}
+
+func TestExtensionRejectsDeepQuery(t *testing.T) {
+	es := graph.NewExecutableSchema(graph.Config{Complexity: graph.NewComplexity()})
+	ext := &querylimit.Extension{MaxDepth: 5}
+	require.NoError(t, ext.Validate(es))
+
+	err := ext.MutateOperationContext(context.Background(), operationContext(t, es, nestedQuery))
+	require.NotNil(t, err)
+	assert.Equal(t, "DEPTH_LIMIT_EXCEEDED", err.Extensions["code"])
+}
+
+func TestValidateRejectsNegativeLimits(t *testing.T) {
+	es := graph.NewExecutableSchema(graph.Config{})
+
+	assert.Error(t, (&querylimit.Extension{MaxDepth: -1}).Validate(es))
+}
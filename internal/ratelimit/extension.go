@@ -0,0 +1,70 @@
+package ratelimit
+
+import (
+	"Commentary/internal/auth"
+	"context"
+	"fmt"
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+	"math"
+	"strconv"
+	"time"
+)
+
+// Extension limits the mutations and subscriptions that have a rule, by the field name. Clients
+// are told apart by IP and authenticated ones by user as well, they must be within both limits,
+// so that logging in does not lift the limit of the IP. Rejected fields fail with an error whose
+// extensions carry the RATE_LIMITED code and retryAfter, the seconds to wait before trying again.
+type Extension struct {
+	Limiter *Limiter
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.FieldInterceptor
+} = Extension{}
+
+func (e Extension) ExtensionName() string {
+	return "RateLimit"
+}
+
+func (e Extension) Validate(graphql.ExecutableSchema) error {
+	return nil
+}
+
+func (e Extension) InterceptField(ctx context.Context, next graphql.Resolver) (any, error) {
+	fc := graphql.GetFieldContext(ctx)
+	if fc == nil || (fc.Object != "Mutation" && fc.Object != "Subscription") {
+		return next(ctx)
+	}
+
+	rule := fc.Field.Name
+	if fc.Object == "Subscription" && !e.Limiter.Limited(rule) {
+		rule = SubscriptionsRule
+	}
+	if ok, retryAfter := e.Limiter.AllowAll(rule, clients(ctx), time.Now()); !ok {
+		return nil, limitedError(ctx, retryAfter)
+	}
+	return next(ctx)
+}
+
+// clients identify the IP the operation comes from and, for authenticated requests, the user.
+func clients(ctx context.Context) []string {
+	ip := "ip:" + ClientIP(ctx)
+	if userID, ok := auth.UserIDFromContext(ctx); ok {
+		return []string{ip, "user:" + strconv.Itoa(userID)}
+	}
+	return []string{ip}
+}
+
+func limitedError(ctx context.Context, retryAfter time.Duration) *gqlerror.Error {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	return &gqlerror.Error{
+		Message: fmt.Sprintf("rate limit exceeded, retry in %d seconds", seconds),
+		Path:    graphql.GetPath(ctx),
+		Extensions: map[string]any{
+			"code":       "RATE_LIMITED",
+			"retryAfter": seconds,
+		},
+	}
+}
@@ -0,0 +1,113 @@
+package ratelimit
+
+import (
+	"Commentary/internal/config"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// SubscriptionsRule limits opening subscriptions of any kind, unless the subscription field
+// has a rule of its own.
+const SubscriptionsRule = "subscriptions"
+
+// sweepInterval is how often buckets that have refilled are forgotten.
+const sweepInterval = time.Minute
+
+// bucket holds the tokens left at the time it was last used.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+type bucketKey struct {
+	rule   string
+	client string
+}
+
+// Limiter keeps a token bucket per rule and client. A bucket starts full with Burst tokens,
+// every operation takes one and one more token is added each Every.
+type Limiter struct {
+	mu        sync.Mutex
+	limits    map[string]config.Limit
+	buckets   map[bucketKey]*bucket
+	lastSweep time.Time
+}
+
+func NewLimiter(limits map[string]config.Limit) (*Limiter, error) {
+	for rule, limit := range limits {
+		if limit.Burst < 1 || limit.Every <= 0 {
+			return nil, fmt.Errorf("limit %q needs a positive burst and every", rule)
+		}
+	}
+	return &Limiter{limits: limits, buckets: make(map[bucketKey]*bucket)}, nil
+}
+
+// Limited tells whether the rule is configured.
+func (l *Limiter) Limited(rule string) bool {
+	_, ok := l.limits[rule]
+	return ok
+}
+
+// Allow takes a token of the rule for the client. When there are none left it returns false
+// and how long to wait for the next one. Rules that are not configured allow everything.
+func (l *Limiter) Allow(rule, client string, now time.Time) (bool, time.Duration) {
+	return l.AllowAll(rule, []string{client}, now)
+}
+
+// AllowAll takes a token of the rule for each of the clients, or none at all if any of them has
+// none left. Then it returns false and how long to wait until every client has a token.
+func (l *Limiter) AllowAll(rule string, clients []string, now time.Time) (bool, time.Duration) {
+	limit, ok := l.limits[rule]
+	if !ok {
+		return true, 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+	buckets := make([]*bucket, 0, len(clients))
+	var retryAfter time.Duration
+	for _, client := range clients {
+		key := bucketKey{rule: rule, client: client}
+		b, ok := l.buckets[key]
+		if !ok {
+			b = &bucket{tokens: float64(limit.Burst), last: now}
+			l.buckets[key] = b
+		}
+
+		if now.After(b.last) {
+			b.tokens = min(float64(limit.Burst), b.tokens+float64(now.Sub(b.last))/float64(limit.Every))
+			b.last = now
+		}
+		if b.tokens < 1 {
+			retryAfter = max(retryAfter, time.Duration((1-b.tokens)*float64(limit.Every)))
+		}
+		buckets = append(buckets, b)
+	}
+	if retryAfter > 0 {
+		return false, retryAfter
+	}
+
+	for _, b := range buckets {
+		b.tokens--
+	}
+	return true, 0
+}
+
+// sweep forgets the buckets that would be full by now, they start full anyway.
+// Must be called with the lock held.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+
+	for key, b := range l.buckets {
+		limit := l.limits[key.rule]
+		if b.tokens+float64(now.Sub(b.last))/float64(limit.Every) >= float64(limit.Burst) {
+			delete(l.buckets, key)
+		}
+	}
+}
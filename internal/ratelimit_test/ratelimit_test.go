@@ -0,0 +1,100 @@
+package ratelimit
+
+import (
+	"Commentary/internal/config"
+	"Commentary/internal/ratelimit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAllowBurstThenRefill(t *testing.T) {
+	limiter, err := ratelimit.NewLimiter(map[string]config.Limit{"createComment": {Burst: 2, Every: time.Second}})
+	require.NoError(t, err)
+	now := time.Now()
+
+	for i := 0; i < 2; i++ {
+		ok, _ := limiter.Allow("createComment", "user:1", now)
+		assert.True(t, ok)
+	}
+	ok, retryAfter := limiter.Allow("createComment", "user:1", now)
+	assert.False(t, ok)
+	assert.Equal(t, time.Second, retryAfter)
+
+	ok, retryAfter = limiter.Allow("createComment", "user:1", now.Add(400*time.Millisecond))
+	assert.False(t, ok)
+	assert.Equal(t, 600*time.Millisecond, retryAfter)
+
+	ok, _ = limiter.Allow("createComment", "user:1", now.Add(time.Second))
+	assert.True(t, ok)
+}
+
+func TestAllowKeepsClientsAndRulesApart(t *testing.T) {
+	limiter, err := ratelimit.NewLimiter(map[string]config.Limit{
+		"createComment": {Burst: 1, Every: time.Minute},
+		"createPost":    {Burst: 1, Every: time.Minute},
+	})
+	require.NoError(t, err)
+	now := time.Now()
+
+	ok, _ := limiter.Allow("createComment", "user:1", now)
+	assert.True(t, ok)
+	ok, _ = limiter.Allow("createComment", "ip:10.0.0.1", now)
+	assert.True(t, ok)
+	ok, _ = limiter.Allow("createPost", "user:1", now)
+	assert.True(t, ok)
+	ok, _ = limiter.Allow("createComment", "user:1", now)
+	assert.False(t, ok)
+
+	ok, _ = limiter.Allow("login", "user:1", now)
+	assert.True(t, ok)
+	assert.False(t, limiter.Limited("login"))
+}
+
+func TestAllowAllNeedsEveryClient(t *testing.T) {
+	limiter, err := ratelimit.NewLimiter(map[string]config.Limit{"createComment": {Burst: 1, Every: time.Minute}})
+	require.NoError(t, err)
+	now := time.Now()
+
+	ok, _ := limiter.Allow("createComment", "ip:10.0.0.1", now)
+	assert.True(t, ok)
+	ok, retryAfter := limiter.AllowAll("createComment", []string{"ip:10.0.0.1", "user:1"}, now)
+	assert.False(t, ok)
+	assert.Equal(t, time.Minute, retryAfter)
+
+	// the user bucket was left untouched by the rejected operation
+	ok, _ = limiter.AllowAll("createComment", []string{"ip:10.0.0.2", "user:1"}, now)
+	assert.True(t, ok)
+	ok, _ = limiter.Allow("createComment", "ip:10.0.0.2", now)
+	assert.False(t, ok)
+}
+
+func TestNewLimiterRejectsEmptyLimits(t *testing.T) {
+	_, err := ratelimit.NewLimiter(map[string]config.Limit{"createPost": {Burst: 1}})
+	assert.Error(t, err)
+
+	_, err = ratelimit.NewLimiter(map[string]config.Limit{"createPost": {Every: time.Second}})
+	assert.Error(t, err)
+}
+
+func TestMiddlewareClientIP(t *testing.T) {
+	var ip string
+	handler := func(trustProxy bool) http.Handler {
+		return ratelimit.Middleware(trustProxy, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip = ratelimit.ClientIP(r.Context())
+		}))
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/query", nil)
+	r.RemoteAddr = "10.0.0.2:5000"
+	r.Header.Set("X-Forwarded-For", "1.1.1.1, 203.0.113.7")
+
+	handler(false).ServeHTTP(httptest.NewRecorder(), r)
+	assert.Equal(t, "10.0.0.2", ip)
+
+	handler(true).ServeHTTP(httptest.NewRecorder(), r)
+	assert.Equal(t, "203.0.113.7", ip)
+}
@@ -0,0 +1,14 @@
+package repo
+
+import "errors"
+
+// Errors every storage reports the same way, so that services do not depend on the storage in use.
+var (
+	ErrUserNotFound           = errors.New("user not found")
+	ErrUserAlreadyExists      = errors.New("user with this nickname already exists")
+	ErrPostNotFound           = errors.New("post not found")
+	ErrCommentNotFound        = errors.New("comment not found")
+	ErrCommentDeleted         = errors.New("comment is already deleted")
+	ErrParentNotFound         = errors.New("parent comment not found in this post")
+	ErrReactionTargetNotFound = errors.New("reaction target not found")
+)
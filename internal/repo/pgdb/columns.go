@@ -0,0 +1,118 @@
+package pgdb
+
+import (
+	"Commentary/internal/entity"
+	"Commentary/internal/pagination"
+	"database/sql"
+	"fmt"
+	"github.com/Masterminds/squirrel"
+	"strings"
+)
+
+var (
+	postColumns         = []string{"id", "author_id", "title", "content", "created", "commentable", "edited", "hidden"}
+	commentColumns      = []string{"id", "post_id", "author_id", "content", "created", "parent_id", "edited", "deleted", "hidden"}
+	notificationColumns = []string{"id", "user_id", "kind", "comment_id", "actor_id", "created", "read"}
+	reportColumns       = []string{"id", "post_id", "comment_id", "reporter_id", "reason", "created", "resolved"}
+)
+
+// rowScanner is implemented by both *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanPost(row rowScanner) (*entity.Post, error) {
+	return scanPostScored(row, false)
+}
+
+// scanPostScored scans a post followed by its ranking score, if scored is set.
+func scanPostScored(row rowScanner, scored bool) (*entity.Post, error) {
+	var post entity.Post
+	dest := []any{&post.ID, &post.AuthorID, &post.Title,
+		&post.Content, &post.Created, &post.Commentable, &post.Edited, &post.Hidden}
+	if scored {
+		dest = append(dest, &post.Score)
+	}
+	err := row.Scan(dest...)
+	return &post, err
+}
+
+func scanComment(row rowScanner) (*entity.Comment, error) {
+	return scanCommentScored(row, false)
+}
+
+// scanCommentScored scans a comment followed by its ranking score, if scored is set, and then by extra columns.
+func scanCommentScored(row rowScanner, scored bool, extra ...any) (*entity.Comment, error) {
+	var comment entity.Comment
+	dest := []any{&comment.ID, &comment.PostID, &comment.AuthorID,
+		&comment.Content, &comment.Created, &comment.ParentID, &comment.Edited, &comment.Deleted,
+		&comment.Hidden}
+	if scored {
+		dest = append(dest, &comment.Score)
+	}
+	err := row.Scan(append(dest, extra...)...)
+	return &comment, err
+}
+
+func scanNotification(row rowScanner) (*entity.Notification, error) {
+	var notification entity.Notification
+	err := row.Scan(&notification.ID, &notification.UserID, &notification.Kind, &notification.CommentID,
+		&notification.ActorID, &notification.Created, &notification.Read)
+	return &notification, err
+}
+
+func scanReport(row rowScanner) (*entity.Report, error) {
+	var report entity.Report
+	var postID, commentID sql.NullInt64
+	err := row.Scan(&report.ID, &postID, &commentID, &report.ReporterID, &report.Reason,
+		&report.Created, &report.Resolved)
+	if commentID.Valid {
+		report.Target = entity.ReactionTarget{Kind: entity.TargetComment, ID: int(commentID.Int64)}
+	} else {
+		report.Target = entity.ReactionTarget{Kind: entity.TargetPost, ID: int(postID.Int64)}
+	}
+	return &report, err
+}
+
+// visible restricts the filter to rows that are not hidden by moderators, unless withHidden is set.
+func visible(filter squirrel.Sqlizer, withHidden bool) squirrel.Sqlizer {
+	if withHidden {
+		return filter
+	}
+	return squirrel.And{filter, squirrel.Eq{"hidden": false}}
+}
+
+// hiddenThread holds when the post of the comment named %[1]s or a comment above it is hidden.
+const hiddenThread = `EXISTS (SELECT 1 FROM posts p WHERE p.id = %[1]s.post_id AND p.hidden)
+	OR EXISTS (WITH RECURSIVE ancestors AS (
+		SELECT a.parent_id, a.hidden FROM comments a WHERE a.id = %[1]s.parent_id
+		UNION ALL
+		SELECT a.parent_id, a.hidden FROM comments a JOIN ancestors ON a.id = ancestors.parent_id
+		WHERE NOT ancestors.hidden)
+	SELECT 1 FROM ancestors WHERE hidden)`
+
+// visibleThread restricts the filter to comments of table that are not hidden by moderators, with
+// their post and every comment above them, unless withHidden is set.
+func visibleThread(table string, filter squirrel.Sqlizer, withHidden bool) squirrel.Sqlizer {
+	if withHidden {
+		return filter
+	}
+	return squirrel.And{filter, squirrel.Eq{table + ".hidden": false},
+		squirrel.Expr("NOT (" + fmt.Sprintf(hiddenThread, table) + ")")}
+}
+
+// withScore appends the score column to columns if the sort is ranked.
+func withScore(columns []string, sort pagination.Sort) []string {
+	if !sort.Ranked() {
+		return columns
+	}
+	return append(columns[:len(columns):len(columns)], "score")
+}
+
+// columnList joins columns for raw SQL fragments, qualifying them with prefix if it is not empty.
+func columnList(prefix string, columns []string) string {
+	if prefix == "" {
+		return strings.Join(columns, ", ")
+	}
+	return prefix + "." + strings.Join(columns, ", "+prefix+".")
+}
@@ -2,30 +2,48 @@ package pgdb
 
 import (
 	"Commentary/internal/entity"
+	"Commentary/internal/pagination"
+	"Commentary/internal/repo"
 	"context"
 	"database/sql"
 	"errors"
 	"github.com/Masterminds/squirrel"
+	"github.com/lib/pq"
 	"github.com/sirupsen/logrus"
+	"strings"
 )
 
-type CommentRepo interface {
-	GetComments(ctx context.Context, postID int) ([]*entity.Comment, error)
-	GetRootCommentsPag(ctx context.Context, postID int, limit *int, offset *int) ([]*entity.Comment, error)
-	AddComment(ctx context.Context, comment *entity.Comment) (int, error)
-	GetCommentByID(ctx context.Context, id int) (*entity.Comment, error)
-}
-
 type commentRepo struct {
-	DB  *sql.DB
-	SQL squirrel.StatementBuilderType
+	DB      *sql.DB
+	SQL     squirrel.StatementBuilderType
+	ranking pagination.Ranking
 }
 
-func NewCommentRepo(DB *sql.DB) CommentRepo {
+func NewCommentRepo(DB *sql.DB, ranking pagination.Ranking) repo.CommentRepo {
 	return &commentRepo{
-		DB:  DB,
-		SQL: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+		DB:      DB,
+		SQL:     squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+		ranking: ranking,
+	}
+}
+
+// rootComments selects root comments matching the filter, scored if the page sort is ranked.
+// The filter must not refer to parent_id, which is ambiguous in the scored query.
+func (cr *commentRepo) rootComments(filter squirrel.Sqlizer, page pagination.Page,
+	extra ...string) squirrel.SelectBuilder {
+	if !page.Sort.Ranked() {
+		return cr.SQL.Select(append(commentColumns, extra...)...).
+			From("comments").
+			Where(filter).
+			Where(squirrel.Expr("parent_id IS NULL"))
 	}
+
+	scored := ranked(cr.SQL.Select(columnList("cm", commentColumns)).From("comments cm"),
+		cr.ranking, page, "cm", "comment_id", "parent_id").
+		Where(filter).
+		Where(squirrel.Expr("cm.parent_id IS NULL"))
+	return cr.SQL.Select(append(withScore(commentColumns, page.Sort), extra...)...).
+		FromSelect(scored, "scored")
 }
 
 func (cr *commentRepo) AddComment(ctx context.Context, comment *entity.Comment) (int, error) {
@@ -33,8 +51,21 @@ func (cr *commentRepo) AddComment(ctx context.Context, comment *entity.Comment)
 
 	statement := cr.SQL.Insert("comments").
 		Columns("post_id", "author_id", "content", "created", "parent_id").
-		Values(comment.PostID, comment.AuthorID, comment.Content, comment.Created, comment.ParentID).
 		Suffix("RETURNING id")
+	if comment.ParentID == nil {
+		statement = statement.Values(comment.PostID, comment.AuthorID, comment.Content, comment.Created, nil)
+	} else {
+		// a reply is only inserted if its parent exists in the same post; the key share lock
+		// holds a concurrent DeleteComment of the parent back until the reply is committed
+		statement = statement.Select(cr.SQL.Select("post_id").
+			Column("?::INTEGER", comment.AuthorID).
+			Column("?::TEXT", comment.Content).
+			Column("?::TIMESTAMP", comment.Created).
+			Column("id").
+			From("comments").
+			Where(squirrel.Eq{"id": *comment.ParentID, "post_id": comment.PostID}).
+			Suffix("FOR KEY SHARE"))
+	}
 
 	query, args, err := statement.ToSql()
 	if err != nil {
@@ -48,6 +79,13 @@ func (cr *commentRepo) AddComment(ctx context.Context, comment *entity.Comment)
 
 	var id int
 	err = cr.DB.QueryRowContext(ctx, query, args...).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, &RepositoryError{
+			Operation: "adding comment",
+			Content:   "parent comment not found",
+			Err:       ErrParentNotFound,
+		}
+	}
 	if err != nil {
 		logrus.WithError(err).Error(ErrAddingComment)
 		return 0, &RepositoryError{
@@ -60,13 +98,14 @@ func (cr *commentRepo) AddComment(ctx context.Context, comment *entity.Comment)
 	return id, nil
 }
 
-func (cr *commentRepo) GetComments(ctx context.Context, postID int) ([]*entity.Comment, error) {
+// GetComments returns all comments of the post, unless withHidden is set comments hidden together
+// with their post or a comment above them are left out.
+func (cr *commentRepo) GetComments(ctx context.Context, postID int, withHidden bool) ([]*entity.Comment, error) {
 	logrus.WithField("postID", postID).Debug("getting comments for post")
 
-	statement := cr.SQL.Select("id", "post_id", "author_id",
-		"content", "created", "parent_id").
+	statement := cr.SQL.Select(commentColumns...).
 		From("comments").
-		Where(squirrel.Eq{"post_id": postID})
+		Where(visibleThread("comments", squirrel.Eq{"post_id": postID}, withHidden))
 
 	query, args, err := statement.ToSql()
 	if err != nil {
@@ -91,9 +130,7 @@ func (cr *commentRepo) GetComments(ctx context.Context, postID int) ([]*entity.C
 
 	var comments []*entity.Comment
 	for rows.Next() {
-		var comment entity.Comment
-		err = rows.Scan(&comment.ID, &comment.PostID, &comment.AuthorID,
-			&comment.Content, &comment.Created, &comment.ParentID)
+		comment, err := scanComment(rows)
 		if err != nil {
 			logrus.WithError(err).Error("failed to scan row")
 			return nil, &RepositoryError{
@@ -102,7 +139,7 @@ func (cr *commentRepo) GetComments(ctx context.Context, postID int) ([]*entity.C
 				Err:       ErrGettingComments,
 			}
 		}
-		comments = append(comments, &comment)
+		comments = append(comments, comment)
 	}
 
 	if err = rows.Err(); err != nil {
@@ -118,27 +155,10 @@ func (cr *commentRepo) GetComments(ctx context.Context, postID int) ([]*entity.C
 	return comments, nil
 }
 
-func (cr *commentRepo) GetRootCommentsPag(ctx context.Context, postID int, limit *int, offset *int) ([]*entity.Comment, error) {
+func (cr *commentRepo) GetRootCommentsPag(ctx context.Context, postID int, page pagination.Page,
+	withHidden bool) ([]*entity.Comment, error) {
 	logrus.Debugf("getting root comments paginated for post id=%d", postID)
-	statement := cr.SQL.Select("id", "post_id", "author_id",
-		"content", "created", "parent_id").
-		From("comments").
-		Where(squirrel.Eq{"post_id": postID}).
-		Where(squirrel.Expr("parent_id IS NULL"))
-
-	if limit != nil && *limit <= 0 {
-		return nil, errors.New("wrong limit parameter value")
-	}
-	if offset != nil && *offset < 0 {
-		return nil, errors.New("wrong offset parameter value")
-	}
-
-	if limit != nil {
-		statement = statement.Limit(uint64(*limit))
-	}
-	if offset != nil {
-		statement = statement.Offset(uint64(*offset))
-	}
+	statement := paginate(cr.rootComments(visible(squirrel.Eq{"post_id": postID}, withHidden), page), page)
 
 	query, args, err := statement.ToSql()
 	if err != nil {
@@ -163,9 +183,7 @@ func (cr *commentRepo) GetRootCommentsPag(ctx context.Context, postID int, limit
 
 	var comments []*entity.Comment
 	for rows.Next() {
-		var comment entity.Comment
-		err = rows.Scan(&comment.ID, &comment.PostID, &comment.AuthorID,
-			&comment.Content, &comment.Created, &comment.ParentID)
+		comment, err := scanCommentScored(rows, page.Sort.Ranked())
 		if err != nil {
 			logrus.WithError(err).Error("failed to scan comment data")
 			return nil, &RepositoryError{
@@ -174,7 +192,7 @@ func (cr *commentRepo) GetRootCommentsPag(ctx context.Context, postID int, limit
 				Err:       ErrGettingComments,
 			}
 		}
-		comments = append(comments, &comment)
+		comments = append(comments, comment)
 	}
 	if err = rows.Err(); err != nil {
 		logrus.WithError(err).Error("error iterating over rows")
@@ -191,12 +209,11 @@ func (cr *commentRepo) GetRootCommentsPag(ctx context.Context, postID int, limit
 func (cr *commentRepo) GetCommentByID(ctx context.Context, id int) (*entity.Comment, error) {
 	logrus.WithField("commentID", id).Debug("getting comment by id")
 
-	statement := cr.SQL.Select("id", "post_id", "author_id", "content", "created", "parent_id").
+	statement := cr.SQL.Select(commentColumns...).
 		From("comments").
 		Where(squirrel.Eq{"id": id})
 
 	query, args, err := statement.ToSql()
-	logrus.WithError(err).Error(ErrGeneratingSQL)
 	if err != nil {
 		logrus.WithError(err).Error("failed to generate SQL query")
 		return nil, &RepositoryError{
@@ -206,15 +223,14 @@ func (cr *commentRepo) GetCommentByID(ctx context.Context, id int) (*entity.Comm
 		}
 	}
 
-	row := cr.DB.QueryRowContext(ctx, query, args...)
-	var comment entity.Comment
-	err = row.Scan(&comment.ID, &comment.PostID, &comment.AuthorID, &comment.Content, &comment.Created, &comment.ParentID)
+	comment, err := scanComment(cr.DB.QueryRowContext(ctx, query, args...))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			logrus.WithError(err).Error("comment not found")
 			return nil, &RepositoryError{
 				Operation: "getting comment by id",
 				Content:   "comment not found",
+				Err:       ErrCommentNotFound,
 			}
 		}
 		logrus.WithError(err).Error("failed to get comment by id")
@@ -226,5 +242,615 @@ func (cr *commentRepo) GetCommentByID(ctx context.Context, id int) (*entity.Comm
 	}
 
 	logrus.WithField("commentID", comment.ID).Debug("got comment")
-	return &comment, nil
+	return comment, nil
+}
+
+// GetReplies returns one page of replies to every given comment, keyed by the comment. A reply is found
+// up to depth levels below the comment, ordered by (created, id), and a depth of 1 fetches only
+// the direct children. Unless withHidden is set, hidden replies are skipped together with the replies below them.
+func (cr *commentRepo) GetReplies(ctx context.Context, parentIDs []int, depth int,
+	page pagination.Page, withHidden bool) (map[int][]*entity.Comment, error) {
+	logrus.WithField("parentIDs", parentIDs).Debugf("getting replies up to depth %d", depth)
+
+	// root_id is the given comment a reply was found under
+	replies := cr.SQL.Select(append(commentColumns, "parent_id AS root_id")...).
+		From("comments").
+		Where(visible(squirrel.Eq{"parent_id": parentIDs}, withHidden))
+	if depth > 1 {
+		var hidden, hiddenReplies string
+		if !withHidden {
+			hidden, hiddenReplies = " AND NOT hidden", " AND NOT c.hidden"
+		}
+		replies = cr.SQL.Select(append(commentColumns, "root_id")...).
+			Prefix(`WITH RECURSIVE replies AS (
+				SELECT `+columnList("", commentColumns)+`, parent_id AS root_id, 1 AS depth
+				FROM comments WHERE parent_id = ANY(?)`+hidden+`
+				UNION ALL
+				SELECT `+columnList("c", commentColumns)+`, r.root_id, r.depth + 1
+				FROM comments c JOIN replies r ON c.parent_id = r.id
+				WHERE r.depth < ?`+hiddenReplies+`)`, pq.Array(parentIDs), depth).
+			From("replies")
+	}
+	if cursor := afterCursor(page); cursor != nil {
+		replies = replies.Where(cursor)
+	}
+
+	order := strings.Join(orderBy(page.Sort), ", ")
+	partitioned := cr.SQL.Select(append(commentColumns, "root_id",
+		"ROW_NUMBER() OVER (PARTITION BY root_id ORDER BY "+order+") AS rn")...).
+		FromSelect(replies, "found")
+	statement := cr.SQL.Select(append(commentColumns, "root_id")...).
+		FromSelect(partitioned, "ranked").
+		OrderBy(append([]string{"root_id"}, orderBy(page.Sort)...)...)
+	if limit := page.Limit(); limit != nil {
+		statement = statement.Where(squirrel.LtOrEq{"rn": *limit})
+	}
+
+	query, args, err := statement.ToSql()
+	if err != nil {
+		logrus.WithError(err).Error("failed to generate SQL query")
+		return nil, &RepositoryError{
+			Operation: "getting replies",
+			Content:   "failed to generate SQL query",
+			Err:       ErrGeneratingSQL,
+		}
+	}
+
+	rows, err := cr.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		logrus.WithError(err).Error("failed to get replies")
+		return nil, &RepositoryError{
+			Operation: "getting replies",
+			Content:   "failed to get replies",
+			Err:       ErrGettingComments,
+		}
+	}
+	defer rows.Close()
+
+	result := make(map[int][]*entity.Comment, len(parentIDs))
+	for rows.Next() {
+		var rootID int
+		comment, err := scanCommentScored(rows, false, &rootID)
+		if err != nil {
+			logrus.WithError(err).Error("failed to scan row")
+			return nil, &RepositoryError{
+				Operation: "getting replies",
+				Content:   "failed to scan row",
+				Err:       ErrGettingComments,
+			}
+		}
+		result[rootID] = append(result[rootID], comment)
+	}
+
+	if err = rows.Err(); err != nil {
+		logrus.WithError(err).Error("rows error")
+		return nil, &RepositoryError{
+			Operation: "getting replies",
+			Content:   "rows error",
+			Err:       ErrGettingComments,
+		}
+	}
+	logrus.Debug("got replies")
+
+	return result, nil
+}
+
+// GetReplyCounts counts the direct replies of the comments, hidden replies are counted only withHidden.
+func (cr *commentRepo) GetReplyCounts(ctx context.Context, parentIDs []int, withHidden bool) (map[int]int, error) {
+	logrus.WithField("parentIDs", parentIDs).Debug("counting replies")
+
+	statement := cr.SQL.Select("parent_id", "COUNT(*)").
+		From("comments").
+		Where(squirrel.Eq{"parent_id": parentIDs})
+	if !withHidden {
+		statement = statement.Where(squirrel.Eq{"hidden": false})
+	}
+	statement = statement.GroupBy("parent_id")
+
+	query, args, err := statement.ToSql()
+	if err != nil {
+		logrus.WithError(err).Error("failed to generate SQL query")
+		return nil, &RepositoryError{
+			Operation: "counting replies",
+			Content:   "failed to generate SQL query",
+			Err:       ErrGeneratingSQL,
+		}
+	}
+
+	rows, err := cr.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		logrus.WithError(err).Error("failed to count replies")
+		return nil, &RepositoryError{
+			Operation: "counting replies",
+			Content:   "failed to count replies",
+			Err:       ErrCountingReplies,
+		}
+	}
+	defer rows.Close()
+
+	counts := make(map[int]int, len(parentIDs))
+	for rows.Next() {
+		var parentID, count int
+		if err = rows.Scan(&parentID, &count); err != nil {
+			logrus.WithError(err).Error("failed to scan row")
+			return nil, &RepositoryError{
+				Operation: "counting replies",
+				Content:   "failed to scan row",
+				Err:       ErrCountingReplies,
+			}
+		}
+		counts[parentID] = count
+	}
+
+	if err = rows.Err(); err != nil {
+		logrus.WithError(err).Error("rows error")
+		return nil, &RepositoryError{
+			Operation: "counting replies",
+			Content:   "rows error",
+			Err:       ErrCountingReplies,
+		}
+	}
+	logrus.Debug("counted replies")
+
+	return counts, nil
+}
+
+// GetRootCommentsByPostIDs returns the first page of root comments of every given post,
+// ordered by post and then by the page sort.
+func (cr *commentRepo) GetRootCommentsByPostIDs(ctx context.Context, postIDs []int,
+	page pagination.Page, withHidden bool) ([]*entity.Comment, error) {
+	logrus.WithField("postIDs", postIDs).Debug("getting root comments for posts")
+
+	order := strings.Join(orderBy(page.Sort), ", ")
+	partitioned := cr.rootComments(visible(squirrel.Eq{"post_id": postIDs}, withHidden), page,
+		"ROW_NUMBER() OVER (PARTITION BY post_id ORDER BY "+order+") AS rn")
+
+	statement := cr.SQL.Select(withScore(commentColumns, page.Sort)...).
+		FromSelect(partitioned, "ranked").
+		OrderBy(append([]string{"post_id"}, orderBy(page.Sort)...)...)
+
+	if limit := page.Limit(); limit != nil {
+		statement = statement.Where(squirrel.LtOrEq{"rn": *limit})
+	}
+
+	query, args, err := statement.ToSql()
+	if err != nil {
+		logrus.WithError(err).Error("failed to generate SQL query")
+		return nil, &RepositoryError{
+			Operation: "getting root comments for posts",
+			Content:   "failed to generate SQL query",
+			Err:       ErrGeneratingSQL,
+		}
+	}
+
+	rows, err := cr.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		logrus.WithError(err).Error("failed to get root comments for posts")
+		return nil, &RepositoryError{
+			Operation: "getting root comments for posts",
+			Content:   "failed to get root comments",
+			Err:       ErrGettingComments,
+		}
+	}
+	defer rows.Close()
+
+	var comments []*entity.Comment
+	for rows.Next() {
+		comment, err := scanCommentScored(rows, page.Sort.Ranked())
+		if err != nil {
+			logrus.WithError(err).Error("failed to scan row")
+			return nil, &RepositoryError{
+				Operation: "getting root comments for posts",
+				Content:   "failed to scan row",
+				Err:       ErrGettingComments,
+			}
+		}
+		comments = append(comments, comment)
+	}
+
+	if err = rows.Err(); err != nil {
+		logrus.WithError(err).Error("rows error")
+		return nil, &RepositoryError{
+			Operation: "getting root comments for posts",
+			Content:   "rows error",
+			Err:       ErrGettingComments,
+		}
+	}
+	logrus.Debug("got root comments for posts")
+
+	return comments, nil
+}
+
+// GetCommentsByIDs returns the comments found, unless withHidden is set comments hidden together
+// with their post or a comment above them are left out.
+func (cr *commentRepo) GetCommentsByIDs(ctx context.Context, ids []int, withHidden bool) ([]*entity.Comment, error) {
+	logrus.WithField("ids", ids).Debug("getting comments by IDs")
+
+	statement := cr.SQL.Select(commentColumns...).
+		From("comments").
+		Where(visibleThread("comments", squirrel.Eq{"id": ids}, withHidden))
+
+	query, args, err := statement.ToSql()
+	if err != nil {
+		logrus.WithError(err).Error("failed to generate SQL query")
+		return nil, &RepositoryError{
+			Operation: "getting comments by IDs",
+			Content:   "failed to generate SQL query",
+			Err:       ErrGeneratingSQL,
+		}
+	}
+
+	rows, err := cr.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		logrus.WithError(err).Error("failed to get comments by IDs")
+		return nil, &RepositoryError{
+			Operation: "getting comments by IDs",
+			Content:   "failed to get comments",
+			Err:       ErrGettingComments,
+		}
+	}
+	defer rows.Close()
+
+	var comments []*entity.Comment
+	for rows.Next() {
+		comment, err := scanComment(rows)
+		if err != nil {
+			logrus.WithError(err).Error("failed to scan row")
+			return nil, &RepositoryError{
+				Operation: "getting comments by IDs",
+				Content:   "failed to scan row",
+				Err:       ErrGettingComments,
+			}
+		}
+		comments = append(comments, comment)
+	}
+
+	if err = rows.Err(); err != nil {
+		logrus.WithError(err).Error("rows error")
+		return nil, &RepositoryError{
+			Operation: "getting comments by IDs",
+			Content:   "rows error",
+			Err:       ErrGettingComments,
+		}
+	}
+	logrus.Debug("got comments by IDs")
+
+	return comments, nil
+}
+
+// UpdateComment saves new content of a comment and keeps the replaced one as a revision
+// of editorID. Tombstones can not be updated.
+func (cr *commentRepo) UpdateComment(ctx context.Context, comment *entity.Comment, editorID int) error {
+	logrus.WithField("commentID", comment.ID).Debug("updating comment")
+
+	revision := cr.SQL.Insert("comment_revisions").
+		Columns("comment_id", "content", "editor_id", "edited").
+		Select(cr.SQL.Select("id", "content").
+			Column("?::INTEGER", editorID).
+			Column("?::TIMESTAMP", comment.Edited).
+			From("comments").
+			Where(squirrel.Eq{"id": comment.ID, "deleted": false}))
+
+	update := cr.SQL.Update("comments").
+		Set("content", comment.Content).
+		Set("edited", comment.Edited).
+		Where(squirrel.Eq{"id": comment.ID, "deleted": false})
+
+	revisionQuery, revisionArgs, err := revision.ToSql()
+	if err != nil {
+		logrus.WithError(err).Error("failed to generate SQL query for UpdateComment")
+		return &RepositoryError{
+			Operation: "updating comment",
+			Content:   "failed to generate SQL query",
+			Err:       ErrGeneratingSQL,
+		}
+	}
+	updateQuery, updateArgs, err := update.ToSql()
+	if err != nil {
+		logrus.WithError(err).Error("failed to generate SQL query for UpdateComment")
+		return &RepositoryError{
+			Operation: "updating comment",
+			Content:   "failed to generate SQL query",
+			Err:       ErrGeneratingSQL,
+		}
+	}
+
+	tx, err := cr.DB.BeginTx(ctx, nil)
+	if err != nil {
+		logrus.WithError(err).Error("failed to begin transaction")
+		return &RepositoryError{
+			Operation: "updating comment",
+			Content:   "failed to begin transaction",
+			Err:       ErrUpdatingComment,
+		}
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, revisionQuery, revisionArgs...)
+	if err != nil {
+		logrus.WithError(err).Error(ErrUpdatingComment)
+		return &RepositoryError{
+			Operation: "updating comment",
+			Content:   "failed to save revision",
+			Err:       ErrUpdatingComment,
+		}
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return &RepositoryError{
+			Operation: "updating comment",
+			Content:   "comment not found",
+			Err:       ErrCommentNotFound,
+		}
+	}
+
+	if _, err = tx.ExecContext(ctx, updateQuery, updateArgs...); err != nil {
+		logrus.WithError(err).Error(ErrUpdatingComment)
+		return &RepositoryError{
+			Operation: "updating comment",
+			Content:   "failed to update comment",
+			Err:       ErrUpdatingComment,
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		logrus.WithError(err).Error("failed to commit transaction")
+		return &RepositoryError{
+			Operation: "updating comment",
+			Content:   "failed to commit transaction",
+			Err:       ErrUpdatingComment,
+		}
+	}
+	logrus.WithField("commentID", comment.ID).Debug("updated comment")
+
+	return nil
+}
+
+// GetRevisions returns revisions of the given comments ordered by comment and then by edit time.
+func (cr *commentRepo) GetRevisions(ctx context.Context, commentIDs []int) ([]*entity.CommentRevision, error) {
+	logrus.WithField("commentIDs", commentIDs).Debug("getting comment revisions")
+
+	statement := cr.SQL.Select("id", "comment_id", "content", "editor_id", "edited").
+		From("comment_revisions").
+		Where(squirrel.Eq{"comment_id": commentIDs}).
+		OrderBy("comment_id", "edited", "id")
+
+	query, args, err := statement.ToSql()
+	if err != nil {
+		logrus.WithError(err).Error("failed to generate SQL query")
+		return nil, &RepositoryError{
+			Operation: "getting comment revisions",
+			Content:   "failed to generate SQL query",
+			Err:       ErrGeneratingSQL,
+		}
+	}
+
+	rows, err := cr.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		logrus.WithError(err).Error("failed to get comment revisions")
+		return nil, &RepositoryError{
+			Operation: "getting comment revisions",
+			Content:   "failed to get revisions",
+			Err:       ErrGettingRevisions,
+		}
+	}
+	defer rows.Close()
+
+	var revisions []*entity.CommentRevision
+	for rows.Next() {
+		var revision entity.CommentRevision
+		err = rows.Scan(&revision.ID, &revision.CommentID, &revision.Content, &revision.EditorID, &revision.Edited)
+		if err != nil {
+			logrus.WithError(err).Error("failed to scan row")
+			return nil, &RepositoryError{
+				Operation: "getting comment revisions",
+				Content:   "failed to scan row",
+				Err:       ErrGettingRevisions,
+			}
+		}
+		revisions = append(revisions, &revision)
+	}
+
+	if err = rows.Err(); err != nil {
+		logrus.WithError(err).Error("rows error")
+		return nil, &RepositoryError{
+			Operation: "getting comment revisions",
+			Content:   "rows error",
+			Err:       ErrGettingRevisions,
+		}
+	}
+	logrus.Debug("got comment revisions")
+
+	return revisions, nil
+}
+
+// DeleteComment removes a comment without replies. A comment that has replies is turned
+// into a tombstone instead, so the thread below it stays reachable. The returned flag
+// reports whether the comment was tombstoned.
+func (cr *commentRepo) DeleteComment(ctx context.Context, id int) (bool, error) {
+	logrus.WithField("commentID", id).Debug("deleting comment")
+
+	tx, err := cr.DB.BeginTx(ctx, nil)
+	if err != nil {
+		logrus.WithError(err).Error("failed to begin transaction")
+		return false, &RepositoryError{
+			Operation: "deleting comment",
+			Content:   "failed to begin transaction",
+			Err:       ErrDeletingComment,
+		}
+	}
+	defer tx.Rollback()
+
+	// AddComment key-share locks the parent of a reply, which conflicts with this lock: a reply
+	// committed first is seen by the replies check below, and one waiting for the lock finds
+	// its parent gone once the deletion is over
+	var deleted bool
+	lock := cr.SQL.Select("deleted").From("comments").Where(squirrel.Eq{"id": id}).Suffix("FOR UPDATE")
+	if err = cr.scanLocked(ctx, tx, lock, &deleted); err != nil {
+		return false, err
+	}
+	if deleted {
+		return false, &RepositoryError{
+			Operation: "deleting comment",
+			Content:   "comment is already deleted",
+			Err:       ErrCommentDeleted,
+		}
+	}
+
+	var hasReplies bool
+	replies := cr.SQL.Select().Column(squirrel.Expr("EXISTS (SELECT 1 FROM comments WHERE parent_id = ?)", id))
+	if err = cr.scanLocked(ctx, tx, replies, &hasReplies); err != nil {
+		return false, err
+	}
+
+	statements := []squirrel.Sqlizer{cr.SQL.Delete("comments").Where(squirrel.Eq{"id": id})}
+	if hasReplies {
+		// the mentions and the revisions were in the content the tombstone replaced
+		statements = []squirrel.Sqlizer{
+			cr.SQL.Update("comments").
+				Set("content", entity.DeletedCommentContent).
+				Set("deleted", true).
+				Where(squirrel.Eq{"id": id}),
+			cr.SQL.Delete("mentions").Where(squirrel.Eq{"comment_id": id}),
+			cr.SQL.Delete("comment_revisions").Where(squirrel.Eq{"comment_id": id}),
+		}
+	}
+	for _, statement := range statements {
+		if err = cr.execDeleting(ctx, tx, statement); err != nil {
+			return false, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		logrus.WithError(err).Error("failed to commit transaction")
+		return false, &RepositoryError{
+			Operation: "deleting comment",
+			Content:   "failed to commit transaction",
+			Err:       ErrDeletingComment,
+		}
+	}
+	if hasReplies {
+		logrus.WithField("commentID", id).Debug("tombstoned comment")
+	} else {
+		logrus.WithField("commentID", id).Debug("deleted comment")
+	}
+
+	return hasReplies, nil
+}
+
+// GetCommentsSince returns at most limit comments of the post created after the given point,
+// oldest first. Unless withHidden is set, comments of a hidden post or under a hidden comment are skipped.
+func (cr *commentRepo) GetCommentsSince(ctx context.Context, postID int, since pagination.Since,
+	limit int, withHidden bool) ([]*entity.Comment, error) {
+	logrus.WithField("postID", postID).Debug("getting comments since")
+
+	statement := cr.SQL.Select(commentColumns...).
+		From("comments").
+		Where(visibleThread("comments", squirrel.Eq{"post_id": postID}, withHidden)).
+		OrderBy("created", "id").
+		Limit(uint64(limit))
+	if since.CommentID != 0 {
+		statement = statement.Where(squirrel.Gt{"id": since.CommentID})
+	} else {
+		statement = statement.Where(squirrel.Gt{"created": since.Time})
+	}
+
+	query, args, err := statement.ToSql()
+	if err != nil {
+		logrus.WithError(err).Error("failed to generate SQL query")
+		return nil, &RepositoryError{
+			Operation: "getting comments since",
+			Content:   "failed to generate SQL query",
+			Err:       ErrGeneratingSQL,
+		}
+	}
+
+	rows, err := cr.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		logrus.WithError(err).Error("failed to get comments since")
+		return nil, &RepositoryError{
+			Operation: "getting comments since",
+			Content:   "failed to get comments",
+			Err:       ErrGettingComments,
+		}
+	}
+	defer rows.Close()
+
+	var comments []*entity.Comment
+	for rows.Next() {
+		comment, err := scanComment(rows)
+		if err != nil {
+			logrus.WithError(err).Error("failed to scan row")
+			return nil, &RepositoryError{
+				Operation: "getting comments since",
+				Content:   "failed to scan row",
+				Err:       ErrGettingComments,
+			}
+		}
+		comments = append(comments, comment)
+	}
+
+	if err = rows.Err(); err != nil {
+		logrus.WithError(err).Error("rows error")
+		return nil, &RepositoryError{
+			Operation: "getting comments since",
+			Content:   "rows error",
+			Err:       ErrGettingComments,
+		}
+	}
+	logrus.Debug("got comments since")
+
+	return comments, nil
+}
+
+// scanLocked reads one row within the transaction of DeleteComment.
+func (cr *commentRepo) scanLocked(ctx context.Context, tx *sql.Tx, statement squirrel.Sqlizer, dest ...any) error {
+	query, args, err := statement.ToSql()
+	if err != nil {
+		logrus.WithError(err).Error("failed to generate SQL query for DeleteComment")
+		return &RepositoryError{
+			Operation: "deleting comment",
+			Content:   "failed to generate SQL query",
+			Err:       ErrGeneratingSQL,
+		}
+	}
+
+	if err = tx.QueryRowContext(ctx, query, args...).Scan(dest...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return &RepositoryError{
+				Operation: "deleting comment",
+				Content:   "comment not found",
+				Err:       ErrCommentNotFound,
+			}
+		}
+		logrus.WithError(err).Error(ErrDeletingComment)
+		return &RepositoryError{
+			Operation: "deleting comment",
+			Content:   "failed to read comment",
+			Err:       ErrDeletingComment,
+		}
+	}
+	return nil
+}
+
+// execDeleting runs a statement within the transaction of DeleteComment.
+func (cr *commentRepo) execDeleting(ctx context.Context, tx *sql.Tx, statement squirrel.Sqlizer) error {
+	query, args, err := statement.ToSql()
+	if err != nil {
+		logrus.WithError(err).Error("failed to generate SQL query for DeleteComment")
+		return &RepositoryError{
+			Operation: "deleting comment",
+			Content:   "failed to generate SQL query",
+			Err:       ErrGeneratingSQL,
+		}
+	}
+
+	if _, err = tx.ExecContext(ctx, query, args...); err != nil {
+		logrus.WithError(err).Error(ErrDeletingComment)
+		return &RepositoryError{
+			Operation: "deleting comment",
+			Content:   "failed to delete comment",
+			Err:       ErrDeletingComment,
+		}
+	}
+	return nil
 }
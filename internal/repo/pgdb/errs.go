@@ -1,6 +1,7 @@
 package pgdb
 
 import (
+	"Commentary/internal/repo"
 	"errors"
 	"fmt"
 )
@@ -15,16 +16,47 @@ func (re *RepositoryError) Error() string {
 	return fmt.Sprintf("repository error occured while %s, %s: %v", re.Operation, re.Content, re.Err)
 }
 
+func (re *RepositoryError) Unwrap() error {
+	return re.Err
+}
+
 var (
-	ErrAddingComment    = errors.New("error adding comment")
-	ErrGeneratingSQL    = errors.New("failed to generate SQL query")
-	ErrGettingComments  = errors.New("error getting comments")
-	ErrGettingPost      = errors.New("error getting post")
-	ErrAddingPost       = errors.New("error adding post")
-	ErrTogglingComments = errors.New("error toggling comments")
-	ErrGettingPosts     = errors.New("error getting posts")
-	ErrGettingUser      = errors.New("error getting user")
-	ErrGettingUsers     = errors.New("error getting users")
-	ErrGettingComment   = errors.New("error getting comment")
-	ErrAddingUser       = errors.New("error adding user")
+	ErrAddingComment          = errors.New("error adding comment")
+	ErrGeneratingSQL          = errors.New("failed to generate SQL query")
+	ErrGettingComments        = errors.New("error getting comments")
+	ErrGettingPost            = errors.New("error getting post")
+	ErrAddingPost             = errors.New("error adding post")
+	ErrTogglingComments       = errors.New("error toggling comments")
+	ErrGettingPosts           = errors.New("error getting posts")
+	ErrGettingUser            = errors.New("error getting user")
+	ErrGettingUsers           = errors.New("error getting users")
+	ErrGettingComment         = errors.New("error getting comment")
+	ErrAddingUser             = errors.New("error adding user")
+	ErrCountingReplies        = errors.New("error counting replies")
+	ErrUserNotFound           = repo.ErrUserNotFound
+	ErrPostNotFound           = repo.ErrPostNotFound
+	ErrCommentNotFound        = repo.ErrCommentNotFound
+	ErrCommentDeleted         = repo.ErrCommentDeleted
+	ErrParentNotFound         = repo.ErrParentNotFound
+	ErrUserAlreadyExists      = repo.ErrUserAlreadyExists
+	ErrUpdatingPost           = errors.New("error updating post")
+	ErrDeletingPost           = errors.New("error deleting post")
+	ErrUpdatingComment        = errors.New("error updating comment")
+	ErrDeletingComment        = errors.New("error deleting comment")
+	ErrGettingRevisions       = errors.New("error getting comment revisions")
+	ErrAddingReaction         = errors.New("error adding reaction")
+	ErrRemovingReaction       = errors.New("error removing reaction")
+	ErrCountingReactions      = errors.New("error counting reactions")
+	ErrReactionTargetNotFound = repo.ErrReactionTargetNotFound
+	ErrSearching              = errors.New("error searching")
+	ErrAddingNotifications    = errors.New("error adding notifications")
+	ErrGettingNotifications   = errors.New("error getting notifications")
+	ErrMarkingNotifications   = errors.New("error marking notifications read")
+	ErrReplacingMentions      = errors.New("error replacing mentions")
+	ErrGettingMentions        = errors.New("error getting mentions")
+	ErrAddingReport           = errors.New("error adding report")
+	ErrGettingReports         = errors.New("error getting reports")
+	ErrResolvingReports       = errors.New("error resolving reports")
+	ErrHidingContent          = errors.New("error hiding content")
+	ErrSettingRole            = errors.New("error setting user role")
 )
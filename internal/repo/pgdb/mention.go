@@ -0,0 +1,154 @@
+package pgdb
+
+import (
+	"Commentary/internal/entity"
+	"Commentary/internal/repo"
+	"context"
+	"database/sql"
+	"github.com/Masterminds/squirrel"
+	"github.com/sirupsen/logrus"
+)
+
+type mentionRepo struct {
+	DB  *sql.DB
+	SQL squirrel.StatementBuilderType
+}
+
+func NewMentionRepo(DB *sql.DB) repo.MentionRepo {
+	return &mentionRepo{
+		DB:  DB,
+		SQL: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}
+}
+
+// ReplaceMentions saves the mentions of the target's content in place of the previous ones.
+func (mr *mentionRepo) ReplaceMentions(ctx context.Context, target entity.ReactionTarget,
+	mentions []*entity.Mention) error {
+	logrus.WithField("target", target).Debug("replacing mentions")
+
+	column := targetColumn(target.Kind)
+	deleteQuery, deleteArgs, err := mr.SQL.Delete("mentions").Where(squirrel.Eq{column: target.ID}).ToSql()
+	if err != nil {
+		logrus.WithError(err).Error("failed to generate SQL query for ReplaceMentions")
+		return &RepositoryError{
+			Operation: "replacing mentions",
+			Content:   "failed to generate SQL query",
+			Err:       ErrGeneratingSQL,
+		}
+	}
+
+	tx, err := mr.DB.BeginTx(ctx, nil)
+	if err != nil {
+		logrus.WithError(err).Error("failed to begin transaction")
+		return &RepositoryError{
+			Operation: "replacing mentions",
+			Content:   "failed to begin transaction",
+			Err:       ErrReplacingMentions,
+		}
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.ExecContext(ctx, deleteQuery, deleteArgs...); err != nil {
+		logrus.WithError(err).Error(ErrReplacingMentions)
+		return &RepositoryError{
+			Operation: "replacing mentions",
+			Content:   "failed to delete previous mentions",
+			Err:       ErrReplacingMentions,
+		}
+	}
+
+	if len(mentions) > 0 {
+		insert := mr.SQL.Insert("mentions").Columns(column, "user_id", "start", "length")
+		for _, mention := range mentions {
+			insert = insert.Values(target.ID, mention.UserID, mention.Offset, mention.Length)
+		}
+		insertQuery, insertArgs, err := insert.ToSql()
+		if err != nil {
+			logrus.WithError(err).Error("failed to generate SQL query for ReplaceMentions")
+			return &RepositoryError{
+				Operation: "replacing mentions",
+				Content:   "failed to generate SQL query",
+				Err:       ErrGeneratingSQL,
+			}
+		}
+		if _, err = tx.ExecContext(ctx, insertQuery, insertArgs...); err != nil {
+			logrus.WithError(err).Error(ErrReplacingMentions)
+			return &RepositoryError{
+				Operation: "replacing mentions",
+				Content:   "failed to add mentions",
+				Err:       ErrReplacingMentions,
+			}
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		logrus.WithError(err).Error("failed to commit transaction")
+		return &RepositoryError{
+			Operation: "replacing mentions",
+			Content:   "failed to commit transaction",
+			Err:       ErrReplacingMentions,
+		}
+	}
+	logrus.WithField("target", target).Debug("replaced mentions")
+
+	return nil
+}
+
+// GetMentions returns the mentions in the content of the given targets ordered by target and offset.
+func (mr *mentionRepo) GetMentions(ctx context.Context, kind entity.TargetKind, ids []int) ([]*entity.Mention, error) {
+	logrus.WithField("ids", ids).Debugf("getting mentions in %s", kind)
+
+	column := targetColumn(kind)
+	statement := mr.SQL.Select(column, "user_id", "start", "length").
+		From("mentions").
+		Where(squirrel.Eq{column: ids}).
+		OrderBy(column, "start")
+
+	query, args, err := statement.ToSql()
+	if err != nil {
+		logrus.WithError(err).Error("failed to generate SQL query")
+		return nil, &RepositoryError{
+			Operation: "getting mentions",
+			Content:   "failed to generate SQL query",
+			Err:       ErrGeneratingSQL,
+		}
+	}
+
+	rows, err := mr.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		logrus.WithError(err).Error("failed to get mentions")
+		return nil, &RepositoryError{
+			Operation: "getting mentions",
+			Content:   "failed to get mentions",
+			Err:       ErrGettingMentions,
+		}
+	}
+	defer rows.Close()
+
+	var mentions []*entity.Mention
+	for rows.Next() {
+		mention := entity.Mention{Target: entity.ReactionTarget{Kind: kind}}
+		err = rows.Scan(&mention.Target.ID, &mention.UserID, &mention.Offset, &mention.Length)
+		if err != nil {
+			logrus.WithError(err).Error("failed to scan row")
+			return nil, &RepositoryError{
+				Operation: "getting mentions",
+				Content:   "failed to scan row",
+				Err:       ErrGettingMentions,
+			}
+		}
+		mentions = append(mentions, &mention)
+	}
+
+	if err = rows.Err(); err != nil {
+		logrus.WithError(err).Error("rows error")
+		return nil, &RepositoryError{
+			Operation: "getting mentions",
+			Content:   "rows error",
+			Err:       ErrGettingMentions,
+		}
+	}
+	logrus.Debug("got mentions")
+
+	return mentions, nil
+}
@@ -0,0 +1,209 @@
+package pgdb
+
+import (
+	"Commentary/internal/entity"
+	"Commentary/internal/pagination"
+	"Commentary/internal/repo"
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/Masterminds/squirrel"
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+)
+
+type moderationRepo struct {
+	DB  *sql.DB
+	SQL squirrel.StatementBuilderType
+}
+
+func NewModerationRepo(DB *sql.DB) repo.ModerationRepo {
+	return &moderationRepo{
+		DB:  DB,
+		SQL: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}
+}
+
+func targetTable(kind entity.TargetKind) string {
+	if kind == entity.TargetComment {
+		return "comments"
+	}
+	return "posts"
+}
+
+// AddReport saves the report and sets its ID. A user reporting a target again before
+// its reports are resolved keeps the first report and leaves the ID unset.
+func (mr *moderationRepo) AddReport(ctx context.Context, report *entity.Report) error {
+	logrus.WithField("target", report.Target).Debug("adding report")
+
+	statement := mr.SQL.Insert("reports").
+		Columns(targetColumn(report.Target.Kind), "reporter_id", "reason", "created").
+		Values(report.Target.ID, report.ReporterID, report.Reason, report.Created).
+		Suffix("ON CONFLICT DO NOTHING RETURNING id")
+
+	query, args, err := statement.ToSql()
+	if err != nil {
+		logrus.WithError(err).Error("failed to generate SQL query for AddReport")
+		return &RepositoryError{
+			Operation: "adding report",
+			Content:   "failed to generate SQL query",
+			Err:       ErrGeneratingSQL,
+		}
+	}
+
+	err = mr.DB.QueryRowContext(ctx, query, args...).Scan(&report.ID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == foreignKeyViolation {
+			return &RepositoryError{
+				Operation: "adding report",
+				Content:   "reported target not found",
+				Err:       ErrReactionTargetNotFound,
+			}
+		}
+		logrus.WithError(err).Error(ErrAddingReport)
+		return &RepositoryError{
+			Operation: "adding report",
+			Content:   "failed to add report",
+			Err:       ErrAddingReport,
+		}
+	}
+	logrus.WithField("target", report.Target).Debug("added report")
+
+	return nil
+}
+
+// GetOpenReports returns a page of the reports no moderator has acted on, oldest first.
+func (mr *moderationRepo) GetOpenReports(ctx context.Context, page pagination.Page) ([]*entity.Report, error) {
+	logrus.Debug("getting open reports")
+
+	statement := paginate(mr.SQL.Select(reportColumns...).
+		From("reports").
+		Where(squirrel.Eq{"resolved": false}), page)
+
+	query, args, err := statement.ToSql()
+	if err != nil {
+		logrus.WithError(err).Error("failed to generate SQL query for GetOpenReports")
+		return nil, &RepositoryError{
+			Operation: "getting reports",
+			Content:   "failed to generate SQL query",
+			Err:       ErrGeneratingSQL,
+		}
+	}
+
+	rows, err := mr.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		logrus.WithError(err).Error("failed to get reports")
+		return nil, &RepositoryError{
+			Operation: "getting reports",
+			Content:   "failed to get reports",
+			Err:       ErrGettingReports,
+		}
+	}
+	defer rows.Close()
+
+	var reports []*entity.Report
+	for rows.Next() {
+		report, err := scanReport(rows)
+		if err != nil {
+			logrus.WithError(err).Error("failed to scan row")
+			return nil, &RepositoryError{
+				Operation: "getting reports",
+				Content:   "failed to scan row",
+				Err:       ErrGettingReports,
+			}
+		}
+		reports = append(reports, report)
+	}
+
+	if err = rows.Err(); err != nil {
+		logrus.WithError(err).Error("rows error")
+		return nil, &RepositoryError{
+			Operation: "getting reports",
+			Content:   "rows error",
+			Err:       ErrGettingReports,
+		}
+	}
+	logrus.Debug("got open reports")
+
+	return reports, nil
+}
+
+// ResolveReports takes the open reports of the target off the queue.
+func (mr *moderationRepo) ResolveReports(ctx context.Context, target entity.ReactionTarget) error {
+	logrus.WithField("target", target).Debug("resolving reports")
+
+	statement := mr.SQL.Update("reports").
+		Set("resolved", true).
+		Where(squirrel.Eq{targetColumn(target.Kind): target.ID, "resolved": false})
+
+	query, args, err := statement.ToSql()
+	if err != nil {
+		logrus.WithError(err).Error("failed to generate SQL query for ResolveReports")
+		return &RepositoryError{
+			Operation: "resolving reports",
+			Content:   "failed to generate SQL query",
+			Err:       ErrGeneratingSQL,
+		}
+	}
+
+	if _, err = mr.DB.ExecContext(ctx, query, args...); err != nil {
+		logrus.WithError(err).Error(ErrResolvingReports)
+		return &RepositoryError{
+			Operation: "resolving reports",
+			Content:   "failed to update reports",
+			Err:       ErrResolvingReports,
+		}
+	}
+	logrus.WithField("target", target).Debug("resolved reports")
+
+	return nil
+}
+
+// SetHidden hides the post or the comment from users other than moderators, or restores it.
+func (mr *moderationRepo) SetHidden(ctx context.Context, target entity.ReactionTarget, hidden bool) error {
+	logrus.WithField("target", target).Debugf("setting hidden to %t", hidden)
+
+	statement := mr.SQL.Update(targetTable(target.Kind)).
+		Set("hidden", hidden).
+		Where(squirrel.Eq{"id": target.ID})
+
+	query, args, err := statement.ToSql()
+	if err != nil {
+		logrus.WithError(err).Error("failed to generate SQL query for SetHidden")
+		return &RepositoryError{
+			Operation: "hiding content",
+			Content:   "failed to generate SQL query",
+			Err:       ErrGeneratingSQL,
+		}
+	}
+
+	result, err := mr.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		logrus.WithError(err).Error(ErrHidingContent)
+		return &RepositoryError{
+			Operation: "hiding content",
+			Content:   "failed to update " + targetTable(target.Kind),
+			Err:       ErrHidingContent,
+		}
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		logrus.WithError(err).Error(ErrHidingContent)
+		return &RepositoryError{
+			Operation: "hiding content",
+			Content:   "failed to count updated rows",
+			Err:       ErrHidingContent,
+		}
+	}
+	if affected == 0 {
+		return &RepositoryError{
+			Operation: "hiding content",
+			Content:   "target not found",
+			Err:       ErrReactionTargetNotFound,
+		}
+	}
+	logrus.WithField("target", target).Debug("set hidden")
+
+	return nil
+}
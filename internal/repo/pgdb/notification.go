@@ -0,0 +1,196 @@
+package pgdb
+
+import (
+	"Commentary/internal/entity"
+	"Commentary/internal/pagination"
+	"Commentary/internal/repo"
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/Masterminds/squirrel"
+	"github.com/sirupsen/logrus"
+)
+
+type notificationRepo struct {
+	DB  *sql.DB
+	SQL squirrel.StatementBuilderType
+}
+
+func NewNotificationRepo(DB *sql.DB) repo.NotificationRepo {
+	return &notificationRepo{
+		DB:  DB,
+		SQL: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}
+}
+
+// AddNotifications saves the notifications in one statement and sets their ids.
+func (nr *notificationRepo) AddNotifications(ctx context.Context, notifications []*entity.Notification) error {
+	logrus.WithField("count", len(notifications)).Debug("adding notifications")
+	if len(notifications) == 0 {
+		return nil
+	}
+
+	statement := nr.SQL.Insert("notifications").
+		Columns("user_id", "kind", "comment_id", "actor_id", "created").
+		Suffix("RETURNING id")
+	for _, notification := range notifications {
+		statement = statement.Values(notification.UserID, notification.Kind, notification.CommentID,
+			notification.ActorID, notification.Created)
+	}
+
+	query, args, err := statement.ToSql()
+	if err != nil {
+		logrus.WithError(err).Error("failed to generate SQL query for AddNotifications")
+		return &RepositoryError{
+			Operation: "adding notifications",
+			Content:   "failed to generate SQL query",
+			Err:       ErrGeneratingSQL,
+		}
+	}
+
+	rows, err := nr.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		logrus.WithError(err).Error(ErrAddingNotifications)
+		return &RepositoryError{
+			Operation: "adding notifications",
+			Content:   "failed to add notifications",
+			Err:       ErrAddingNotifications,
+		}
+	}
+	defer rows.Close()
+
+	// ids are returned in the order of the inserted rows
+	for i := 0; rows.Next(); i++ {
+		if err = rows.Scan(&notifications[i].ID); err != nil {
+			logrus.WithError(err).Error("failed to scan row")
+			return &RepositoryError{
+				Operation: "adding notifications",
+				Content:   "failed to scan row",
+				Err:       ErrAddingNotifications,
+			}
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		logrus.WithError(err).Error("rows error")
+		return &RepositoryError{
+			Operation: "adding notifications",
+			Content:   "rows error",
+			Err:       ErrAddingNotifications,
+		}
+	}
+	logrus.Debug("added notifications")
+
+	return nil
+}
+
+// GetNotifications returns a page of the user's notifications, the page sort is expected to be newest first.
+// The notifications about comments hidden from non-moderators are returned only withHidden.
+func (nr *notificationRepo) GetNotifications(ctx context.Context, userID int, unreadOnly bool,
+	page pagination.Page, withHidden bool) ([]*entity.Notification, error) {
+	logrus.WithField("userID", userID).Debug("getting notifications")
+
+	statement := nr.SQL.Select(notificationColumns...).
+		From("notifications").
+		Where(squirrel.Eq{"user_id": userID})
+	if unreadOnly {
+		statement = statement.Where(squirrel.Eq{"read": false})
+	}
+	if !withHidden {
+		statement = statement.Where(squirrel.Expr(`EXISTS (SELECT 1 FROM comments c
+	WHERE c.id = notifications.comment_id AND NOT c.hidden AND NOT (` + fmt.Sprintf(hiddenThread, "c") + `))`))
+	}
+	statement = paginate(statement, page)
+
+	query, args, err := statement.ToSql()
+	if err != nil {
+		logrus.WithError(err).Error("failed to generate SQL query for GetNotifications")
+		return nil, &RepositoryError{
+			Operation: "getting notifications",
+			Content:   "failed to generate SQL query",
+			Err:       ErrGeneratingSQL,
+		}
+	}
+
+	rows, err := nr.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		logrus.WithError(err).Error("failed to get notifications")
+		return nil, &RepositoryError{
+			Operation: "getting notifications",
+			Content:   "failed to get notifications",
+			Err:       ErrGettingNotifications,
+		}
+	}
+	defer rows.Close()
+
+	var notifications []*entity.Notification
+	for rows.Next() {
+		notification, err := scanNotification(rows)
+		if err != nil {
+			logrus.WithError(err).Error("failed to scan row")
+			return nil, &RepositoryError{
+				Operation: "getting notifications",
+				Content:   "failed to scan row",
+				Err:       ErrGettingNotifications,
+			}
+		}
+		notifications = append(notifications, notification)
+	}
+
+	if err = rows.Err(); err != nil {
+		logrus.WithError(err).Error("rows error")
+		return nil, &RepositoryError{
+			Operation: "getting notifications",
+			Content:   "rows error",
+			Err:       ErrGettingNotifications,
+		}
+	}
+	logrus.Debug("got notifications")
+
+	return notifications, nil
+}
+
+// MarkNotificationsRead marks the user's notifications with the given ids as read, all of them if ids is nil.
+// It returns the number of notifications that were unread.
+func (nr *notificationRepo) MarkNotificationsRead(ctx context.Context, userID int, ids []int) (int, error) {
+	logrus.WithField("userID", userID).Debug("marking notifications read")
+
+	statement := nr.SQL.Update("notifications").
+		Set("read", true).
+		Where(squirrel.Eq{"user_id": userID, "read": false})
+	if ids != nil {
+		statement = statement.Where(squirrel.Eq{"id": ids})
+	}
+
+	query, args, err := statement.ToSql()
+	if err != nil {
+		logrus.WithError(err).Error("failed to generate SQL query for MarkNotificationsRead")
+		return 0, &RepositoryError{
+			Operation: "marking notifications read",
+			Content:   "failed to generate SQL query",
+			Err:       ErrGeneratingSQL,
+		}
+	}
+
+	result, err := nr.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		logrus.WithError(err).Error(ErrMarkingNotifications)
+		return 0, &RepositoryError{
+			Operation: "marking notifications read",
+			Content:   "failed to update notifications",
+			Err:       ErrMarkingNotifications,
+		}
+	}
+	marked, err := result.RowsAffected()
+	if err != nil {
+		logrus.WithError(err).Error(ErrMarkingNotifications)
+		return 0, &RepositoryError{
+			Operation: "marking notifications read",
+			Content:   "failed to count updated notifications",
+			Err:       ErrMarkingNotifications,
+		}
+	}
+	logrus.WithField("marked", marked).Debug("marked notifications read")
+
+	return int(marked), nil
+}
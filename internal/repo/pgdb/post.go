@@ -2,6 +2,8 @@ package pgdb
 
 import (
 	"Commentary/internal/entity"
+	"Commentary/internal/pagination"
+	"Commentary/internal/repo"
 	"context"
 	"database/sql"
 	"errors"
@@ -9,30 +11,24 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
-type PostRepo interface {
-	GetPost(ctx context.Context, postID int) (*entity.Post, error)
-	AddPost(ctx context.Context, post *entity.Post) (*entity.Post, error)
-	ToggleComments(ctx context.Context, postID int) error
-	GetPostsPag(ctx context.Context, limit *int, offset *int) ([]*entity.Post, error)
-}
-
 type postRepo struct {
-	DB  *sql.DB
-	SQL squirrel.StatementBuilderType
+	DB      *sql.DB
+	SQL     squirrel.StatementBuilderType
+	ranking pagination.Ranking
 }
 
-func NewPostRepo(DB *sql.DB) PostRepo {
+func NewPostRepo(DB *sql.DB, ranking pagination.Ranking) repo.PostRepo {
 	return &postRepo{
-		DB:  DB,
-		SQL: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+		DB:      DB,
+		SQL:     squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+		ranking: ranking,
 	}
 }
 
 func (pr *postRepo) GetPost(ctx context.Context, postID int) (*entity.Post, error) {
 	logrus.Debugf("getting post by id=%d", postID)
 	statement := pr.SQL.
-		Select("id", "author_id", "title",
-			"content", "created", "commentable").
+		Select(postColumns...).
 		From("posts").
 		Where(squirrel.Eq{"id": postID})
 
@@ -46,9 +42,7 @@ func (pr *postRepo) GetPost(ctx context.Context, postID int) (*entity.Post, erro
 		}
 	}
 
-	var post entity.Post
-	err = pr.DB.QueryRowContext(ctx, query, args...).Scan(&post.ID, &post.AuthorID, &post.Title,
-		&post.Content, &post.Created, &post.Commentable)
+	post, err := scanPost(pr.DB.QueryRowContext(ctx, query, args...))
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -56,7 +50,7 @@ func (pr *postRepo) GetPost(ctx context.Context, postID int) (*entity.Post, erro
 			return nil, &RepositoryError{
 				Operation: "getting post",
 				Content:   "post record not found",
-				Err:       err,
+				Err:       ErrPostNotFound,
 			}
 		}
 		logrus.WithError(err).Error("error getting post")
@@ -68,7 +62,7 @@ func (pr *postRepo) GetPost(ctx context.Context, postID int) (*entity.Post, erro
 	}
 
 	logrus.WithField("postID", post.ID).Debug("got post")
-	return &post, nil
+	return post, nil
 }
 
 func (pr *postRepo) AddPost(ctx context.Context, post *entity.Post) (*entity.Post, error) {
@@ -135,30 +129,26 @@ func (pr *postRepo) ToggleComments(ctx context.Context, postID int) error {
 	return nil
 }
 
-func (pr *postRepo) GetPostsPag(ctx context.Context, limit *int, offset *int) ([]*entity.Post, error) {
+func (pr *postRepo) GetPostsPag(ctx context.Context, page pagination.Page,
+	withHidden bool) ([]*entity.Post, error) {
 	logrus.Debugf("getting posts paginated")
 
-	statement := pr.SQL.
-		Select("id", "author_id", "title", "content", "created", "commentable").
-		From("posts")
-
-	if limit != nil && *limit <= 0 {
-		return nil, errors.New("wrong limit parameter value")
+	statement := pr.SQL.Select(withScore(postColumns, page.Sort)...)
+	if page.Sort.Ranked() {
+		scored := ranked(pr.SQL.Select(columnList("p", postColumns)).From("posts p"),
+			pr.ranking, page, "p", "post_id", "post_id")
+		statement = statement.FromSelect(scored, "scored")
+	} else {
+		statement = statement.From("posts")
 	}
-	if offset != nil && *offset < 0 {
-		return nil, errors.New("wrong offset parameter value")
-	}
-
-	if limit != nil {
-		statement = statement.Limit(uint64(*limit))
-	}
-	if offset != nil {
-		statement = statement.Offset(uint64(*offset))
+	if !withHidden {
+		statement = statement.Where(squirrel.Eq{"hidden": false})
 	}
+	statement = paginate(statement, page)
 
 	query, args, err := statement.ToSql()
 	if err != nil {
-		logrus.WithError(err).Error("failed to generate SQL query for AddPost")
+		logrus.WithError(err).Error("failed to generate SQL query for GetPostsPag")
 		return nil, &RepositoryError{
 			Operation: "getting posts",
 			Content:   "failed to generate SQL query",
@@ -179,10 +169,7 @@ func (pr *postRepo) GetPostsPag(ctx context.Context, limit *int, offset *int) ([
 
 	var posts []*entity.Post
 	for rows.Next() {
-		var post entity.Post
-		err = rows.Scan(&post.ID, &post.AuthorID, &post.Title,
-			&post.Content, &post.Created, &post.Commentable,
-		)
+		post, err := scanPostScored(rows, page.Sort.Ranked())
 		if err != nil {
 			logrus.WithError(err).Error("failed to scan data")
 			return nil, &RepositoryError{
@@ -191,7 +178,7 @@ func (pr *postRepo) GetPostsPag(ctx context.Context, limit *int, offset *int) ([
 				Err:       err,
 			}
 		}
-		posts = append(posts, &post)
+		posts = append(posts, post)
 	}
 
 	if err = rows.Err(); err != nil {
@@ -203,14 +190,144 @@ func (pr *postRepo) GetPostsPag(ctx context.Context, limit *int, offset *int) ([
 		}
 	}
 
-	if len(posts) == 0 {
+	logrus.Debug("got posts paginated")
+
+	return posts, nil
+}
+
+func (pr *postRepo) GetPostsByIDs(ctx context.Context, ids []int) ([]*entity.Post, error) {
+	logrus.WithField("ids", ids).Debug("getting posts by IDs")
+
+	statement := pr.SQL.
+		Select(postColumns...).
+		From("posts").
+		Where(squirrel.Eq{"id": ids})
+
+	query, args, err := statement.ToSql()
+	if err != nil {
+		logrus.WithError(err).Error("failed to generate SQL query for GetPostsByIDs")
 		return nil, &RepositoryError{
-			Operation: "getting posts",
-			Content:   "no posts found",
+			Operation: "getting posts by IDs",
+			Content:   "failed to generate SQL query",
+			Err:       ErrGeneratingSQL,
 		}
 	}
 
-	logrus.Debug("got posts paginated")
+	rows, err := pr.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		logrus.WithError(err).Error("failed to get posts by IDs")
+		return nil, &RepositoryError{
+			Operation: "getting posts by IDs",
+			Content:   "failed to get posts",
+			Err:       ErrGettingPosts,
+		}
+	}
+	defer rows.Close()
+
+	var posts []*entity.Post
+	for rows.Next() {
+		post, err := scanPost(rows)
+		if err != nil {
+			logrus.WithError(err).Error("failed to scan data")
+			return nil, &RepositoryError{
+				Operation: "getting posts by IDs",
+				Content:   "failed to scan row",
+				Err:       ErrGettingPosts,
+			}
+		}
+		posts = append(posts, post)
+	}
+
+	if err = rows.Err(); err != nil {
+		logrus.WithError(err).Error("rows error")
+		return nil, &RepositoryError{
+			Operation: "getting posts by IDs",
+			Content:   "rows error",
+			Err:       ErrGettingPosts,
+		}
+	}
+
+	logrus.Debug("got posts by IDs")
 
 	return posts, nil
 }
+
+func (pr *postRepo) UpdatePost(ctx context.Context, post *entity.Post) error {
+	logrus.WithField("postID", post.ID).Debug("updating post")
+
+	statement := pr.SQL.
+		Update("posts").
+		Set("title", post.Title).
+		Set("content", post.Content).
+		Set("edited", post.Edited).
+		Where(squirrel.Eq{"id": post.ID})
+
+	query, args, err := statement.ToSql()
+	if err != nil {
+		logrus.WithError(err).Error("failed to generate SQL query for UpdatePost")
+		return &RepositoryError{
+			Operation: "updating post",
+			Content:   "failed to generate SQL query",
+			Err:       ErrGeneratingSQL,
+		}
+	}
+
+	result, err := pr.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		logrus.WithError(err).Error(ErrUpdatingPost)
+		return &RepositoryError{
+			Operation: "updating post",
+			Content:   "failed to update post",
+			Err:       ErrUpdatingPost,
+		}
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return &RepositoryError{
+			Operation: "updating post",
+			Content:   "post record not found",
+			Err:       ErrPostNotFound,
+		}
+	}
+	logrus.WithField("postID", post.ID).Debug("updated post")
+
+	return nil
+}
+
+// DeletePost removes the post, its comments are removed by the foreign key cascade.
+func (pr *postRepo) DeletePost(ctx context.Context, postID int) error {
+	logrus.WithField("postID", postID).Debug("deleting post")
+
+	statement := pr.SQL.
+		Delete("posts").
+		Where(squirrel.Eq{"id": postID})
+
+	query, args, err := statement.ToSql()
+	if err != nil {
+		logrus.WithError(err).Error("failed to generate SQL query for DeletePost")
+		return &RepositoryError{
+			Operation: "deleting post",
+			Content:   "failed to generate SQL query",
+			Err:       ErrGeneratingSQL,
+		}
+	}
+
+	result, err := pr.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		logrus.WithError(err).Error(ErrDeletingPost)
+		return &RepositoryError{
+			Operation: "deleting post",
+			Content:   "failed to delete post",
+			Err:       ErrDeletingPost,
+		}
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return &RepositoryError{
+			Operation: "deleting post",
+			Content:   "post record not found",
+			Err:       ErrPostNotFound,
+		}
+	}
+	logrus.WithField("postID", postID).Debug("deleted post")
+
+	return nil
+}
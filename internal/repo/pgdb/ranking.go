@@ -0,0 +1,86 @@
+package pgdb
+
+import (
+	"Commentary/internal/pagination"
+	"fmt"
+	"github.com/Masterminds/squirrel"
+	"github.com/lib/pq"
+)
+
+// ranked joins rows of the table aliased alias with their reaction counts r and reply counts c
+// and adds the score column for the page sort. reactionColumn refers the table from reactions,
+// repliesBy is the column of comments that groups the replies of a row.
+func ranked(statement squirrel.SelectBuilder, ranking pagination.Ranking, page pagination.Page,
+	alias, reactionColumn, repliesBy string) squirrel.SelectBuilder {
+	positive := "COALESCE(r.positive, 0)"
+	negative := "COALESCE(r.negative, 0)"
+	replies := "COALESCE(c.replies, 0)"
+	top := fmt.Sprintf("(%s - %s + %s)::DOUBLE PRECISION", positive, negative, replies)
+
+	switch page.Sort {
+	case pagination.SortControversial:
+		statement = statement.Column(fmt.Sprintf(
+			"CASE WHEN %[1]s > 0 AND %[2]s > 0 THEN (%[1]s + %[2]s)::DOUBLE PRECISION * LEAST(%[1]s, %[2]s) / "+
+				"GREATEST(%[1]s, %[2]s) ELSE 0 END AS score", positive, negative))
+	case pagination.SortHot:
+		statement = statement.Column(fmt.Sprintf(
+			"%s / POWER(GREATEST(EXTRACT(EPOCH FROM (?::TIMESTAMP - %s.created))::DOUBLE PRECISION / 3600, 0) + 2, 1.5) "+
+				"AS score", top, alias), page.Now)
+	default:
+		statement = statement.Column(top + " AS score")
+	}
+
+	negativeTypes := pq.Array(ranking.Negative)
+	if ranking.Negative == nil {
+		negativeTypes = pq.Array([]string{})
+	}
+
+	return statement.
+		LeftJoin(fmt.Sprintf(`(SELECT %[1]s, COUNT(*) FILTER (WHERE NOT type = ANY(?)) AS positive,
+			COUNT(*) FILTER (WHERE type = ANY(?)) AS negative
+			FROM reactions WHERE %[1]s IS NOT NULL GROUP BY %[1]s) r ON r.%[1]s = %[2]s.id`, reactionColumn, alias),
+			negativeTypes, negativeTypes).
+		LeftJoin(fmt.Sprintf(`(SELECT %[1]s, COUNT(*) AS replies
+			FROM comments WHERE %[1]s IS NOT NULL GROUP BY %[1]s) c ON c.%[1]s = %[2]s.id`, repliesBy, alias))
+}
+
+// orderBy returns the ORDER BY terms of the page sort, ties are broken by id.
+func orderBy(sort pagination.Sort) []string {
+	switch {
+	case sort.Ranked():
+		return []string{"score DESC", "id DESC"}
+	case sort == pagination.SortNewest:
+		return []string{"created DESC", "id DESC"}
+	default:
+		return []string{"created", "id"}
+	}
+}
+
+// paginate orders the statement by the page sort and skips the rows up to the page cursor.
+// Ranked statements must select the score column.
+func paginate(statement squirrel.SelectBuilder, page pagination.Page) squirrel.SelectBuilder {
+	statement = statement.OrderBy(orderBy(page.Sort)...)
+
+	if cursor := afterCursor(page); cursor != nil {
+		statement = statement.Where(cursor)
+	}
+	if limit := page.Limit(); limit != nil {
+		statement = statement.Limit(uint64(*limit))
+	}
+	return statement
+}
+
+// afterCursor selects the rows past the page cursor in the page sort, nil if the page has no cursor.
+func afterCursor(page pagination.Page) squirrel.Sqlizer {
+	after := page.After
+	switch {
+	case after == nil:
+		return nil
+	case after.Score != nil:
+		return squirrel.Expr("(score, id) < (?, ?)", *after.Score, after.ID)
+	case page.Sort == pagination.SortNewest:
+		return squirrel.Expr("(created, id) < (?, ?)", after.Created, after.ID)
+	default:
+		return squirrel.Expr("(created, id) > (?, ?)", after.Created, after.ID)
+	}
+}
@@ -0,0 +1,171 @@
+package pgdb
+
+import (
+	"Commentary/internal/entity"
+	"Commentary/internal/repo"
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/Masterminds/squirrel"
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+)
+
+// foreignKeyViolation is the PostgreSQL error code for a missing referenced row.
+const (
+	foreignKeyViolation = "23503"
+	uniqueViolation     = "23505"
+)
+
+type reactionRepo struct {
+	DB  *sql.DB
+	SQL squirrel.StatementBuilderType
+}
+
+func NewReactionRepo(DB *sql.DB) repo.ReactionRepo {
+	return &reactionRepo{
+		DB:  DB,
+		SQL: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}
+}
+
+func targetColumn(kind entity.TargetKind) string {
+	if kind == entity.TargetComment {
+		return "comment_id"
+	}
+	return "post_id"
+}
+
+// AddReaction saves the reaction, reacting twice with the same type is a no-op.
+func (rr *reactionRepo) AddReaction(ctx context.Context, reaction *entity.Reaction) error {
+	logrus.WithField("target", reaction.Target).Debug("adding reaction")
+
+	statement := rr.SQL.Insert("reactions").
+		Columns("user_id", targetColumn(reaction.Target.Kind), "type", "created").
+		Values(reaction.UserID, reaction.Target.ID, reaction.Type, reaction.Created).
+		Suffix("ON CONFLICT DO NOTHING")
+
+	query, args, err := statement.ToSql()
+	if err != nil {
+		logrus.WithError(err).Error("failed to generate SQL query for AddReaction")
+		return &RepositoryError{
+			Operation: "adding reaction",
+			Content:   "failed to generate SQL query",
+			Err:       ErrGeneratingSQL,
+		}
+	}
+
+	_, err = rr.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == foreignKeyViolation {
+			return &RepositoryError{
+				Operation: "adding reaction",
+				Content:   "reaction target not found",
+				Err:       ErrReactionTargetNotFound,
+			}
+		}
+		logrus.WithError(err).Error(ErrAddingReaction)
+		return &RepositoryError{
+			Operation: "adding reaction",
+			Content:   "failed to add reaction",
+			Err:       ErrAddingReaction,
+		}
+	}
+	logrus.WithField("target", reaction.Target).Debug("added reaction")
+
+	return nil
+}
+
+func (rr *reactionRepo) RemoveReaction(ctx context.Context, userID int, target entity.ReactionTarget,
+	reactionType string) error {
+	logrus.WithField("target", target).Debug("removing reaction")
+
+	statement := rr.SQL.Delete("reactions").
+		Where(squirrel.Eq{"user_id": userID, targetColumn(target.Kind): target.ID, "type": reactionType})
+
+	query, args, err := statement.ToSql()
+	if err != nil {
+		logrus.WithError(err).Error("failed to generate SQL query for RemoveReaction")
+		return &RepositoryError{
+			Operation: "removing reaction",
+			Content:   "failed to generate SQL query",
+			Err:       ErrGeneratingSQL,
+		}
+	}
+
+	if _, err = rr.DB.ExecContext(ctx, query, args...); err != nil {
+		logrus.WithError(err).Error(ErrRemovingReaction)
+		return &RepositoryError{
+			Operation: "removing reaction",
+			Content:   "failed to remove reaction",
+			Err:       ErrRemovingReaction,
+		}
+	}
+	logrus.WithField("target", target).Debug("removed reaction")
+
+	return nil
+}
+
+// GetReactionCounts counts reactions of every type left on the given targets. Targets and types
+// without reactions are omitted.
+func (rr *reactionRepo) GetReactionCounts(ctx context.Context, kind entity.TargetKind, ids []int,
+	viewerID int) ([]*entity.ReactionCount, error) {
+	logrus.WithField("ids", ids).Debugf("counting reactions on %s", kind)
+
+	column := targetColumn(kind)
+	statement := rr.SQL.Select(column, "type", "COUNT(*)").
+		Column("BOOL_OR(user_id = ?)", viewerID).
+		From("reactions").
+		Where(squirrel.Eq{column: ids}).
+		GroupBy(column, "type").
+		OrderBy(column, "type")
+
+	query, args, err := statement.ToSql()
+	if err != nil {
+		logrus.WithError(err).Error("failed to generate SQL query")
+		return nil, &RepositoryError{
+			Operation: "counting reactions",
+			Content:   "failed to generate SQL query",
+			Err:       ErrGeneratingSQL,
+		}
+	}
+
+	rows, err := rr.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		logrus.WithError(err).Error("failed to count reactions")
+		return nil, &RepositoryError{
+			Operation: "counting reactions",
+			Content:   "failed to count reactions",
+			Err:       ErrCountingReactions,
+		}
+	}
+	defer rows.Close()
+
+	var counts []*entity.ReactionCount
+	for rows.Next() {
+		var count entity.ReactionCount
+		err = rows.Scan(&count.TargetID, &count.Type, &count.Count, &count.ViewerReacted)
+		if err != nil {
+			logrus.WithError(err).Error("failed to scan row")
+			return nil, &RepositoryError{
+				Operation: "counting reactions",
+				Content:   "failed to scan row",
+				Err:       ErrCountingReactions,
+			}
+		}
+		counts = append(counts, &count)
+	}
+
+	if err = rows.Err(); err != nil {
+		logrus.WithError(err).Error("rows error")
+		return nil, &RepositoryError{
+			Operation: "counting reactions",
+			Content:   "rows error",
+			Err:       ErrCountingReactions,
+		}
+	}
+	logrus.Debug("counted reactions")
+
+	return counts, nil
+}
@@ -0,0 +1,154 @@
+package pgdb
+
+import (
+	"Commentary/internal/entity"
+	"Commentary/internal/pagination"
+	"Commentary/internal/repo"
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/Masterminds/squirrel"
+	"github.com/sirupsen/logrus"
+)
+
+// searchConfig is the text search configuration of the search columns. It does no stemming,
+// so that words are matched the same way as in the in-memory index.
+const searchConfig = "simple"
+
+var headlineOptions = fmt.Sprintf("StartSel=%s, StopSel=%s", entity.SnippetStart, entity.SnippetStop)
+
+type searchRepo struct {
+	DB  *sql.DB
+	SQL squirrel.StatementBuilderType
+}
+
+func NewSearchRepo(DB *sql.DB) repo.SearchRepo {
+	return &searchRepo{
+		DB:  DB,
+		SQL: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}
+}
+
+// escapeHTML wraps the text expression to escape it the same way as html.EscapeString.
+func escapeHTML(text string) string {
+	return fmt.Sprintf(`replace(replace(replace(replace(replace(%s, '&', '&amp;'), '<', '&lt;'), `+
+		`'>', '&gt;'), '"', '&#34;'), '''', '&#39;')`, text)
+}
+
+// searchIn selects hits of one kind, text is the expression the snippet is cut from. The text
+// is escaped before the matches are highlighted, so that the snippet has no tags but the highlights.
+func searchIn(kind entity.TargetKind, table, text, query string) squirrel.SelectBuilder {
+	return squirrel.Select(fmt.Sprintf("'%s' AS kind", kind), "t.id",
+		"ts_rank(t.search, q)::DOUBLE PRECISION AS rank").
+		Column(fmt.Sprintf("ts_headline('%s', %s, q, ?) AS snippet", searchConfig, escapeHTML(text)),
+			headlineOptions).
+		From(table+" t").
+		JoinClause(fmt.Sprintf("CROSS JOIN plainto_tsquery('%s', ?) q", searchConfig), query).
+		Where("t.search @@ q")
+}
+
+// Search finds posts and comments of the given kinds containing all words of the text.
+// Tombstones of deleted comments are never found, hidden posts and comments, with the comments
+// of hidden posts and under hidden comments, only withHidden.
+func (sr *searchRepo) Search(ctx context.Context, text string, kinds []entity.TargetKind, page pagination.SearchPage,
+	withHidden bool) ([]*entity.SearchHit, error) {
+	logrus.WithField("kinds", kinds).Debug("searching")
+
+	var parts []squirrel.SelectBuilder
+	for _, kind := range kinds {
+		var part squirrel.SelectBuilder
+		switch kind {
+		case entity.TargetPost:
+			part = searchIn(kind, "posts", "t.title || ' ' || t.content", text)
+		case entity.TargetComment:
+			part = searchIn(kind, "comments", "t.content", text).
+				Where(visibleThread("t", squirrel.Eq{"t.deleted": false}, withHidden))
+		default:
+			continue
+		}
+		if kind == entity.TargetPost && !withHidden {
+			part = part.Where(squirrel.Eq{"t.hidden": false})
+		}
+		parts = append(parts, part)
+	}
+	if len(parts) == 0 {
+		return nil, nil
+	}
+
+	hits := parts[0]
+	for _, part := range parts[1:] {
+		query, args, err := part.ToSql()
+		if err != nil {
+			logrus.WithError(err).Error("failed to generate SQL query for Search")
+			return nil, &RepositoryError{
+				Operation: "searching",
+				Content:   "failed to generate SQL query",
+				Err:       ErrGeneratingSQL,
+			}
+		}
+		hits = hits.Suffix("UNION ALL "+query, args...)
+	}
+
+	statement := sr.SQL.Select("kind", "id", "rank", "snippet").
+		FromSelect(hits, "hits").
+		OrderBy("rank DESC", "kind", "id DESC")
+	if after := page.After; after != nil {
+		statement = statement.Where(squirrel.Or{
+			squirrel.Lt{"rank": after.Rank},
+			squirrel.And{squirrel.Eq{"rank": after.Rank}, squirrel.Or{
+				squirrel.Gt{"kind": after.Kind},
+				squirrel.And{squirrel.Eq{"kind": after.Kind}, squirrel.Lt{"id": after.ID}},
+			}},
+		})
+	}
+	if limit := page.Limit(); limit != nil {
+		statement = statement.Limit(uint64(*limit))
+	}
+
+	query, args, err := statement.ToSql()
+	if err != nil {
+		logrus.WithError(err).Error("failed to generate SQL query for Search")
+		return nil, &RepositoryError{
+			Operation: "searching",
+			Content:   "failed to generate SQL query",
+			Err:       ErrGeneratingSQL,
+		}
+	}
+
+	rows, err := sr.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		logrus.WithError(err).Error(ErrSearching)
+		return nil, &RepositoryError{
+			Operation: "searching",
+			Content:   "failed to search",
+			Err:       ErrSearching,
+		}
+	}
+	defer rows.Close()
+
+	var result []*entity.SearchHit
+	for rows.Next() {
+		var hit entity.SearchHit
+		if err = rows.Scan(&hit.Kind, &hit.ID, &hit.Rank, &hit.Snippet); err != nil {
+			logrus.WithError(err).Error("failed to scan row")
+			return nil, &RepositoryError{
+				Operation: "searching",
+				Content:   "failed to scan row",
+				Err:       ErrSearching,
+			}
+		}
+		result = append(result, &hit)
+	}
+
+	if err = rows.Err(); err != nil {
+		logrus.WithError(err).Error("rows error")
+		return nil, &RepositoryError{
+			Operation: "searching",
+			Content:   "rows error",
+			Err:       ErrSearching,
+		}
+	}
+	logrus.WithField("count", len(result)).Debug("searched")
+
+	return result, nil
+}
@@ -1,39 +1,36 @@
 package pgdb
 
 import (
+	"Commentary/internal/entity"
 	"Commentary/internal/graph/model"
+	"Commentary/internal/repo"
 	"context"
 	"database/sql"
 	"errors"
 	"github.com/Masterminds/squirrel"
+	"github.com/lib/pq"
 	"github.com/sirupsen/logrus"
 )
 
-type UserRepo interface {
-	GetUsersByIDs(ctx context.Context, ids []int) (map[int]*model.User, error)
-	GetUserByID(ctx context.Context, id int) (*model.User, error)
-	AddUser(ctx context.Context, username string) (*model.User, error)
-}
-
 type userRepo struct {
 	DB  *sql.DB
 	SQL squirrel.StatementBuilderType
 }
 
-func NewUserRepo(DB *sql.DB) UserRepo {
+func NewUserRepo(DB *sql.DB) repo.UserRepo {
 	return &userRepo{
 		DB:  DB,
 		SQL: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
 	}
 }
 
-func (ur *userRepo) AddUser(ctx context.Context, username string) (*model.User, error) {
+func (ur *userRepo) AddUser(ctx context.Context, username, passwordHash string) (*model.User, error) {
 	logrus.WithField("username", username).Debug("adding user")
 
 	statement := ur.SQL.
 		Insert("users").
-		Columns("username").
-		Values(username).
+		Columns("username", "password_hash").
+		Values(username, passwordHash).
 		Suffix("RETURNING id")
 
 	query, args, err := statement.ToSql()
@@ -48,6 +45,14 @@ func (ur *userRepo) AddUser(ctx context.Context, username string) (*model.User,
 	var id int
 	err = ur.DB.QueryRowContext(ctx, query, args...).Scan(&id)
 	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+			return nil, &RepositoryError{
+				Operation: "adding user",
+				Content:   "username is taken",
+				Err:       ErrUserAlreadyExists,
+			}
+		}
 		logrus.WithError(err).Error("failed to add user")
 		return nil, &RepositoryError{
 			Operation: "adding user",
@@ -133,6 +138,7 @@ func (ur *userRepo) GetUserByID(ctx context.Context, id int) (*model.User, error
 			return nil, &RepositoryError{
 				Operation: "getting user by id",
 				Content:   "user not found",
+				Err:       ErrUserNotFound,
 			}
 		} else {
 			logrus.WithError(err).Error("rows error")
@@ -147,3 +153,167 @@ func (ur *userRepo) GetUserByID(ctx context.Context, id int) (*model.User, error
 	logrus.WithField("userID", dbID).Debug("successfully retrieved user")
 	return &user, nil
 }
+
+func (ur *userRepo) GetUserByUsername(ctx context.Context, username string) (*entity.User, error) {
+	logrus.WithField("username", username).Debug("getting user by username")
+	statement := ur.SQL.
+		Select("id", "username", "password_hash").
+		From("users").
+		Where(squirrel.Eq{"username": username})
+
+	query, args, err := statement.ToSql()
+	if err != nil {
+		logrus.WithError(err).Error(ErrGeneratingSQL)
+		return nil, &RepositoryError{
+			Operation: "getting user by username",
+			Content:   "failed to generate SQL query",
+			Err:       ErrGeneratingSQL,
+		}
+	}
+
+	var user entity.User
+	err = ur.DB.QueryRowContext(ctx, query, args...).Scan(&user.ID, &user.Username, &user.PasswordHash)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			logrus.WithField("username", username).Debug("user not found")
+			return nil, &RepositoryError{
+				Operation: "getting user by username",
+				Content:   "user not found",
+				Err:       ErrUserNotFound,
+			}
+		}
+		logrus.WithError(err).Error("failed to get user by username")
+		return nil, &RepositoryError{
+			Operation: "getting user by username",
+			Content:   "failed to get user",
+			Err:       ErrGettingUser,
+		}
+	}
+
+	logrus.WithField("userID", user.ID).Debug("got user by username")
+	return &user, nil
+}
+
+// GetUsersByUsernames returns the users with the given usernames, unknown usernames are skipped.
+func (ur *userRepo) GetUsersByUsernames(ctx context.Context, usernames []string) ([]*model.User, error) {
+	logrus.WithField("usernames", usernames).Debug("getting users by usernames")
+	statement := ur.SQL.
+		Select("id", "username").
+		From("users").
+		Where(squirrel.Eq{"username": usernames})
+
+	query, args, err := statement.ToSql()
+	if err != nil {
+		logrus.WithError(err).Error(ErrGeneratingSQL)
+		return nil, &RepositoryError{
+			Operation: "getting users by usernames",
+			Content:   "failed to generate SQL query",
+			Err:       ErrGeneratingSQL,
+		}
+	}
+
+	rows, err := ur.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		logrus.WithError(err).Error("failed to get users by usernames")
+		return nil, &RepositoryError{
+			Operation: "getting users by usernames",
+			Content:   "failed to get users",
+			Err:       ErrGettingUsers,
+		}
+	}
+	defer rows.Close()
+
+	var users []*model.User
+	for rows.Next() {
+		var user model.User
+		if err = rows.Scan(&user.ID, &user.Username); err != nil {
+			logrus.WithError(err).Error("failed to scan row")
+			return nil, &RepositoryError{
+				Operation: "getting users by usernames",
+				Content:   "failed to scan row",
+				Err:       ErrGettingUsers,
+			}
+		}
+		users = append(users, &user)
+	}
+
+	if err = rows.Err(); err != nil {
+		logrus.WithError(err).Error("rows error")
+		return nil, &RepositoryError{
+			Operation: "getting users by usernames",
+			Content:   "rows error",
+			Err:       ErrGettingUsers,
+		}
+	}
+	logrus.Debug("got users by usernames")
+
+	return users, nil
+}
+
+func (ur *userRepo) GetRole(ctx context.Context, id int) (entity.Role, error) {
+	logrus.WithField("userID", id).Debug("getting user role")
+
+	query, args, err := ur.SQL.Select("role").From("users").Where(squirrel.Eq{"id": id}).ToSql()
+	if err != nil {
+		logrus.WithError(err).Error("failed to generate SQL query for GetRole")
+		return "", &RepositoryError{
+			Operation: "getting user role",
+			Content:   "failed to generate SQL query",
+			Err:       ErrGeneratingSQL,
+		}
+	}
+
+	var role entity.Role
+	if err = ur.DB.QueryRowContext(ctx, query, args...).Scan(&role); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", &RepositoryError{
+				Operation: "getting user role",
+				Content:   "user not found",
+				Err:       ErrUserNotFound,
+			}
+		}
+		logrus.WithError(err).Error(ErrGettingUser)
+		return "", &RepositoryError{
+			Operation: "getting user role",
+			Content:   "failed to get role",
+			Err:       ErrGettingUser,
+		}
+	}
+	logrus.WithField("userID", id).Debug("got user role")
+
+	return role, nil
+}
+
+func (ur *userRepo) SetRole(ctx context.Context, id int, role entity.Role) error {
+	logrus.WithField("userID", id).Debugf("setting user role to %s", role)
+
+	query, args, err := ur.SQL.Update("users").Set("role", role).Where(squirrel.Eq{"id": id}).ToSql()
+	if err != nil {
+		logrus.WithError(err).Error("failed to generate SQL query for SetRole")
+		return &RepositoryError{
+			Operation: "setting user role",
+			Content:   "failed to generate SQL query",
+			Err:       ErrGeneratingSQL,
+		}
+	}
+
+	result, err := ur.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		logrus.WithError(err).Error(ErrSettingRole)
+		return &RepositoryError{
+			Operation: "setting user role",
+			Content:   "failed to update user",
+			Err:       ErrSettingRole,
+		}
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return &RepositoryError{
+			Operation: "setting user role",
+			Content:   "user record not found",
+			Err:       ErrUserNotFound,
+		}
+	}
+	logrus.WithField("userID", id).Debug("set user role")
+
+	return nil
+}
@@ -2,6 +2,7 @@ package pgdb_test
 
 import (
 	"Commentary/internal/entity"
+	"Commentary/internal/pagination"
 	"Commentary/internal/repo/pgdb"
 	"context"
 	"github.com/DATA-DOG/go-sqlmock"
@@ -13,7 +14,7 @@ import (
 
 func TestAddComment(t *testing.T) {
 	db, mock, _ := sqlmock.New()
-	repo := pgdb.NewCommentRepo(db)
+	repo := pgdb.NewCommentRepo(db, pagination.Ranking{})
 
 	mock.ExpectQuery(`INSERT INTO comments`).
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
@@ -29,31 +30,378 @@ func TestAddComment(t *testing.T) {
 	assert.Equal(t, "comment1", comment.Content)
 }
 
+func TestAddReplyChecksParent(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	repo := pgdb.NewCommentRepo(db, pagination.Ranking{})
+
+	mock.ExpectQuery(`INSERT INTO comments \(post_id,author_id,content,created,parent_id\) ` +
+		`SELECT post_id, \$1::INTEGER, \$2::TEXT, \$3::TIMESTAMP, id FROM comments ` +
+		`WHERE id = \$4 AND post_id = \$5 FOR KEY SHARE RETURNING id`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	parentID := 3
+	comment := &entity.Comment{PostID: 1, AuthorID: 1, Content: "reply", Created: time.Now(), ParentID: &parentID}
+
+	_, err := repo.AddComment(context.Background(), comment)
+	assert.ErrorIs(t, err, pgdb.ErrParentNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestGetComments(t *testing.T) {
 	db, mock, _ := sqlmock.New()
-	repo := pgdb.NewCommentRepo(db)
+	repo := pgdb.NewCommentRepo(db, pagination.Ranking{})
 
-	mock.ExpectQuery("SELECT id, post_id, author_id, content, created, parent_id FROM comments").
+	mock.ExpectQuery("SELECT id, post_id, author_id, content, created, parent_id, edited, deleted, hidden FROM comments").
 		WillReturnRows(sqlmock.NewRows([]string{"id", "post_id", "author_id",
-			"content", "created", "parent_id"}).
-			AddRow(1, 1, 1, "comment1", time.Now(), nil))
+			"content", "created", "parent_id", "edited", "deleted",
+			"hidden"}).
+			AddRow(1, 1, 1, "comment1", time.Now(), nil, nil, false, false))
 
-	comments, err := repo.GetComments(context.Background(), 1)
+	comments, err := repo.GetComments(context.Background(), 1, false)
 	require.NoError(t, err)
 	assert.Len(t, comments, 1)
 }
 
 func TestGetCommentByID(t *testing.T) {
 	db, mock, _ := sqlmock.New()
-	repo := pgdb.NewCommentRepo(db)
+	repo := pgdb.NewCommentRepo(db, pagination.Ranking{})
 
-	mock.ExpectQuery(`SELECT id, post_id, author_id, content, created, parent_id FROM comments WHERE id = \$1`).
+	mock.ExpectQuery(`SELECT id, post_id, author_id, content, created, parent_id, edited, deleted, hidden FROM comments WHERE id = \$1`).
 		WithArgs(1).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "post_id", "author_id", "content", "created", "parent_id"}).
-			AddRow(1, 1, 1, "comment1", time.Now(), nil))
+		WillReturnRows(sqlmock.NewRows([]string{"id", "post_id", "author_id", "content", "created", "parent_id", "edited", "deleted",
+			"hidden"}).
+			AddRow(1, 1, 1, "comment1", time.Now(), nil, nil, false, false))
 
 	comment, err := repo.GetCommentByID(context.Background(), 1)
 	require.NoError(t, err)
 	assert.NotNil(t, comment)
 	assert.Equal(t, "comment1", comment.Content)
 }
+
+func TestGetRootCommentsPag(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	repo := pgdb.NewCommentRepo(db, pagination.Ranking{})
+
+	first := 1
+
+	mock.ExpectQuery(`SELECT id, post_id, author_id, content, created, parent_id, edited, deleted, hidden FROM comments ` +
+		`WHERE post_id = \$1 AND parent_id IS NULL ORDER BY created, id LIMIT 2`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "post_id", "author_id", "content", "created", "parent_id", "edited", "deleted",
+			"hidden"}).
+			AddRow(1, 1, 1, "comment1", time.Now(), nil, nil, false, false).
+			AddRow(2, 1, 1, "comment2", time.Now(), nil, nil, false, false))
+
+	comments, err := repo.GetRootCommentsPag(context.Background(), 1, pagination.Page{First: &first}, true)
+	require.NoError(t, err)
+	assert.Len(t, comments, 2)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetRootCommentsPagWithoutHidden(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	repo := pgdb.NewCommentRepo(db, pagination.Ranking{})
+
+	mock.ExpectQuery(`SELECT id, post_id, author_id, content, created, parent_id, edited, deleted, hidden FROM comments `+
+		`WHERE \(post_id = \$1 AND hidden = \$2\) AND parent_id IS NULL ORDER BY created, id`).
+		WithArgs(1, false).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "post_id", "author_id", "content", "created", "parent_id", "edited", "deleted",
+			"hidden"}).
+			AddRow(1, 1, 1, "comment1", time.Now(), nil, nil, false, false))
+
+	comments, err := repo.GetRootCommentsPag(context.Background(), 1, pagination.Page{}, false)
+	require.NoError(t, err)
+	assert.Len(t, comments, 1)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetRepliesOneLevel(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	repo := pgdb.NewCommentRepo(db, pagination.Ranking{})
+
+	first := 2
+
+	mock.ExpectQuery(`SELECT id, post_id, author_id, content, created, parent_id, edited, deleted, hidden, root_id FROM \(`+
+		`SELECT id, .+, root_id, ROW_NUMBER\(\) OVER \(PARTITION BY root_id ORDER BY created, id\) AS rn FROM \(`+
+		`SELECT id, .+, parent_id AS root_id FROM comments WHERE parent_id IN \(\$1,\$2\)\) AS found\) AS ranked `+
+		`WHERE rn <= \$3 ORDER BY root_id, created, id`).
+		WithArgs(1, 2, 3).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "post_id", "author_id", "content", "created", "parent_id", "edited", "deleted",
+			"hidden", "root_id"}).
+			AddRow(3, 1, 1, "reply", time.Now(), 1, nil, false, false, 1).
+			AddRow(4, 1, 1, "other reply", time.Now(), 2, nil, false, false, 2))
+
+	replies, err := repo.GetReplies(context.Background(), []int{1, 2}, 1, pagination.Page{First: &first}, true)
+	require.NoError(t, err)
+	require.Len(t, replies[1], 1)
+	require.Len(t, replies[2], 1)
+	assert.Equal(t, 1, *replies[1][0].ParentID)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetRepliesRecursive(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	repo := pgdb.NewCommentRepo(db, pagination.Ranking{})
+
+	first := 5
+
+	mock.ExpectQuery(`FROM \(SELECT .+ FROM \(WITH RECURSIVE replies AS \(.+r.root_id, r.depth \+ 1.+WHERE r.depth < \$2\) `+
+		`SELECT id, post_id, author_id, content, created, parent_id, edited, deleted, hidden, root_id FROM replies\) AS found\) AS ranked `+
+		`WHERE rn <= \$3 ORDER BY root_id, created, id`).
+		WithArgs(sqlmock.AnyArg(), 3, 6).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "post_id", "author_id", "content", "created", "parent_id", "edited", "deleted",
+			"hidden", "root_id"}).
+			AddRow(2, 1, 1, "reply", time.Now(), 1, nil, false, false, 1).
+			AddRow(3, 1, 1, "nested reply", time.Now(), 2, nil, false, false, 1))
+
+	replies, err := repo.GetReplies(context.Background(), []int{1}, 3, pagination.Page{First: &first}, true)
+	require.NoError(t, err)
+	assert.Len(t, replies[1], 2)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetReplyCounts(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	repo := pgdb.NewCommentRepo(db, pagination.Ranking{})
+
+	mock.ExpectQuery(`SELECT parent_id, COUNT\(\*\) FROM comments WHERE parent_id IN \(\$1,\$2\) AND hidden = \$3 `+
+		`GROUP BY parent_id`).
+		WithArgs(1, 2, false).
+		WillReturnRows(sqlmock.NewRows([]string{"parent_id", "count"}).AddRow(1, 4))
+
+	counts, err := repo.GetReplyCounts(context.Background(), []int{1, 2}, false)
+	require.NoError(t, err)
+	assert.Equal(t, 4, counts[1])
+	assert.Equal(t, 0, counts[2])
+}
+
+func TestGetRootCommentsByPostIDs(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	repo := pgdb.NewCommentRepo(db, pagination.Ranking{})
+
+	first := 1
+
+	mock.ExpectQuery(`SELECT id, post_id, author_id, content, created, parent_id, edited, deleted, hidden FROM \(SELECT .+ `+
+		`ROW_NUMBER\(\) OVER \(PARTITION BY post_id ORDER BY created, id\) AS rn FROM comments `+
+		`WHERE post_id IN \(\$1,\$2\) AND parent_id IS NULL\) AS ranked WHERE rn <= \$3 ORDER BY post_id, created, id`).
+		WithArgs(1, 2, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "post_id", "author_id", "content", "created", "parent_id", "edited", "deleted",
+			"hidden"}).
+			AddRow(1, 1, 1, "comment1", time.Now(), nil, nil, false, false).
+			AddRow(2, 2, 1, "comment2", time.Now(), nil, nil, false, false))
+
+	comments, err := repo.GetRootCommentsByPostIDs(context.Background(), []int{1, 2}, pagination.Page{First: &first}, true)
+	require.NoError(t, err)
+	assert.Len(t, comments, 2)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetCommentsByIDs(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	repo := pgdb.NewCommentRepo(db, pagination.Ranking{})
+
+	mock.ExpectQuery(`SELECT id, post_id, author_id, content, created, parent_id, edited, deleted, hidden FROM comments WHERE id IN \(\$1,\$2\)`).
+		WithArgs(1, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "post_id", "author_id", "content", "created", "parent_id", "edited", "deleted",
+			"hidden"}).
+			AddRow(1, 1, 1, "comment1", time.Now(), nil, nil, false, false))
+
+	comments, err := repo.GetCommentsByIDs(context.Background(), []int{1, 2}, true)
+	require.NoError(t, err)
+	assert.Len(t, comments, 1)
+}
+
+func TestGetCommentsByIDsSkipsHiddenThreads(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	repo := pgdb.NewCommentRepo(db, pagination.Ranking{})
+
+	mock.ExpectQuery(`FROM comments WHERE \(id IN \(\$1\) AND comments.hidden = \$2 AND NOT \(`+
+		`EXISTS \(SELECT 1 FROM posts p WHERE p.id = comments.post_id AND p.hidden\)\s+`+
+		`OR EXISTS \(WITH RECURSIVE ancestors AS \(.+WHERE a.id = comments.parent_id`).
+		WithArgs(1, false).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "post_id", "author_id", "content", "created", "parent_id", "edited", "deleted",
+			"hidden"}))
+
+	comments, err := repo.GetCommentsByIDs(context.Background(), []int{1}, false)
+	require.NoError(t, err)
+	assert.Empty(t, comments)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateCommentSavesRevision(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	repo := pgdb.NewCommentRepo(db, pagination.Ranking{})
+
+	edited := time.Now()
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO comment_revisions \(comment_id,content,editor_id,edited\) `+
+		`SELECT id, content, \$1::INTEGER, \$2::TIMESTAMP FROM comments WHERE deleted = \$3 AND id = \$4`).
+		WithArgs(2, &edited, false, 1).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`UPDATE comments SET content = \$1, edited = \$2 WHERE deleted = \$3 AND id = \$4`).
+		WithArgs("edited", &edited, false, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := repo.UpdateComment(context.Background(), &entity.Comment{ID: 1, Content: "edited", Edited: &edited}, 2)
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateDeletedComment(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	repo := pgdb.NewCommentRepo(db, pagination.Ranking{})
+
+	edited := time.Now()
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO comment_revisions`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	err := repo.UpdateComment(context.Background(), &entity.Comment{ID: 1, Content: "edited", Edited: &edited}, 2)
+	assert.ErrorIs(t, err, pgdb.ErrCommentNotFound)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetRevisions(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	repo := pgdb.NewCommentRepo(db, pagination.Ranking{})
+
+	mock.ExpectQuery(`SELECT id, comment_id, content, editor_id, edited FROM comment_revisions `+
+		`WHERE comment_id IN \(\$1,\$2\) ORDER BY comment_id, edited, id`).
+		WithArgs(1, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "comment_id", "content", "editor_id", "edited"}).
+			AddRow(1, 1, "first", 1, time.Now()).
+			AddRow(2, 1, "second", 1, time.Now()))
+
+	revisions, err := repo.GetRevisions(context.Background(), []int{1, 2})
+	require.NoError(t, err)
+	require.Len(t, revisions, 2)
+	assert.Equal(t, "first", revisions[0].Content)
+}
+
+func expectDeleteCommentLock(mock sqlmock.Sqlmock, deleted, hasReplies bool) {
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT deleted FROM comments WHERE id = \$1 FOR UPDATE`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"deleted"}).AddRow(deleted))
+	if deleted {
+		return
+	}
+	mock.ExpectQuery(`SELECT EXISTS \(SELECT 1 FROM comments WHERE parent_id = \$1\)`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(hasReplies))
+}
+
+func TestDeleteCommentWithRepliesLeavesTombstone(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	repo := pgdb.NewCommentRepo(db, pagination.Ranking{})
+
+	expectDeleteCommentLock(mock, false, true)
+	mock.ExpectExec(`UPDATE comments SET content = \$1, deleted = \$2 WHERE id = \$3`).
+		WithArgs(entity.DeletedCommentContent, true, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM mentions WHERE comment_id = \$1`).
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`DELETE FROM comment_revisions WHERE comment_id = \$1`).
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	tombstoned, err := repo.DeleteComment(context.Background(), 1)
+	require.NoError(t, err)
+	assert.True(t, tombstoned)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteCommentWithoutReplies(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	repo := pgdb.NewCommentRepo(db, pagination.Ranking{})
+
+	expectDeleteCommentLock(mock, false, false)
+	mock.ExpectExec(`DELETE FROM comments WHERE id = \$1`).
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	tombstoned, err := repo.DeleteComment(context.Background(), 1)
+	require.NoError(t, err)
+	assert.False(t, tombstoned)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteCommentAlreadyDeleted(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	repo := pgdb.NewCommentRepo(db, pagination.Ranking{})
+
+	expectDeleteCommentLock(mock, true, false)
+	mock.ExpectRollback()
+
+	_, err := repo.DeleteComment(context.Background(), 1)
+	assert.ErrorIs(t, err, pgdb.ErrCommentDeleted)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteCommentNotFound(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	repo := pgdb.NewCommentRepo(db, pagination.Ranking{})
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT deleted FROM comments WHERE id = \$1 FOR UPDATE`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"deleted"}))
+	mock.ExpectRollback()
+
+	_, err := repo.DeleteComment(context.Background(), 1)
+	assert.ErrorIs(t, err, pgdb.ErrCommentNotFound)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetRootCommentsPagNewest(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	repo := pgdb.NewCommentRepo(db, pagination.Ranking{})
+
+	first := 2
+	after := &pagination.Cursor{Created: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), ID: 5}
+
+	mock.ExpectQuery(`SELECT id, post_id, author_id, content, created, parent_id, edited, deleted, hidden FROM comments `+
+		`WHERE post_id = \$1 AND parent_id IS NULL AND \(created, id\) < \(\$2, \$3\) `+
+		`ORDER BY created DESC, id DESC LIMIT 3`).
+		WithArgs(1, after.Created, after.ID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "post_id", "author_id", "content", "created", "parent_id", "edited", "deleted",
+			"hidden"}).
+			AddRow(4, 1, 1, "comment4", time.Now(), nil, nil, false, false))
+
+	comments, err := repo.GetRootCommentsPag(context.Background(), 1, pagination.Page{First: &first, After: after,
+		Sort: pagination.SortNewest}, true)
+	require.NoError(t, err)
+	assert.Len(t, comments, 1)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetCommentsSince(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	repo := pgdb.NewCommentRepo(db, pagination.Ranking{})
+
+	mock.ExpectQuery(`SELECT id, post_id, author_id, content, created, parent_id, edited, deleted, hidden FROM comments `+
+		`WHERE post_id = \$1 AND id > \$2 ORDER BY created, id LIMIT 2`).
+		WithArgs(1, 3).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "post_id", "author_id", "content", "created", "parent_id", "edited", "deleted",
+			"hidden"}).
+			AddRow(4, 1, 1, "comment4", time.Now(), nil, nil, false, false))
+
+	comments, err := repo.GetCommentsSince(context.Background(), 1, pagination.Since{CommentID: 3}, 2, true)
+	require.NoError(t, err)
+	assert.Len(t, comments, 1)
+
+	since := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`FROM comments WHERE post_id = \$1 AND created > \$2 ORDER BY created, id LIMIT 2`).
+		WithArgs(1, since).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "post_id", "author_id", "content", "created", "parent_id", "edited", "deleted",
+			"hidden"}))
+
+	comments, err = repo.GetCommentsSince(context.Background(), 1, pagination.Since{Time: since}, 2, true)
+	require.NoError(t, err)
+	assert.Empty(t, comments)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,67 @@
+package pgdb_test
+
+import (
+	"Commentary/internal/entity"
+	"Commentary/internal/repo/pgdb"
+	"context"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestReplaceMentions(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	repo := pgdb.NewMentionRepo(db)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM mentions WHERE comment_id = \$1`).
+		WithArgs(5).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO mentions \(comment_id,user_id,start,length\) `+
+		`VALUES \(\$1,\$2,\$3,\$4\),\(\$5,\$6,\$7,\$8\)`).
+		WithArgs(5, 1, 0, 6, 5, 2, 10, 4).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	target := entity.ReactionTarget{Kind: entity.TargetComment, ID: 5}
+	err := repo.ReplaceMentions(context.Background(), target, []*entity.Mention{
+		{UserID: 1, Offset: 0, Length: 6},
+		{UserID: 2, Offset: 10, Length: 4},
+	})
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReplaceMentionsWithNone(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	repo := pgdb.NewMentionRepo(db)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM mentions WHERE post_id = \$1`).
+		WithArgs(3).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	err := repo.ReplaceMentions(context.Background(), entity.ReactionTarget{Kind: entity.TargetPost, ID: 3}, nil)
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetMentions(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	repo := pgdb.NewMentionRepo(db)
+
+	mock.ExpectQuery(`SELECT post_id, user_id, start, length FROM mentions WHERE post_id IN \(\$1,\$2\) `+
+		`ORDER BY post_id, start`).
+		WithArgs(1, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"post_id", "user_id", "start", "length"}).
+			AddRow(1, 4, 0, 5).AddRow(2, 4, 3, 5))
+
+	mentions, err := repo.GetMentions(context.Background(), entity.TargetPost, []int{1, 2})
+	require.NoError(t, err)
+	require.Len(t, mentions, 2)
+	assert.Equal(t, entity.ReactionTarget{Kind: entity.TargetPost, ID: 2}, mentions[1].Target)
+	assert.Equal(t, 3, mentions[1].Offset)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,108 @@
+package pgdb_test
+
+import (
+	"Commentary/internal/entity"
+	"Commentary/internal/pagination"
+	"Commentary/internal/repo/pgdb"
+	"context"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestAddReport(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	repo := pgdb.NewModerationRepo(db)
+
+	created := time.Now()
+	reporterID := 2
+	mock.ExpectQuery(`INSERT INTO reports \(comment_id,reporter_id,reason,created\) VALUES \(\$1,\$2,\$3,\$4\) `+
+		`ON CONFLICT DO NOTHING RETURNING id`).
+		WithArgs(5, 2, "spam", created).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+
+	report := &entity.Report{Target: entity.ReactionTarget{Kind: entity.TargetComment, ID: 5}, ReporterID: &reporterID,
+		Reason: "spam", Created: created}
+	require.NoError(t, repo.AddReport(context.Background(), report))
+	assert.Equal(t, 3, report.ID)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAddReportAgain(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	repo := pgdb.NewModerationRepo(db)
+
+	reporterID := 2
+	mock.ExpectQuery(`INSERT INTO reports`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	report := &entity.Report{Target: entity.ReactionTarget{Kind: entity.TargetPost, ID: 1}, ReporterID: &reporterID,
+		Reason: "spam", Created: time.Now()}
+	require.NoError(t, repo.AddReport(context.Background(), report))
+	assert.Zero(t, report.ID)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAddFlagReport(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	repo := pgdb.NewModerationRepo(db)
+
+	mock.ExpectQuery(`INSERT INTO reports`).
+		WithArgs(1, nil, "flagged", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4))
+
+	report := &entity.Report{Target: entity.ReactionTarget{Kind: entity.TargetPost, ID: 1}, Reason: "flagged",
+		Created: time.Now()}
+	require.NoError(t, repo.AddReport(context.Background(), report))
+	assert.Equal(t, 4, report.ID)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetOpenReports(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	repo := pgdb.NewModerationRepo(db)
+
+	first := 1
+	mock.ExpectQuery(`SELECT id, post_id, comment_id, reporter_id, reason, created, resolved FROM reports ` +
+		`WHERE resolved = \$1 ORDER BY created, id LIMIT 2`).
+		WithArgs(false).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "post_id", "comment_id", "reporter_id", "reason", "created",
+			"resolved"}).
+			AddRow(1, 4, nil, 2, "spam", time.Now(), false).
+			AddRow(2, nil, 7, 3, "rude", time.Now(), false))
+
+	reports, err := repo.GetOpenReports(context.Background(), pagination.Page{First: &first})
+	require.NoError(t, err)
+	require.Len(t, reports, 2)
+	assert.Equal(t, entity.ReactionTarget{Kind: entity.TargetPost, ID: 4}, reports[0].Target)
+	assert.Equal(t, entity.ReactionTarget{Kind: entity.TargetComment, ID: 7}, reports[1].Target)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestResolveReports(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	repo := pgdb.NewModerationRepo(db)
+
+	mock.ExpectExec(`UPDATE reports SET resolved = \$1 WHERE post_id = \$2 AND resolved = \$3`).
+		WithArgs(true, 4, false).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	err := repo.ResolveReports(context.Background(), entity.ReactionTarget{Kind: entity.TargetPost, ID: 4})
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSetHiddenNotFound(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	repo := pgdb.NewModerationRepo(db)
+
+	mock.ExpectExec(`UPDATE comments SET hidden = \$1 WHERE id = \$2`).
+		WithArgs(true, 9).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err := repo.SetHidden(context.Background(), entity.ReactionTarget{Kind: entity.TargetComment, ID: 9}, true)
+	assert.ErrorIs(t, err, pgdb.ErrReactionTargetNotFound)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,91 @@
+package pgdb_test
+
+import (
+	"Commentary/internal/entity"
+	"Commentary/internal/pagination"
+	"Commentary/internal/repo/pgdb"
+	"context"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestAddNotifications(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	repo := pgdb.NewNotificationRepo(db)
+
+	created := time.Now()
+	mock.ExpectQuery(`INSERT INTO notifications \(user_id,kind,comment_id,actor_id,created\) `+
+		`VALUES \(\$1,\$2,\$3,\$4,\$5\),\(\$6,\$7,\$8,\$9,\$10\) RETURNING id`).
+		WithArgs(1, entity.NotificationReply, 5, 3, created, 2, entity.NotificationMention, 5, 3, created).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7).AddRow(8))
+
+	notifications := []*entity.Notification{
+		{UserID: 1, Kind: entity.NotificationReply, CommentID: 5, ActorID: 3, Created: created},
+		{UserID: 2, Kind: entity.NotificationMention, CommentID: 5, ActorID: 3, Created: created},
+	}
+	require.NoError(t, repo.AddNotifications(context.Background(), notifications))
+	assert.Equal(t, 7, notifications[0].ID)
+	assert.Equal(t, 8, notifications[1].ID)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetUnreadNotifications(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	repo := pgdb.NewNotificationRepo(db)
+
+	first := 1
+	after := &pagination.Cursor{Created: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), ID: 9}
+	mock.ExpectQuery(`SELECT id, user_id, kind, comment_id, actor_id, created, read FROM notifications `+
+		`WHERE user_id = \$1 AND read = \$2 AND \(created, id\) < \(\$3, \$4\) ORDER BY created DESC, id DESC LIMIT 2`).
+		WithArgs(1, false, after.Created, after.ID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "kind", "comment_id", "actor_id", "created", "read"}).
+			AddRow(8, 1, "mention", 5, 3, time.Now(), false))
+
+	notifications, err := repo.GetNotifications(context.Background(), 1, true,
+		pagination.Page{First: &first, After: after, Sort: pagination.SortNewest}, true)
+	require.NoError(t, err)
+	require.Len(t, notifications, 1)
+	assert.Equal(t, entity.NotificationMention, notifications[0].Kind)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetNotificationsWithoutHidden(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	repo := pgdb.NewNotificationRepo(db)
+
+	mock.ExpectQuery(`SELECT id, user_id, kind, comment_id, actor_id, created, read FROM notifications ` +
+		`WHERE user_id = \$1 AND EXISTS \(SELECT 1 FROM comments c\s+WHERE c.id = notifications.comment_id ` +
+		`AND NOT c.hidden AND NOT \(EXISTS \(SELECT 1 FROM posts p WHERE p.id = c.post_id AND p.hidden\)`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "kind", "comment_id", "actor_id", "created", "read"}))
+
+	notifications, err := repo.GetNotifications(context.Background(), 1, false,
+		pagination.Page{Sort: pagination.SortNewest}, false)
+	require.NoError(t, err)
+	assert.Empty(t, notifications)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMarkNotificationsRead(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	repo := pgdb.NewNotificationRepo(db)
+
+	mock.ExpectExec(`UPDATE notifications SET read = \$1 WHERE read = \$2 AND user_id = \$3 AND id IN \(\$4,\$5\)`).
+		WithArgs(true, false, 1, 7, 8).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(`UPDATE notifications SET read = \$1 WHERE read = \$2 AND user_id = \$3$`).
+		WithArgs(true, false, 1).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	marked, err := repo.MarkNotificationsRead(context.Background(), 1, []int{7, 8})
+	require.NoError(t, err)
+	assert.Equal(t, 2, marked)
+
+	marked, err = repo.MarkNotificationsRead(context.Background(), 1, nil)
+	require.NoError(t, err)
+	assert.Zero(t, marked)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -2,6 +2,7 @@ package pgdb
 
 import (
 	"Commentary/internal/entity"
+	"Commentary/internal/pagination"
 	"Commentary/internal/repo/pgdb"
 	"context"
 	"github.com/DATA-DOG/go-sqlmock"
@@ -13,12 +14,13 @@ import (
 
 func TestGetPost(t *testing.T) {
 	db, mock, _ := sqlmock.New()
-	repo := pgdb.NewPostRepo(db)
+	repo := pgdb.NewPostRepo(db, pagination.Ranking{})
 
-	mock.ExpectQuery(`SELECT id, author_id, title, content, created, commentable FROM posts WHERE id = \$1`).
+	mock.ExpectQuery(`SELECT id, author_id, title, content, created, commentable, edited, hidden FROM posts WHERE id = \$1`).
 		WithArgs(1).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "author_id", "title", "content", "created", "commentable"}).
-			AddRow(1, 1, "title", "content", time.Now(), true))
+		WillReturnRows(sqlmock.NewRows([]string{"id", "author_id", "title", "content", "created", "commentable", "edited",
+			"hidden"}).
+			AddRow(1, 1, "title", "content", time.Now(), true, nil, false))
 
 	post, err := repo.GetPost(context.Background(), 1)
 	require.NoError(t, err)
@@ -29,7 +31,7 @@ func TestGetPost(t *testing.T) {
 
 func TestAddPost(t *testing.T) {
 	db, mock, _ := sqlmock.New()
-	repo := pgdb.NewPostRepo(db)
+	repo := pgdb.NewPostRepo(db, pagination.Ranking{})
 
 	mock.ExpectQuery(`INSERT INTO posts`).
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
@@ -49,7 +51,7 @@ func TestAddPost(t *testing.T) {
 
 func TestToggleComments(t *testing.T) {
 	db, mock, _ := sqlmock.New()
-	repo := pgdb.NewPostRepo(db)
+	repo := pgdb.NewPostRepo(db, pagination.Ranking{})
 
 	mock.ExpectExec(`UPDATE posts SET commentable = NOT commentable WHERE id = \$1`).
 		WithArgs(1).
@@ -58,3 +60,94 @@ func TestToggleComments(t *testing.T) {
 	err := repo.ToggleComments(context.Background(), 1)
 	require.NoError(t, err)
 }
+
+func TestGetPostsPagAfterCursor(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	repo := pgdb.NewPostRepo(db, pagination.Ranking{})
+
+	first := 2
+	after := &pagination.Cursor{Created: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), ID: 3}
+
+	mock.ExpectQuery(`SELECT id, author_id, title, content, created, commentable, edited, hidden FROM posts `+
+		`WHERE \(created, id\) > \(\$1, \$2\) ORDER BY created, id LIMIT 3`).
+		WithArgs(after.Created, after.ID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "author_id", "title", "content", "created", "commentable", "edited",
+			"hidden"}).
+			AddRow(4, 1, "title4", "content4", time.Now(), true, nil, false).
+			AddRow(5, 1, "title5", "content5", time.Now(), true, nil, false))
+
+	posts, err := repo.GetPostsPag(context.Background(), pagination.Page{First: &first, After: after}, true)
+	require.NoError(t, err)
+	assert.Len(t, posts, 2)
+	assert.Equal(t, 4, posts[0].ID)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetPostsByIDs(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	repo := pgdb.NewPostRepo(db, pagination.Ranking{})
+
+	mock.ExpectQuery(`SELECT id, author_id, title, content, created, commentable, edited, hidden FROM posts WHERE id IN \(\$1,\$2\)`).
+		WithArgs(1, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "author_id", "title", "content", "created", "commentable", "edited",
+			"hidden"}).
+			AddRow(1, 1, "title1", "content1", time.Now(), true, nil, false).
+			AddRow(2, 1, "title2", "content2", time.Now(), false, nil, false))
+
+	posts, err := repo.GetPostsByIDs(context.Background(), []int{1, 2})
+	require.NoError(t, err)
+	assert.Len(t, posts, 2)
+}
+
+func TestUpdatePost(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	repo := pgdb.NewPostRepo(db, pagination.Ranking{})
+
+	edited := time.Now()
+	mock.ExpectExec(`UPDATE posts SET title = \$1, content = \$2, edited = \$3 WHERE id = \$4`).
+		WithArgs("new title", "new content", &edited, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := repo.UpdatePost(context.Background(), &entity.Post{ID: 1, Title: "new title",
+		Content: "new content", Edited: &edited})
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeletePostNotFound(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	repo := pgdb.NewPostRepo(db, pagination.Ranking{})
+
+	mock.ExpectExec(`DELETE FROM posts WHERE id = \$1`).
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err := repo.DeletePost(context.Background(), 1)
+	assert.ErrorIs(t, err, pgdb.ErrPostNotFound)
+}
+
+func TestGetPostsPagTop(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	repo := pgdb.NewPostRepo(db, pagination.Ranking{Negative: []string{"dislike"}})
+
+	first := 1
+	score := 5.0
+	after := &pagination.Cursor{ID: 7, Score: &score, RankedAt: time.Now()}
+
+	mock.ExpectQuery(`SELECT id, author_id, title, content, created, commentable, edited, hidden, score FROM \(SELECT p.id, .+ `+
+		`AS score FROM posts p LEFT JOIN \(SELECT post_id, .+ FROM reactions .+\) r ON r.post_id = p.id `+
+		`LEFT JOIN \(SELECT post_id, COUNT\(\*\) AS replies .+\) c ON c.post_id = p.id\) AS scored `+
+		`WHERE \(score, id\) < \(\$3, \$4\) ORDER BY score DESC, id DESC LIMIT 2`).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), score, after.ID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "author_id", "title", "content", "created", "commentable",
+			"edited", "hidden", "score"}).
+			AddRow(9, 1, "title9", "content9", time.Now(), true, nil, false, 5.0).
+			AddRow(3, 1, "title3", "content3", time.Now(), true, nil, false, 2.0))
+
+	posts, err := repo.GetPostsPag(context.Background(), pagination.Page{First: &first, After: after,
+		Sort: pagination.SortTop}, true)
+	require.NoError(t, err)
+	require.Len(t, posts, 2)
+	assert.Equal(t, 5.0, posts[0].Score)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
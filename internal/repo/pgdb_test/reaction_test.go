@@ -0,0 +1,65 @@
+package pgdb_test
+
+import (
+	"Commentary/internal/entity"
+	"Commentary/internal/repo/pgdb"
+	"context"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestAddReaction(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	repo := pgdb.NewReactionRepo(db)
+
+	created := time.Now()
+	mock.ExpectExec(`INSERT INTO reactions \(user_id,comment_id,type,created\) VALUES \(\$1,\$2,\$3,\$4\) ON CONFLICT DO NOTHING`).
+		WithArgs(1, 2, "like", created).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	err := repo.AddReaction(context.Background(), &entity.Reaction{
+		UserID:  1,
+		Target:  entity.ReactionTarget{Kind: entity.TargetComment, ID: 2},
+		Type:    "like",
+		Created: created,
+	})
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAddReactionMissingTarget(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	repo := pgdb.NewReactionRepo(db)
+
+	mock.ExpectExec(`INSERT INTO reactions`).
+		WillReturnError(&pq.Error{Code: "23503"})
+
+	err := repo.AddReaction(context.Background(), &entity.Reaction{
+		UserID: 1,
+		Target: entity.ReactionTarget{Kind: entity.TargetPost, ID: 5},
+		Type:   "like",
+	})
+	assert.ErrorIs(t, err, pgdb.ErrReactionTargetNotFound)
+}
+
+func TestGetReactionCounts(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	repo := pgdb.NewReactionRepo(db)
+
+	mock.ExpectQuery(`SELECT post_id, type, COUNT\(\*\), BOOL_OR\(user_id = \$1\) FROM reactions `+
+		`WHERE post_id IN \(\$2,\$3\) GROUP BY post_id, type ORDER BY post_id, type`).
+		WithArgs(7, 1, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"post_id", "type", "count", "bool_or"}).
+			AddRow(1, "heart", 1, false).
+			AddRow(1, "like", 3, true))
+
+	counts, err := repo.GetReactionCounts(context.Background(), entity.TargetPost, []int{1, 2}, 7)
+	require.NoError(t, err)
+	require.Len(t, counts, 2)
+	assert.Equal(t, 3, counts[1].Count)
+	assert.True(t, counts[1].ViewerReacted)
+}
@@ -0,0 +1,47 @@
+package pgdb_test
+
+import (
+	"Commentary/internal/entity"
+	"Commentary/internal/pagination"
+	"Commentary/internal/repo/pgdb"
+	"context"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestSearch(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	repo := pgdb.NewSearchRepo(db)
+
+	first := 1
+	after := &pagination.SearchCursor{Rank: 0.5, Kind: "comment", ID: 3}
+
+	mock.ExpectQuery(`SELECT kind, id, rank, snippet FROM \(`+
+		`SELECT 'post' AS kind, t.id, ts_rank\(t.search, q\)::DOUBLE PRECISION AS rank, `+
+		`ts_headline\('simple', replace\(replace\(replace\(replace\(replace\(t.title \|\| ' ' \|\| t.content, `+
+		`'&', '&amp;'\), '<', '&lt;'\), '>', '&gt;'\), '"', '&#34;'\), '''', '&#39;'\), q, \$1\) AS snippet `+
+		`FROM posts t `+
+		`CROSS JOIN plainto_tsquery\('simple', \$2\) q WHERE t.search @@ q AND t.hidden = \$3 `+
+		`UNION ALL SELECT 'comment' AS kind, .+ FROM comments t `+
+		`CROSS JOIN plainto_tsquery\('simple', \$5\) q WHERE t.search @@ q AND \(t.deleted = \$6 `+
+		`AND t.hidden = \$7 AND NOT \(EXISTS \(SELECT 1 FROM posts p WHERE p.id = t.post_id AND p.hidden\).+`+
+		`WHERE a.id = t.parent_id .+\)\)\)\) AS hits `+
+		`WHERE \(rank < \$8 OR \(rank = \$9 AND \(kind > \$10 OR \(kind = \$11 AND id < \$12\)\)\)\) `+
+		`ORDER BY rank DESC, kind, id DESC LIMIT 2`).
+		WithArgs(sqlmock.AnyArg(), "migration", false, sqlmock.AnyArg(), "migration", false, false,
+			0.5, 0.5, "comment", "comment", 3).
+		WillReturnRows(sqlmock.NewRows([]string{"kind", "id", "rank", "snippet"}).
+			AddRow("post", 1, 0.5, "the <mark>migration</mark>").
+			AddRow("comment", 2, 0.1, "<mark>migration</mark>"))
+
+	hits, err := repo.Search(context.Background(), "migration",
+		[]entity.TargetKind{entity.TargetPost, entity.TargetComment},
+		pagination.SearchPage{First: &first, After: after}, false)
+	require.NoError(t, err)
+	require.Len(t, hits, 2)
+	assert.Equal(t, entity.TargetPost, hits[0].Kind)
+	assert.Equal(t, "the <mark>migration</mark>", hits[0].Snippet)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
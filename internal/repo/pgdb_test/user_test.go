@@ -1,9 +1,12 @@
 package pgdb
 
 import (
+	"Commentary/internal/entity"
 	"Commentary/internal/repo/pgdb"
 	"context"
+	"database/sql"
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"testing"
@@ -15,13 +18,23 @@ func TestAddUser(t *testing.T) {
 
 	mock.ExpectQuery("INSERT INTO users").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
 
-	user, err := repo.AddUser(context.Background(), "user1")
+	user, err := repo.AddUser(context.Background(), "user1", "hash")
 	require.NoError(t, err)
 	assert.NotNil(t, user)
 	assert.Equal(t, "user1", user.Username)
 	assert.Equal(t, 1, user.ID)
 }
 
+func TestAddUserDuplicate(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	repo := pgdb.NewUserRepo(db)
+
+	mock.ExpectQuery("INSERT INTO users").WillReturnError(&pq.Error{Code: "23505"})
+
+	_, err := repo.AddUser(context.Background(), "user1", "hash")
+	assert.ErrorIs(t, err, pgdb.ErrUserAlreadyExists)
+}
+
 func TestGetUserByID(t *testing.T) {
 	db, mock, _ := sqlmock.New()
 	repo := pgdb.NewUserRepo(db)
@@ -36,3 +49,37 @@ func TestGetUserByID(t *testing.T) {
 	assert.Equal(t, "user1", user.Username)
 	assert.Equal(t, 1, user.ID)
 }
+
+func TestGetUserByUsername(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	repo := pgdb.NewUserRepo(db)
+
+	mock.ExpectQuery(`SELECT id, username, password_hash FROM users WHERE username = \$1`).
+		WithArgs("user1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "password_hash"}).AddRow(1, "user1", "hash"))
+
+	user, err := repo.GetUserByUsername(context.Background(), "user1")
+	require.NoError(t, err)
+	assert.Equal(t, 1, user.ID)
+	assert.Equal(t, "hash", user.PasswordHash)
+
+	mock.ExpectQuery(`SELECT id, username, password_hash FROM users WHERE username = \$1`).
+		WithArgs("user2").
+		WillReturnError(sql.ErrNoRows)
+
+	_, err = repo.GetUserByUsername(context.Background(), "user2")
+	assert.ErrorIs(t, err, pgdb.ErrUserNotFound)
+}
+
+func TestSetRoleOfMissingUser(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	repo := pgdb.NewUserRepo(db)
+
+	mock.ExpectExec(`UPDATE users SET role = \$1 WHERE id = \$2`).
+		WithArgs(entity.RoleModerator, 404).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err := repo.SetRole(context.Background(), 404, entity.RoleModerator)
+	assert.ErrorIs(t, err, pgdb.ErrUserNotFound)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
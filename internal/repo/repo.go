@@ -0,0 +1,80 @@
+package repo
+
+import (
+	"Commentary/internal/entity"
+	"Commentary/internal/graph/model"
+	"Commentary/internal/pagination"
+	"context"
+)
+
+type PostRepo interface {
+	GetPost(ctx context.Context, postID int) (*entity.Post, error)
+	AddPost(ctx context.Context, post *entity.Post) (*entity.Post, error)
+	ToggleComments(ctx context.Context, postID int) error
+	GetPostsPag(ctx context.Context, page pagination.Page, withHidden bool) ([]*entity.Post, error)
+	GetPostsByIDs(ctx context.Context, ids []int) ([]*entity.Post, error)
+	UpdatePost(ctx context.Context, post *entity.Post) error
+	DeletePost(ctx context.Context, postID int) error
+}
+
+type CommentRepo interface {
+	GetComments(ctx context.Context, postID int, withHidden bool) ([]*entity.Comment, error)
+	GetRootCommentsPag(ctx context.Context, postID int, page pagination.Page,
+		withHidden bool) ([]*entity.Comment, error)
+	GetRootCommentsByPostIDs(ctx context.Context, postIDs []int, page pagination.Page,
+		withHidden bool) ([]*entity.Comment, error)
+	AddComment(ctx context.Context, comment *entity.Comment) (int, error)
+	GetCommentByID(ctx context.Context, id int) (*entity.Comment, error)
+	GetCommentsByIDs(ctx context.Context, ids []int, withHidden bool) ([]*entity.Comment, error)
+	GetReplies(ctx context.Context, parentIDs []int, depth int, page pagination.Page,
+		withHidden bool) (map[int][]*entity.Comment, error)
+	GetReplyCounts(ctx context.Context, parentIDs []int, withHidden bool) (map[int]int, error)
+	UpdateComment(ctx context.Context, comment *entity.Comment, editorID int) error
+	GetRevisions(ctx context.Context, commentIDs []int) ([]*entity.CommentRevision, error)
+	DeleteComment(ctx context.Context, id int) (bool, error)
+	GetCommentsSince(ctx context.Context, postID int, since pagination.Since, limit int,
+		withHidden bool) ([]*entity.Comment, error)
+}
+
+type UserRepo interface {
+	GetUsersByIDs(ctx context.Context, ids []int) (map[int]*model.User, error)
+	GetUserByID(ctx context.Context, id int) (*model.User, error)
+	GetUserByUsername(ctx context.Context, username string) (*entity.User, error)
+	AddUser(ctx context.Context, username, passwordHash string) (*model.User, error)
+	GetUsersByUsernames(ctx context.Context, usernames []string) ([]*model.User, error)
+	GetRole(ctx context.Context, id int) (entity.Role, error)
+	SetRole(ctx context.Context, id int, role entity.Role) error
+}
+
+type ReactionRepo interface {
+	AddReaction(ctx context.Context, reaction *entity.Reaction) error
+	RemoveReaction(ctx context.Context, userID int, target entity.ReactionTarget, reactionType string) error
+	GetReactionCounts(ctx context.Context, kind entity.TargetKind, ids []int,
+		viewerID int) ([]*entity.ReactionCount, error)
+}
+
+type SearchRepo interface {
+	Search(ctx context.Context, text string, kinds []entity.TargetKind, page pagination.SearchPage,
+		withHidden bool) ([]*entity.SearchHit, error)
+}
+
+type NotificationRepo interface {
+	AddNotifications(ctx context.Context, notifications []*entity.Notification) error
+	// GetNotifications leaves out the notifications about comments hidden by moderators, with their
+	// post or a comment above them, unless withHidden is set.
+	GetNotifications(ctx context.Context, userID int, unreadOnly bool, page pagination.Page,
+		withHidden bool) ([]*entity.Notification, error)
+	MarkNotificationsRead(ctx context.Context, userID int, ids []int) (int, error)
+}
+
+type MentionRepo interface {
+	ReplaceMentions(ctx context.Context, target entity.ReactionTarget, mentions []*entity.Mention) error
+	GetMentions(ctx context.Context, kind entity.TargetKind, ids []int) ([]*entity.Mention, error)
+}
+
+type ModerationRepo interface {
+	AddReport(ctx context.Context, report *entity.Report) error
+	GetOpenReports(ctx context.Context, page pagination.Page) ([]*entity.Report, error)
+	ResolveReports(ctx context.Context, target entity.ReactionTarget) error
+	SetHidden(ctx context.Context, target entity.ReactionTarget, hidden bool) error
+}
@@ -0,0 +1,18 @@
+package service
+
+import (
+	"Commentary/internal/auth"
+	"context"
+)
+
+// CheckAuthor allows modifying content only to its author.
+func CheckAuthor(ctx context.Context, authorID int) error {
+	viewerID, err := auth.ViewerID(ctx)
+	if err != nil {
+		return err
+	}
+	if viewerID != authorID {
+		return ErrNotAuthor
+	}
+	return nil
+}
@@ -1,240 +1,436 @@
 package service
 
 import (
+	"Commentary/internal/auth"
 	"Commentary/internal/common"
 	"Commentary/internal/entity"
+	"Commentary/internal/filter"
 	"Commentary/internal/graph/model"
+	"Commentary/internal/pagination"
 	"Commentary/internal/pubsub"
-	"Commentary/internal/repo/pgdb"
+	"Commentary/internal/repo"
 	"context"
-	"errors"
-	"fmt"
-	"sync"
+	"github.com/sirupsen/logrus"
 	"time"
 )
 
+// MaxCommentLength is the number of characters a comment may take.
+const MaxCommentLength = 2000
+
 type commentService struct {
-	commentRepo pgdb.CommentRepo
-	postRepo    pgdb.PostRepo
-	userRepo    pgdb.UserRepo
-	postService common.PostService
-	broker      *pubsub.Broker
+	commentRepo   repo.CommentRepo
+	postService   common.PostService
+	notifications common.NotificationService
+	mentions      common.MentionService
+	moderation    common.ModerationService
+	filters       *filter.Pipeline
+	broker        pubsub.Broker
 }
 
-func NewCommentService(commentRepo pgdb.CommentRepo, postRepo pgdb.PostRepo, userRepo pgdb.UserRepo,
-	postService common.PostService, broker *pubsub.Broker) common.CommentService {
-	return &commentService{
-		commentRepo: commentRepo, postRepo: postRepo, userRepo: userRepo,
-		postService: postService, broker: broker,
-	}
+func NewCommentService(commentRepo repo.CommentRepo, postService common.PostService,
+	notifications common.NotificationService, mentions common.MentionService,
+	moderation common.ModerationService, filters *filter.Pipeline, broker pubsub.Broker) common.CommentService {
+	return &commentService{commentRepo: commentRepo, postService: postService, notifications: notifications,
+		mentions: mentions, moderation: moderation, filters: filters, broker: broker}
 }
 
-func (cs *commentService) GetComments(ctx context.Context, postID int, limit *int, offset *int) ([]*model.Comment, error) {
-	roots, comments, authors, post, err := cs.getCommentsData(ctx, postID, limit, offset)
+func (cs *commentService) GetComments(ctx context.Context, postID int, first *int, after *string,
+	sort model.SortOrder) (*model.CommentConnection, error) {
+	page, err := pagination.NewSortedPage(first, after, pagination.Sort(sort))
+	if err != nil {
+		return nil, err
+	}
+	withHidden, err := cs.moderation.IsModerator(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	roots, err := cs.commentRepo.GetRootCommentsPag(ctx, postID, page, withHidden)
 	if err != nil {
 		return nil, err
 	}
+	roots, hasNext := pagination.Trim(roots, page)
 
-	return FillComments(roots, comments, authors, post), nil
+	edges, pageInfo := newConnection(CommentsToModels(roots), page.After != nil, hasNext, commentEdge(page))
+	return &model.CommentConnection{Edges: edges, PageInfo: pageInfo}, nil
 }
 
-func (cs *commentService) getCommentsData(ctx context.Context, postID int, limit, offset *int) ([]*entity.Comment,
-	[]*entity.Comment, map[int]*model.User, *model.Post, error) {
+func (cs *commentService) GetCommentsByPostIDs(ctx context.Context, postIDs []int,
+	first *int, sort model.SortOrder) (map[int]*model.CommentConnection, error) {
+	page, err := pagination.NewSortedPage(first, nil, pagination.Sort(sort))
+	if err != nil {
+		return nil, err
+	}
+	withHidden, err := cs.moderation.IsModerator(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-	var wg sync.WaitGroup
-	var errs [3]error
-	var roots, allComments []*entity.Comment
-	var post *entity.Post
+	roots, err := cs.commentRepo.GetRootCommentsByPostIDs(ctx, postIDs, page, withHidden)
+	if err != nil {
+		return nil, err
+	}
 
-	wg.Add(3)
+	return GroupCommentConnections(postIDs, roots, page), nil
+}
 
-	go func() {
-		defer wg.Done()
-		allComments, errs[0] = cs.commentRepo.GetComments(ctx, postID)
-	}()
+func (cs *commentService) GetReplies(ctx context.Context, parentIDs []int, first *int, after *string,
+	depth int) (map[int]*model.CommentConnection, error) {
+	if depth < 1 {
+		return nil, ErrWrongDepth
+	}
+	page, err := pagination.NewPage(first, after)
+	if err != nil {
+		return nil, err
+	}
+	withHidden, err := cs.moderation.IsModerator(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-	go func() {
-		defer wg.Done()
-		roots, errs[1] = cs.commentRepo.GetRootCommentsPag(ctx, postID, limit, offset)
-	}()
+	replies, err := cs.commentRepo.GetReplies(ctx, parentIDs, depth, page, withHidden)
+	if err != nil {
+		return nil, err
+	}
 
-	go func() {
-		defer wg.Done()
-		post, errs[2] = cs.postRepo.GetPost(ctx, postID)
-	}()
+	result := make(map[int]*model.CommentConnection, len(parentIDs))
+	for _, parentID := range parentIDs {
+		comments, hasNext := pagination.Trim(replies[parentID], page)
+		edges, pageInfo := newConnection(CommentsToModels(comments), page.After != nil, hasNext, commentEdge(page))
+		result[parentID] = &model.CommentConnection{Edges: edges, PageInfo: pageInfo}
+	}
+	return result, nil
+}
 
-	wg.Wait()
+func (cs *commentService) GetReplyCounts(ctx context.Context, ids []int) (map[int]int, error) {
+	withHidden, err := cs.moderation.IsModerator(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-	for _, err := range errs {
-		if err != nil {
-			return nil, nil, nil, nil, err
-		}
+	counts, err := cs.commentRepo.GetReplyCounts(ctx, ids, withHidden)
+	if err != nil {
+		return nil, err
 	}
 
-	authorIDs := GetAuthorIDs(allComments)
-	authorIDs = append(authorIDs, post.AuthorID)
+	return FillReplyCounts(ids, counts), nil
+}
 
-	authors, err := cs.userRepo.GetUsersByIDs(ctx, authorIDs)
+// GetCommentsByIDs leaves hidden comments out unless the viewer is a moderator, along with
+// the comments of hidden posts and the replies under hidden comments.
+func (cs *commentService) GetCommentsByIDs(ctx context.Context, ids []int) (map[int]*model.Comment, error) {
+	withHidden, err := cs.moderation.IsModerator(ctx)
 	if err != nil {
-		return nil, nil, nil, nil, err
+		return nil, err
 	}
-	modelPost := &model.Post{
-		ID:          post.ID,
-		Author:      authors[post.AuthorID],
-		Title:       post.Title,
-		Content:     post.Content,
-		Created:     post.Created,
-		Commentable: post.Commentable,
-		Comments:    []*model.Comment{},
+	comments, err := cs.commentRepo.GetCommentsByIDs(ctx, ids, withHidden)
+	if err != nil {
+		return nil, err
 	}
-	return roots, allComments, authors, modelPost, nil
-}
 
-func GetAuthorIDs(comments []*entity.Comment) []int {
-	uniqueIDs := make(map[int]struct{})
+	result := make(map[int]*model.Comment, len(comments))
 	for _, comment := range comments {
-		uniqueIDs[comment.AuthorID] = struct{}{}
+		result[comment.ID] = CommentToModel(comment)
+	}
+	return result, nil
+}
+
+// MaxReplay is the number of missed comments a resumed subscription may replay.
+const MaxReplay = 1000
+
+func (cs *commentService) GetCommentsSince(ctx context.Context, postID int, since string) ([]*model.Comment, error) {
+	point, err := pagination.ParseSince(since)
+	if err != nil {
+		return nil, err
+	}
+	withHidden, err := cs.moderation.IsModerator(ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	ids := make([]int, 0, len(uniqueIDs))
-	for id := range uniqueIDs {
-		ids = append(ids, id)
+	comments, err := cs.commentRepo.GetCommentsSince(ctx, postID, point, MaxReplay+1, withHidden)
+	if err != nil {
+		return nil, err
 	}
-	return ids
+	if len(comments) > MaxReplay {
+		return nil, ErrReplayTooLong
+	}
+
+	return CommentsToModels(comments), nil
 }
 
 func (cs *commentService) CreateComment(ctx context.Context,
 	comment model.CreateCommentInput) (*model.Comment, error) {
 
-	if len(comment.Content) > 2000 {
-		return nil, errors.New("comment content exceeds the limitation of 2000 symbols")
+	authorID, err := auth.ViewerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	content, flags, err := cs.filters.Apply(filter.TargetComments, comment.Content)
+	if err != nil {
+		return nil, err
+	}
+	if err = CheckLength(content, MaxCommentLength, ErrCommentTooLong); err != nil {
+		return nil, err
 	}
 
 	commentToAdd := &entity.Comment{
 		PostID:   comment.PostID,
-		AuthorID: comment.AuthorID,
-		Content:  comment.Content,
+		AuthorID: authorID,
+		Content:  content,
 		Created:  time.Now(),
 		ParentID: comment.Parent,
 	}
 
-	post, err := cs.postService.GetPost(ctx, comment.PostID, nil, nil)
+	post, err := cs.postService.GetPost(ctx, comment.PostID)
 	if err != nil {
 		return nil, err
 	}
 
 	if !post.Commentable {
-		return nil, fmt.Errorf("comments are disabled")
+		return nil, ErrCommentsDisabled
+	}
+	hiddenThread, err := HiddenThread(ctx, cs.commentRepo, comment.Parent)
+	if err != nil {
+		return nil, err
+	}
+	// only moderators see a hidden parent to reply to
+	if hiddenThread {
+		moderator, err := cs.moderation.IsModerator(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if !moderator {
+			return nil, repo.ErrParentNotFound
+		}
 	}
 
 	id, err := cs.commentRepo.AddComment(ctx, commentToAdd)
 	if err != nil {
 		return nil, err
 	}
+	commentToAdd.ID = id
+
+	added := CommentToModel(commentToAdd)
+	cs.publish(ctx, added.PostID, &model.CommentCreated{Comment: added, HiddenThread: hiddenThread})
+	if err = cs.mentions.SetCommentMentions(ctx, added.ID, added.Content); err != nil {
+		logrus.WithError(err).Error("failed to save comment mentions")
+	}
+	if err = cs.moderation.FlagComment(ctx, added.ID, flags); err != nil {
+		logrus.WithError(err).Error("failed to flag comment")
+	}
+	if err = cs.notifications.Notify(ctx, added); err != nil {
+		logrus.WithError(err).Error("failed to notify about comment")
+	}
 
-	author, err := cs.userRepo.GetUserByID(ctx, comment.AuthorID)
+	return added, nil
+}
+
+func (cs *commentService) UpdateComment(ctx context.Context, commentID int,
+	content string) (*model.Comment, error) {
+	editorID, err := auth.ViewerID(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	var parent *model.Comment
-	if comment.Parent != nil {
-		parentComment, err := cs.GetCommentByID(ctx, *comment.Parent)
-		if err != nil {
-			return nil, err
-		}
-		parent = parentComment
+	comment, err := cs.commentRepo.GetCommentByID(ctx, commentID)
+	if err != nil {
+		return nil, err
 	}
 
-	added := &model.Comment{
-		ID:      id,
-		Post:    post,
-		Author:  author,
-		Content: comment.Content,
-		Created: commentToAdd.Created,
-		Parent:  parent,
-		Replies: []*model.Comment{},
+	updated, flags, err := UpdatedComment(ctx, comment, content, cs.filters)
+	if err != nil {
+		return nil, err
+	}
+	hiddenThread, err := HiddenThread(ctx, cs.commentRepo, comment.ParentID)
+	if err != nil {
+		return nil, err
+	}
+	if err = cs.commentRepo.UpdateComment(ctx, updated, editorID); err != nil {
+		return nil, err
 	}
 
-	if cs.broker != nil {
-		go func() {
-			cs.broker.Publish(comment.PostID, added)
-		}()
+	if err = cs.mentions.SetCommentMentions(ctx, updated.ID, updated.Content); err != nil {
+		logrus.WithError(err).Error("failed to save comment mentions")
+	}
+	if err = cs.moderation.FlagComment(ctx, updated.ID, flags); err != nil {
+		logrus.WithError(err).Error("failed to flag comment")
 	}
+	result := CommentToModel(updated)
+	cs.publish(ctx, result.PostID, &model.CommentUpdated{Comment: result, HiddenThread: hiddenThread})
 
-	return added, nil
+	return result, nil
 }
 
-func FillComments(roots, fullComments []*entity.Comment, users map[int]*model.User,
-	post *model.Post) []*model.Comment {
-	commentMap := make(map[int]*model.Comment)
+func (cs *commentService) DeleteComment(ctx context.Context, commentID int) (*model.Comment, error) {
+	comment, err := cs.commentRepo.GetCommentByID(ctx, commentID)
+	if err != nil {
+		return nil, err
+	}
+	if err = CheckAuthor(ctx, comment.AuthorID); err != nil {
+		return nil, err
+	}
+	if comment.Deleted {
+		return nil, ErrCommentDeleted
+	}
+	hiddenThread, err := HiddenThread(ctx, cs.commentRepo, comment.ParentID)
+	if err != nil {
+		return nil, err
+	}
 
-	for _, comment := range fullComments {
-		commentMap[comment.ID] = commentToModel(comment, users, post)
+	tombstoned, err := cs.commentRepo.DeleteComment(ctx, commentID)
+	if err != nil {
+		return nil, err
 	}
 
-	for _, comment := range fullComments {
-		if comment.ParentID != nil {
-			parent := commentMap[*comment.ParentID]
-			child := commentMap[comment.ID]
-			child.Parent = parent
-			parent.Replies = append(parent.Replies, child)
-		}
+	event := DeletedEvent(comment, tombstoned)
+	event.HiddenThread = hiddenThread
+	cs.publish(ctx, comment.PostID, event)
+
+	return event.Tombstone, nil
+}
+
+// publish is synchronous, so that subscribers get events of a post in the order they happened.
+// The broker never waits for slow subscribers, whatever the backpressure policy.
+func (cs *commentService) publish(ctx context.Context, postID int, event model.CommentEvent) {
+	if cs.broker != nil {
+		cs.broker.Publish(ctx, postID, event)
 	}
+}
 
-	result := make([]*model.Comment, 0, len(roots))
-	for _, root := range roots {
-		result = append(result, commentMap[root.ID])
+// HiddenThread tells whether the parent comment is hidden from non-moderators, itself, with its post
+// or with a comment above it. Root comments have no parent to hide them.
+func HiddenThread(ctx context.Context, comments repo.CommentRepo, parentID *int) (bool, error) {
+	if parentID == nil {
+		return false, nil
+	}
+	parents, err := comments.GetCommentsByIDs(ctx, []int{*parentID}, false)
+	if err != nil {
+		return false, err
 	}
+	return len(parents) == 0, nil
+}
 
+// DeletedEvent describes the deletion of a comment, tombstoned tells whether it was left as a tombstone.
+func DeletedEvent(comment *entity.Comment, tombstoned bool) *model.CommentDeleted {
+	event := &model.CommentDeleted{CommentID: comment.ID, ParentID: comment.ParentID}
+	if tombstoned {
+		event.Tombstone = CommentToModel(Tombstone(comment))
+	}
+	return event
+}
+
+// UpdatedComment checks that the viewer may edit the comment, runs the new content through
+// the filters, checks its length and returns the comment's copy with the filtered content and the edit time set,
+// along with the reasons to flag it.
+func UpdatedComment(ctx context.Context, comment *entity.Comment, content string,
+	filters *filter.Pipeline) (*entity.Comment, []string, error) {
+	if err := CheckAuthor(ctx, comment.AuthorID); err != nil {
+		return nil, nil, err
+	}
+	if comment.Deleted {
+		return nil, nil, ErrCommentDeleted
+	}
+	content, flags, err := filters.Apply(filter.TargetComments, content)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err = CheckLength(content, MaxCommentLength, ErrCommentTooLong); err != nil {
+		return nil, nil, err
+	}
+
+	updated := *comment
+	updated.Content = content
+	edited := time.Now()
+	updated.Edited = &edited
+
+	return &updated, flags, nil
+}
+
+// Tombstone returns a copy of the comment as it is left after deletion when it has replies.
+func Tombstone(comment *entity.Comment) *entity.Comment {
+	tombstone := *comment
+	tombstone.Content = entity.DeletedCommentContent
+	tombstone.Deleted = true
+	return &tombstone
+}
+
+func CommentsToModels(comments []*entity.Comment) []*model.Comment {
+	result := make([]*model.Comment, 0, len(comments))
+	for _, comment := range comments {
+		result = append(result, CommentToModel(comment))
+	}
 	return result
 }
 
-func commentToModel(comment *entity.Comment, authors map[int]*model.User,
-	post *model.Post) *model.Comment {
+func CommentToModel(comment *entity.Comment) *model.Comment {
 	return &model.Comment{
-		ID:      comment.ID,
-		Post:    post,
-		Author:  authors[comment.AuthorID],
-		Content: comment.Content,
-		Created: comment.Created,
-		Replies: make([]*model.Comment, 0),
+		ID:       comment.ID,
+		PostID:   comment.PostID,
+		AuthorID: comment.AuthorID,
+		Content:  comment.Content,
+		Created:  comment.Created,
+		ParentID: comment.ParentID,
+		EditedAt: comment.Edited,
+		Deleted:  comment.Deleted,
+		Hidden:   comment.Hidden,
+		Score:    comment.Score,
 	}
 }
 
-func (cs *commentService) GetCommentByID(ctx context.Context, id int) (*model.Comment, error) {
-	comment, err := cs.commentRepo.GetCommentByID(ctx, id)
-	if err != nil {
-		return nil, err
+// GroupCommentConnections splits root comments fetched for several posts into one connection per post.
+// Every requested post gets a connection, even if it has no comments.
+func GroupCommentConnections(postIDs []int, roots []*entity.Comment,
+	page pagination.Page) map[int]*model.CommentConnection {
+	grouped := make(map[int][]*entity.Comment, len(postIDs))
+	for _, root := range roots {
+		grouped[root.PostID] = append(grouped[root.PostID], root)
 	}
 
-	author, err := cs.userRepo.GetUserByID(ctx, comment.AuthorID)
-	if err != nil {
-		return nil, err
+	result := make(map[int]*model.CommentConnection, len(postIDs))
+	for _, postID := range postIDs {
+		comments, hasNext := pagination.Trim(grouped[postID], page)
+		edges, pageInfo := newConnection(CommentsToModels(comments), page.After != nil, hasNext, commentEdge(page))
+		result[postID] = &model.CommentConnection{Edges: edges, PageInfo: pageInfo}
 	}
+	return result
+}
 
-	post, err := cs.postService.GetPost(ctx, comment.PostID, nil, nil)
+// FillReplyCounts adds zero counts for comments without replies.
+func FillReplyCounts(ids []int, counts map[int]int) map[int]int {
+	for _, id := range ids {
+		if _, ok := counts[id]; !ok {
+			counts[id] = 0
+		}
+	}
+	return counts
+}
+
+func (cs *commentService) GetRevisions(ctx context.Context,
+	commentIDs []int) (map[int][]*model.CommentRevision, error) {
+	revisions, err := cs.commentRepo.GetRevisions(ctx, commentIDs)
 	if err != nil {
 		return nil, err
 	}
 
-	return &model.Comment{
-		ID:      comment.ID,
-		Post:    post,
-		Author:  author,
-		Content: comment.Content,
-		Created: comment.Created,
-	}, nil
+	return GroupRevisions(commentIDs, revisions), nil
 }
 
-func (cs *commentService) GetCommentsForPosts(ctx context.Context, postIDs []int) ([]*model.Comment, error) {
-	var allComments []*model.Comment
-	for _, postID := range postIDs {
-		comments, err := cs.GetComments(ctx, postID, nil, nil)
-		if err != nil {
-			return nil, err
-		}
-		allComments = append(allComments, comments...)
+// GroupRevisions splits revisions by comment, comments without revisions get an empty list.
+func GroupRevisions(commentIDs []int, revisions []*entity.CommentRevision) map[int][]*model.CommentRevision {
+	result := make(map[int][]*model.CommentRevision, len(commentIDs))
+	for _, id := range commentIDs {
+		result[id] = []*model.CommentRevision{}
 	}
-	return allComments, nil
+	for _, revision := range revisions {
+		result[revision.CommentID] = append(result[revision.CommentID], &model.CommentRevision{
+			ID:        revision.ID,
+			CommentID: revision.CommentID,
+			EditorID:  revision.EditorID,
+			Content:   revision.Content,
+			Edited:    revision.Edited,
+		})
+	}
+	return result
 }
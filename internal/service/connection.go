@@ -0,0 +1,61 @@
+package service
+
+import (
+	"Commentary/internal/entity"
+	"Commentary/internal/graph/model"
+	"Commentary/internal/pagination"
+)
+
+// newConnection turns a page of items into edges and the page info of a connection,
+// edge wraps an item into its edge and returns the edge's cursor.
+func newConnection[T, E any](items []T, hasPrevious, hasNext bool,
+	edge func(item T) (E, string)) ([]E, *model.PageInfo) {
+	edges := make([]E, 0, len(items))
+	pageInfo := &model.PageInfo{HasNextPage: hasNext, HasPreviousPage: hasPrevious}
+	for i, item := range items {
+		wrapped, cursor := edge(item)
+		edges = append(edges, wrapped)
+		if i == 0 {
+			pageInfo.StartCursor = &cursor
+		}
+		pageInfo.EndCursor = &cursor
+	}
+	return edges, pageInfo
+}
+
+func postEdge(page pagination.Page) func(post *model.Post) (*model.PostEdge, string) {
+	return func(post *model.Post) (*model.PostEdge, string) {
+		cursor := page.Cursor(pagination.Key{Created: post.Created, ID: post.ID, Score: post.Score})
+		return &model.PostEdge{Cursor: cursor, Node: post}, cursor
+	}
+}
+
+func commentEdge(page pagination.Page) func(comment *model.Comment) (*model.CommentEdge, string) {
+	return func(comment *model.Comment) (*model.CommentEdge, string) {
+		cursor := page.Cursor(pagination.Key{Created: comment.Created, ID: comment.ID, Score: comment.Score})
+		return &model.CommentEdge{Cursor: cursor, Node: comment}, cursor
+	}
+}
+
+func searchEdge(hit *entity.SearchHit) (*model.SearchEdge, string) {
+	kind := model.SearchKindPost
+	if hit.Kind == entity.TargetComment {
+		kind = model.SearchKindComment
+	}
+	cursor := pagination.EncodeSearchCursor(hit.Rank, string(hit.Kind), hit.ID)
+	return &model.SearchEdge{Cursor: cursor, Snippet: hit.Snippet, Kind: kind, ID: hit.ID}, cursor
+}
+
+func notificationEdge(page pagination.Page) func(notification *entity.Notification) (*model.NotificationEdge, string) {
+	return func(notification *entity.Notification) (*model.NotificationEdge, string) {
+		cursor := page.Cursor(pagination.Key{Created: notification.Created, ID: notification.ID})
+		return &model.NotificationEdge{Cursor: cursor, Node: NotificationToModel(notification)}, cursor
+	}
+}
+
+func reportEdge(page pagination.Page) func(report *entity.Report) (*model.ReportEdge, string) {
+	return func(report *entity.Report) (*model.ReportEdge, string) {
+		cursor := page.Cursor(pagination.Key{Created: report.Created, ID: report.ID})
+		return &model.ReportEdge{Cursor: cursor, Node: ReportToModel(report)}, cursor
+	}
+}
@@ -0,0 +1,23 @@
+package service
+
+import "errors"
+
+var (
+	ErrWrongDepth            = errors.New("wrong depth parameter value")
+	ErrNotAuthor             = errors.New("only the author can modify this content")
+	ErrEmptyUpdate           = errors.New("nothing to update")
+	ErrCommentDeleted        = errors.New("deleted comment can not be changed")
+	ErrUnknownReaction       = errors.New("unknown reaction type")
+	ErrInvalidReactionTarget = errors.New("exactly one of postID and commentID must be set")
+	ErrEmptySearch           = errors.New("search text must not be empty")
+	ErrReplayTooLong         = errors.New("too many missed comments to replay, reload the post instead")
+	ErrNotModerator          = errors.New("moderator role required")
+	ErrEmptyReason           = errors.New("report reason must not be empty")
+	ErrReasonTooLong         = errors.New("report reason exceeds the limitation of 500 symbols")
+	ErrCommentsDisabled      = errors.New("comments are disabled")
+	ErrTitleTooLong          = errors.New("post title exceeds the limitation of 150 symbols")
+	ErrPostTooLong           = errors.New("post content exceeds the limitation of 5000 symbols")
+	ErrCommentTooLong        = errors.New("comment content exceeds the limitation of 2000 symbols")
+	ErrEmptyUsername         = errors.New("username must not be empty")
+	ErrUsernameTooLong       = errors.New("username exceeds the limitation of 20 symbols")
+)
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"Commentary/internal/common"
+	"Commentary/internal/entity"
+	"Commentary/internal/graph/model"
+	"Commentary/internal/mention"
+	"Commentary/internal/repo"
+	"context"
+)
+
+type mentionService struct {
+	mentionRepo repo.MentionRepo
+	userRepo    repo.UserRepo
+}
+
+func NewMentionService(mentionRepo repo.MentionRepo, userRepo repo.UserRepo) common.MentionService {
+	return &mentionService{mentionRepo: mentionRepo, userRepo: userRepo}
+}
+
+func (ms *mentionService) SetPostMentions(ctx context.Context, postID int, content string) error {
+	return ms.setMentions(ctx, entity.ReactionTarget{Kind: entity.TargetPost, ID: postID}, content)
+}
+
+func (ms *mentionService) SetCommentMentions(ctx context.Context, commentID int, content string) error {
+	return ms.setMentions(ctx, entity.ReactionTarget{Kind: entity.TargetComment, ID: commentID}, content)
+}
+
+func (ms *mentionService) setMentions(ctx context.Context, target entity.ReactionTarget, content string) error {
+	matches := mention.Parse(content)
+
+	var users []*model.User
+	if len(matches) > 0 {
+		var err error
+		if users, err = ms.userRepo.GetUsersByUsernames(ctx, mention.Usernames(content)); err != nil {
+			return err
+		}
+	}
+
+	return ms.mentionRepo.ReplaceMentions(ctx, target, ResolveMentions(matches, users))
+}
+
+func (ms *mentionService) GetPostMentions(ctx context.Context, postIDs []int) (map[int][]*model.Mention, error) {
+	mentions, err := ms.mentionRepo.GetMentions(ctx, entity.TargetPost, postIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	return GroupMentions(postIDs, mentions), nil
+}
+
+func (ms *mentionService) GetCommentMentions(ctx context.Context,
+	commentIDs []int) (map[int][]*model.Mention, error) {
+	mentions, err := ms.mentionRepo.GetMentions(ctx, entity.TargetComment, commentIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	return GroupMentions(commentIDs, mentions), nil
+}
+
+// ResolveMentions links the matches to the users with their usernames, matches of unknown usernames are dropped.
+func ResolveMentions(matches []mention.Match, users []*model.User) []*entity.Mention {
+	ids := make(map[string]int, len(users))
+	for _, user := range users {
+		ids[user.Username] = user.ID
+	}
+
+	var mentions []*entity.Mention
+	for _, match := range matches {
+		if userID, ok := ids[match.Username]; ok {
+			mentions = append(mentions, &entity.Mention{UserID: userID, Offset: match.Offset, Length: match.Length})
+		}
+	}
+	return mentions
+}
+
+// GroupMentions splits mentions by target, targets without mentions get an empty list.
+func GroupMentions(ids []int, mentions []*entity.Mention) map[int][]*model.Mention {
+	result := make(map[int][]*model.Mention, len(ids))
+	for _, id := range ids {
+		result[id] = []*model.Mention{}
+	}
+	for _, mention := range mentions {
+		result[mention.Target.ID] = append(result[mention.Target.ID], &model.Mention{
+			UserID: mention.UserID,
+			Offset: mention.Offset,
+			Length: mention.Length,
+		})
+	}
+	return result
+}
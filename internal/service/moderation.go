@@ -0,0 +1,287 @@
+package service
+
+import (
+	"Commentary/internal/auth"
+	"Commentary/internal/common"
+	"Commentary/internal/entity"
+	"Commentary/internal/graph/model"
+	"Commentary/internal/pagination"
+	"Commentary/internal/pubsub"
+	"Commentary/internal/repo"
+	"context"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// MaxReasonLength is the number of characters a report reason may take.
+const MaxReasonLength = 500
+
+type moderationService struct {
+	moderationRepo repo.ModerationRepo
+	userRepo       repo.UserRepo
+	commentRepo    repo.CommentRepo
+	postRepo       repo.PostRepo
+	broker         pubsub.Broker
+}
+
+func NewModerationService(moderationRepo repo.ModerationRepo, userRepo repo.UserRepo, commentRepo repo.CommentRepo,
+	postRepo repo.PostRepo, broker pubsub.Broker) common.ModerationService {
+	return &moderationService{moderationRepo: moderationRepo, userRepo: userRepo, commentRepo: commentRepo,
+		postRepo: postRepo, broker: broker}
+}
+
+// roleKey is the context key of the viewer's role looked up for a request.
+type roleKey struct{}
+
+type viewerRole struct {
+	once      sync.Once
+	moderator bool
+	err       error
+}
+
+// WithViewerRole makes IsModerator look the viewer's role up once for the context, every response
+// gets a context of its own, so a role granted meanwhile shows in the next one.
+func WithViewerRole(ctx context.Context) context.Context {
+	return context.WithValue(ctx, roleKey{}, &viewerRole{})
+}
+
+func (ms *moderationService) IsModerator(ctx context.Context) (bool, error) {
+	role, ok := ctx.Value(roleKey{}).(*viewerRole)
+	if !ok {
+		return ms.isModerator(ctx)
+	}
+	role.once.Do(func() {
+		role.moderator, role.err = ms.isModerator(ctx)
+	})
+	return role.moderator, role.err
+}
+
+func (ms *moderationService) isModerator(ctx context.Context) (bool, error) {
+	userID, ok := auth.UserIDFromContext(ctx)
+	if !ok {
+		return false, nil
+	}
+
+	role, err := ms.userRepo.GetRole(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return role == entity.RoleModerator, nil
+}
+
+func (ms *moderationService) ReportPost(ctx context.Context, postID int, reason string) error {
+	return ms.report(ctx, entity.ReactionTarget{Kind: entity.TargetPost, ID: postID}, reason)
+}
+
+func (ms *moderationService) ReportComment(ctx context.Context, commentID int, reason string) error {
+	return ms.report(ctx, entity.ReactionTarget{Kind: entity.TargetComment, ID: commentID}, reason)
+}
+
+func (ms *moderationService) FlagPost(ctx context.Context, postID int, flags []string) error {
+	if len(flags) == 0 {
+		return nil
+	}
+	target := entity.ReactionTarget{Kind: entity.TargetPost, ID: postID}
+	return ms.moderationRepo.AddReport(ctx, NewFlagReport(target, flags))
+}
+
+func (ms *moderationService) FlagComment(ctx context.Context, commentID int, flags []string) error {
+	if len(flags) == 0 {
+		return nil
+	}
+	target := entity.ReactionTarget{Kind: entity.TargetComment, ID: commentID}
+	return ms.moderationRepo.AddReport(ctx, NewFlagReport(target, flags))
+}
+
+func (ms *moderationService) report(ctx context.Context, target entity.ReactionTarget, reason string) error {
+	reporterID, err := auth.ViewerID(ctx)
+	if err != nil {
+		return err
+	}
+
+	report, err := NewReport(reporterID, target, reason)
+	if err != nil {
+		return err
+	}
+	return ms.moderationRepo.AddReport(ctx, report)
+}
+
+func (ms *moderationService) GetQueue(ctx context.Context, first *int, after *string) (*model.ReportConnection, error) {
+	if err := RequireModerator(ctx, ms); err != nil {
+		return nil, err
+	}
+	page, err := pagination.NewPage(first, after)
+	if err != nil {
+		return nil, err
+	}
+
+	reports, err := ms.moderationRepo.GetOpenReports(ctx, page)
+	if err != nil {
+		return nil, err
+	}
+	reports, hasNext := pagination.Trim(reports, page)
+
+	edges, pageInfo := newConnection(reports, page.After != nil, hasNext, reportEdge(page))
+	return &model.ReportConnection{Edges: edges, PageInfo: pageInfo}, nil
+}
+
+func (ms *moderationService) ModeratePost(ctx context.Context, postID int, action model.ModerationAction) error {
+	if err := RequireModerator(ctx, ms); err != nil {
+		return err
+	}
+	post, err := ms.postRepo.GetPost(ctx, postID)
+	if err != nil {
+		return err
+	}
+
+	target := entity.ReactionTarget{Kind: entity.TargetPost, ID: postID}
+	if action == model.ModerationActionDelete {
+		return RemovePost(ctx, ms.postRepo, ms.broker, postID)
+	}
+
+	moderated := *post
+	moderated.Hidden = action == model.ModerationActionHide
+	if err = ms.moderationRepo.SetHidden(ctx, target, moderated.Hidden); err != nil {
+		return err
+	}
+	if err = ms.moderationRepo.ResolveReports(ctx, target); err != nil {
+		return err
+	}
+	ms.publish(ctx, postID, &model.PostUpdated{Post: PostToModel(&moderated)})
+
+	return nil
+}
+
+func (ms *moderationService) ModerateComment(ctx context.Context, commentID int,
+	action model.ModerationAction) error {
+	if err := RequireModerator(ctx, ms); err != nil {
+		return err
+	}
+	comment, err := ms.commentRepo.GetCommentByID(ctx, commentID)
+	if err != nil {
+		return err
+	}
+	hiddenThread, err := HiddenThread(ctx, ms.commentRepo, comment.ParentID)
+	if err != nil {
+		return err
+	}
+
+	target := entity.ReactionTarget{Kind: entity.TargetComment, ID: commentID}
+	if action == model.ModerationActionDelete {
+		tombstoned, err := ms.commentRepo.DeleteComment(ctx, commentID)
+		if err != nil {
+			return err
+		}
+		if err = ms.moderationRepo.ResolveReports(ctx, target); err != nil {
+			return err
+		}
+		event := DeletedEvent(comment, tombstoned)
+		event.HiddenThread = hiddenThread
+		ms.publish(ctx, comment.PostID, event)
+		return nil
+	}
+
+	moderated := *comment
+	moderated.Hidden = action == model.ModerationActionHide
+	if err = ms.moderationRepo.SetHidden(ctx, target, moderated.Hidden); err != nil {
+		return err
+	}
+	if err = ms.moderationRepo.ResolveReports(ctx, target); err != nil {
+		return err
+	}
+	ms.publish(ctx, comment.PostID, &model.CommentUpdated{Comment: CommentToModel(&moderated), HiddenThread: hiddenThread})
+
+	return nil
+}
+
+func (ms *moderationService) GrantModerator(ctx context.Context, userID int) error {
+	return ms.userRepo.SetRole(ctx, userID, entity.RoleModerator)
+}
+
+func (ms *moderationService) publish(ctx context.Context, postID int, event model.Event) {
+	if ms.broker != nil {
+		ms.broker.Publish(ctx, postID, event)
+	}
+}
+
+// RequireModerator allows moderation only to moderators.
+func RequireModerator(ctx context.Context, moderation common.ModerationService) error {
+	if _, err := auth.ViewerID(ctx); err != nil {
+		return err
+	}
+	moderator, err := moderation.IsModerator(ctx)
+	if err != nil {
+		return err
+	}
+	if !moderator {
+		return ErrNotModerator
+	}
+	return nil
+}
+
+// SeesHidden tells whether the viewer is shown hidden posts and comments. The role is looked up
+// only when anything is hidden.
+func SeesHidden(ctx context.Context, moderation common.ModerationService, anyHidden bool) (bool, error) {
+	if !anyHidden {
+		return false, nil
+	}
+	return moderation.IsModerator(ctx)
+}
+
+// SeesRevisions tells whether the viewer is shown the edit history of a comment: its author
+// and moderators are.
+func SeesRevisions(ctx context.Context, moderation common.ModerationService, comment *model.Comment) (bool, error) {
+	viewerID, ok := auth.UserIDFromContext(ctx)
+	if !ok {
+		return false, nil
+	}
+	if viewerID == comment.AuthorID {
+		return true, nil
+	}
+	return moderation.IsModerator(ctx)
+}
+
+// NewReport checks the reason and returns the report to save.
+func NewReport(reporterID int, target entity.ReactionTarget, reason string) (*entity.Report, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, ErrEmptyReason
+	}
+	if utf8.RuneCountInString(reason) > MaxReasonLength {
+		return nil, ErrReasonTooLong
+	}
+
+	return &entity.Report{
+		Target:     target,
+		ReporterID: &reporterID,
+		Reason:     reason,
+		Created:    time.Now(),
+	}, nil
+}
+
+// NewFlagReport returns the report of content flagged by the content filters.
+func NewFlagReport(target entity.ReactionTarget, flags []string) *entity.Report {
+	return &entity.Report{
+		Target:  target,
+		Reason:  "flagged by content filters: " + strings.Join(flags, "; "),
+		Created: time.Now(),
+	}
+}
+
+func ReportToModel(report *entity.Report) *model.Report {
+	result := &model.Report{
+		ID:         report.ID,
+		ReporterID: report.ReporterID,
+		Reason:     report.Reason,
+		Created:    report.Created,
+	}
+	targetID := report.Target.ID
+	if report.Target.Kind == entity.TargetComment {
+		result.CommentID = &targetID
+	} else {
+		result.PostID = &targetID
+	}
+	return result
+}
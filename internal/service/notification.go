@@ -0,0 +1,159 @@
+package service
+
+import (
+	"Commentary/internal/auth"
+	"Commentary/internal/common"
+	"Commentary/internal/entity"
+	"Commentary/internal/graph/model"
+	"Commentary/internal/mention"
+	"Commentary/internal/pagination"
+	"Commentary/internal/pubsub"
+	"Commentary/internal/repo"
+	"context"
+)
+
+type notificationService struct {
+	notificationRepo repo.NotificationRepo
+	commentRepo      repo.CommentRepo
+	userRepo         repo.UserRepo
+	moderation       common.ModerationService
+	broker           pubsub.Broker
+}
+
+func NewNotificationService(notificationRepo repo.NotificationRepo, commentRepo repo.CommentRepo,
+	userRepo repo.UserRepo, moderation common.ModerationService, broker pubsub.Broker) common.NotificationService {
+	return &notificationService{
+		notificationRepo: notificationRepo,
+		commentRepo:      commentRepo,
+		userRepo:         userRepo,
+		moderation:       moderation,
+		broker:           broker,
+	}
+}
+
+// Notify notifies nobody about a comment in a hidden thread, such as a moderator's reply under
+// a hidden comment, since only moderators could load it.
+func (ns *notificationService) Notify(ctx context.Context, comment *model.Comment) error {
+	visible, err := ns.commentRepo.GetCommentsByIDs(ctx, []int{comment.ID}, false)
+	if err != nil {
+		return err
+	}
+	if len(visible) == 0 {
+		return nil
+	}
+
+	var parentAuthorID *int
+	if comment.ParentID != nil {
+		parent, err := ns.commentRepo.GetCommentByID(ctx, *comment.ParentID)
+		if err != nil {
+			return err
+		}
+		if !parent.Deleted {
+			parentAuthorID = &parent.AuthorID
+		}
+	}
+
+	var mentioned []*model.User
+	if usernames := mention.Usernames(comment.Content); len(usernames) > 0 {
+		if mentioned, err = ns.userRepo.GetUsersByUsernames(ctx, usernames); err != nil {
+			return err
+		}
+	}
+
+	notifications := NewNotifications(comment, parentAuthorID, mentioned)
+	if err = ns.notificationRepo.AddNotifications(ctx, notifications); err != nil {
+		return err
+	}
+	PublishNotifications(ctx, ns.broker, notifications)
+	return nil
+}
+
+func (ns *notificationService) GetNotifications(ctx context.Context, first *int, after *string,
+	unreadOnly bool) (*model.NotificationConnection, error) {
+	userID, err := auth.ViewerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	page, err := pagination.NewSortedPage(first, after, pagination.SortNewest)
+	if err != nil {
+		return nil, err
+	}
+
+	withHidden, err := ns.moderation.IsModerator(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	notifications, err := ns.notificationRepo.GetNotifications(ctx, userID, unreadOnly, page, withHidden)
+	if err != nil {
+		return nil, err
+	}
+	notifications, hasNext := pagination.Trim(notifications, page)
+
+	edges, pageInfo := newConnection(notifications, page.After != nil, hasNext, notificationEdge(page))
+	return &model.NotificationConnection{Edges: edges, PageInfo: pageInfo}, nil
+}
+
+func (ns *notificationService) MarkNotificationsRead(ctx context.Context, ids []int) (int, error) {
+	userID, err := auth.ViewerID(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return ns.notificationRepo.MarkNotificationsRead(ctx, userID, ids)
+}
+
+// NewNotifications notifies the author of the parent comment about a reply and the mentioned users
+// about a mention. Nobody is notified about their own comment or twice about the same comment.
+func NewNotifications(comment *model.Comment, parentAuthorID *int, mentioned []*model.User) []*entity.Notification {
+	var notifications []*entity.Notification
+	notified := map[int]struct{}{comment.AuthorID: {}}
+	add := func(userID int, kind entity.NotificationKind) {
+		if _, ok := notified[userID]; ok {
+			return
+		}
+		notified[userID] = struct{}{}
+		notifications = append(notifications, &entity.Notification{
+			UserID:    userID,
+			Kind:      kind,
+			CommentID: comment.ID,
+			ActorID:   comment.AuthorID,
+			Created:   comment.Created,
+		})
+	}
+
+	if parentAuthorID != nil {
+		add(*parentAuthorID, entity.NotificationReply)
+	}
+	for _, user := range mentioned {
+		add(user.ID, entity.NotificationMention)
+	}
+	return notifications
+}
+
+// PublishNotifications sends every notification to the subscription of its user.
+func PublishNotifications(ctx context.Context, broker pubsub.Broker, notifications []*entity.Notification) {
+	if broker == nil {
+		return
+	}
+	for _, notification := range notifications {
+		broker.Publish(ctx, pubsub.UserTopic(notification.UserID),
+			&model.NotificationAdded{Notification: NotificationToModel(notification)})
+	}
+}
+
+func NotificationToModel(notification *entity.Notification) *model.Notification {
+	kind := model.NotificationKindReply
+	if notification.Kind == entity.NotificationMention {
+		kind = model.NotificationKindMention
+	}
+	return &model.Notification{
+		ID:        notification.ID,
+		UserID:    notification.UserID,
+		Kind:      kind,
+		CommentID: notification.CommentID,
+		ActorID:   notification.ActorID,
+		Created:   notification.Created,
+		Read:      notification.Read,
+	}
+}
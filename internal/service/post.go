@@ -1,35 +1,53 @@
 package service
 
 import (
+	"Commentary/internal/auth"
 	"Commentary/internal/common"
 	"Commentary/internal/entity"
+	"Commentary/internal/filter"
 	"Commentary/internal/graph/model"
-	"Commentary/internal/repo/pgdb"
+	"Commentary/internal/pagination"
+	"Commentary/internal/pubsub"
+	"Commentary/internal/repo"
 	"context"
-	"errors"
-	"fmt"
-	"log"
-	"sync"
+	"github.com/sirupsen/logrus"
+	"slices"
 	"time"
+	"unicode/utf8"
 )
 
-type PostService struct {
-	postRepo       pgdb.PostRepo
-	userRepo       pgdb.UserRepo
-	commentService common.CommentService
-}
+const (
+	// MaxTitleLength and MaxPostLength are the numbers of characters a post title and content may take.
+	MaxTitleLength = 150
+	MaxPostLength  = 5000
+)
 
-func NewPostService(postRepo pgdb.PostRepo, userRepo pgdb.UserRepo) common.PostService {
-	return &PostService{postRepo: postRepo, userRepo: userRepo}
+type PostService struct {
+	postRepo   repo.PostRepo
+	mentions   common.MentionService
+	moderation common.ModerationService
+	filters    *filter.Pipeline
+	broker     pubsub.Broker
 }
 
-func (ps *PostService) SetCommentService(commentService common.CommentService) {
-	ps.commentService = commentService
+func NewPostService(postRepo repo.PostRepo, mentions common.MentionService, moderation common.ModerationService,
+	filters *filter.Pipeline, broker pubsub.Broker) common.PostService {
+	return &PostService{postRepo: postRepo, mentions: mentions, moderation: moderation, filters: filters,
+		broker: broker}
 }
 
 func (ps *PostService) CreatePost(ctx context.Context, input model.CreatePostInput) (*model.Post, error) {
+	authorID, err := auth.ViewerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	input, flags, err := FilteredPostInput(input, ps.filters)
+	if err != nil {
+		return nil, err
+	}
+
 	newPost := &entity.Post{
-		AuthorID:    input.AuthorID,
+		AuthorID:    authorID,
 		Title:       input.Title,
 		Content:     input.Content,
 		Created:     time.Now(),
@@ -40,160 +58,248 @@ func (ps *PostService) CreatePost(ctx context.Context, input model.CreatePostInp
 	if err != nil {
 		return nil, err
 	}
-	author, err := ps.userRepo.GetUserByID(ctx, input.AuthorID)
+
+	if err = ps.mentions.SetPostMentions(ctx, post.ID, post.Content); err != nil {
+		logrus.WithError(err).Error("failed to save post mentions")
+	}
+	if err = ps.moderation.FlagPost(ctx, post.ID, flags); err != nil {
+		logrus.WithError(err).Error("failed to flag post")
+	}
+	added := PostToModel(post)
+	ps.publish(ctx, pubsub.FeedTopic, &model.PostCreated{Post: added})
+	return added, nil
+}
+
+// GetPost returns a hidden post only to moderators, other viewers get ErrPostNotFound.
+func (ps *PostService) GetPost(ctx context.Context, id int) (*model.Post, error) {
+	post, err := ps.postRepo.GetPost(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	withHidden, err := SeesHidden(ctx, ps.moderation, post.Hidden)
 	if err != nil {
 		return nil, err
 	}
+	if post.Hidden && !withHidden {
+		return nil, repo.ErrPostNotFound
+	}
 
-	return &model.Post{
-		ID:          post.ID,
-		Author:      author,
-		Title:       post.Title,
-		Content:     post.Content,
-		Created:     post.Created,
-		Commentable: post.Commentable,
-		Comments:    []*model.Comment{},
-	}, nil
+	return PostToModel(post), nil
 }
 
-func (ps *PostService) GetPost(ctx context.Context, id int, limit *int, offset *int) (*model.Post, error) {
-	ctx, cancel := context.WithCancel(ctx)
-	defer cancel()
-
-	var wg sync.WaitGroup
-	var postErr, commentsErr, authorErr error
-	var post *entity.Post
-	var comments []*model.Comment
-	var author *model.User
-
-	postChan := make(chan *entity.Post, 1)
-	wg.Add(3)
-
-	go func() {
-		defer wg.Done()
-		post, postErr = ps.postRepo.GetPost(ctx, id)
-		if postErr != nil {
-			cancel()
-			return
-		}
-		postChan <- post
-	}()
-
-	go func() {
-		defer wg.Done()
-		comments, commentsErr = ps.commentService.GetComments(ctx, id, limit, offset)
-		if commentsErr != nil {
-			cancel()
-			return
-		}
-	}()
-
-	go func() {
-		defer wg.Done()
-		select {
-		case p := <-postChan:
-			if p == nil {
-				cancel()
-				return
-			}
-			if post != nil {
-				a, authorErr := ps.userRepo.GetUserByID(ctx, post.AuthorID)
-				if authorErr != nil {
-					cancel()
-					return
-				}
-				author = a
-			}
-		case <-ctx.Done():
-			authorErr = errors.New("context canceled")
-			return
-		}
-	}()
-
-	wg.Wait()
-
-	if postErr != nil || commentsErr != nil || authorErr != nil {
-		log.Println(postErr)
-		log.Println(commentsErr)
-		log.Println(authorErr)
-		return nil, fmt.Errorf("error getting post")
+// GetPostsByIDs leaves hidden posts out unless the viewer is a moderator.
+func (ps *PostService) GetPostsByIDs(ctx context.Context, ids []int) (map[int]*model.Post, error) {
+	posts, err := ps.postRepo.GetPostsByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	withHidden, err := SeesHidden(ctx, ps.moderation, slices.ContainsFunc(posts, func(post *entity.Post) bool {
+		return post.Hidden
+	}))
+	if err != nil {
+		return nil, err
 	}
 
-	return &model.Post{
-		ID:          id,
-		Author:      author,
-		Title:       post.Title,
-		Content:     post.Content,
-		Created:     post.Created,
-		Commentable: post.Commentable,
-		Comments:    comments,
-	}, nil
+	result := make(map[int]*model.Post, len(posts))
+	for _, post := range posts {
+		if !post.Hidden || withHidden {
+			result[post.ID] = PostToModel(post)
+		}
+	}
+	return result, nil
 }
 
 func (ps *PostService) ToggleComments(ctx context.Context, postID int) (*model.Post, error) {
-	err := ps.postRepo.ToggleComments(ctx, postID)
+	post, err := ps.postRepo.GetPost(ctx, postID)
 	if err != nil {
 		return nil, err
 	}
+	if err = CheckAuthor(ctx, post.AuthorID); err != nil {
+		return nil, err
+	}
+
+	if err = ps.postRepo.ToggleComments(ctx, postID); err != nil {
+		return nil, err
+	}
 
-	return ps.GetPost(ctx, postID, nil, nil)
+	toggled, err := ps.postRepo.GetPost(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+	result := PostToModel(toggled)
+	ps.publish(ctx, postID, &model.CommentsToggled{PostID: postID, Commentable: result.Commentable})
+	ps.publish(ctx, postID, &model.PostUpdated{Post: result})
+	return result, nil
 }
 
-func (ps *PostService) GetPosts(ctx context.Context, limit *int, offset *int) ([]*model.Post, error) {
-	posts, err := ps.postRepo.GetPostsPag(ctx, limit, offset)
+func (ps *PostService) GetPosts(ctx context.Context, first *int, after *string,
+	sort model.SortOrder) (*model.PostConnection, error) {
+	page, err := pagination.NewSortedPage(first, after, pagination.Sort(sort))
+	if err != nil {
+		return nil, err
+	}
+	withHidden, err := ps.moderation.IsModerator(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	authorIDs := extractAuthorIDs(posts)
-	users, err := ps.userRepo.GetUsersByIDs(ctx, authorIDs)
+	posts, err := ps.postRepo.GetPostsPag(ctx, page, withHidden)
 	if err != nil {
 		return nil, err
 	}
+	posts, hasNext := pagination.Trim(posts, page)
 
-	postIDs := make([]int, len(posts))
-	for i, post := range posts {
-		postIDs[i] = post.ID
+	modelPosts := make([]*model.Post, 0, len(posts))
+	for _, post := range posts {
+		modelPosts = append(modelPosts, PostToModel(post))
 	}
+	edges, pageInfo := newConnection(modelPosts, page.After != nil, hasNext, postEdge(page))
+	return &model.PostConnection{Edges: edges, PageInfo: pageInfo}, nil
+}
 
-	comments, err := ps.commentService.GetCommentsForPosts(ctx, postIDs)
+func (ps *PostService) UpdatePost(ctx context.Context, postID int,
+	input model.UpdatePostInput) (*model.Post, error) {
+	post, err := ps.postRepo.GetPost(ctx, postID)
 	if err != nil {
 		return nil, err
 	}
+	if err = CheckAuthor(ctx, post.AuthorID); err != nil {
+		return nil, err
+	}
 
-	commentsByPID := groupComments(comments)
+	updated, flags, err := UpdatedPost(post, input, ps.filters)
+	if err != nil {
+		return nil, err
+	}
+	if err = ps.postRepo.UpdatePost(ctx, updated); err != nil {
+		return nil, err
+	}
+	if updated.Content != post.Content {
+		if err = ps.mentions.SetPostMentions(ctx, postID, updated.Content); err != nil {
+			logrus.WithError(err).Error("failed to save post mentions")
+		}
+	}
+	if err = ps.moderation.FlagPost(ctx, postID, flags); err != nil {
+		logrus.WithError(err).Error("failed to flag post")
+	}
 
-	var modelPosts []*model.Post
-	for _, post := range posts {
-		modelPosts = append(modelPosts, &model.Post{
-			ID:          post.ID,
-			Author:      users[post.AuthorID],
-			Title:       post.Title,
-			Content:     post.Content,
-			Created:     post.Created,
-			Commentable: post.Commentable,
-			Comments:    commentsByPID[post.ID],
-		})
-	}
-	return modelPosts, nil
+	edited := PostToModel(updated)
+	ps.publish(ctx, postID, &model.PostUpdated{Post: edited})
+	return edited, nil
 }
 
-func extractAuthorIDs(posts []*entity.Post) []int {
-	ids := make(map[int]bool)
-	for _, post := range posts {
-		ids[post.AuthorID] = true
+func (ps *PostService) DeletePost(ctx context.Context, postID int) (bool, error) {
+	post, err := ps.postRepo.GetPost(ctx, postID)
+	if err != nil {
+		return false, err
+	}
+	if err = CheckAuthor(ctx, post.AuthorID); err != nil {
+		return false, err
+	}
+
+	if err = RemovePost(ctx, ps.postRepo, ps.broker, postID); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// RemovePost deletes the post and ends the subscriptions to it, the caller checks that the viewer
+// may delete it.
+func RemovePost(ctx context.Context, postRepo repo.PostRepo, broker pubsub.Broker, postID int) error {
+	if err := postRepo.DeletePost(ctx, postID); err != nil {
+		return err
+	}
+
+	if broker != nil {
+		broker.Publish(ctx, postID, &model.PostDeleted{PostID: postID})
+	}
+	return nil
+}
+
+func (ps *PostService) publish(ctx context.Context, postID int, event model.Event) {
+	if ps.broker != nil {
+		ps.broker.Publish(ctx, postID, event)
+	}
+}
+
+// UpdatedPost returns a copy of the post with the input run through the filters, checked and applied
+// and the edit time set, along with the reasons to flag it.
+func UpdatedPost(post *entity.Post, input model.UpdatePostInput,
+	filters *filter.Pipeline) (*entity.Post, []string, error) {
+	if input.Title == nil && input.Content == nil {
+		return nil, nil, ErrEmptyUpdate
+	}
+
+	updated := *post
+	var flags []string
+	if input.Title != nil {
+		title, titleFlags, err := filters.Apply(filter.TargetPosts, *input.Title)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err = CheckLength(title, MaxTitleLength, ErrTitleTooLong); err != nil {
+			return nil, nil, err
+		}
+		updated.Title = title
+		flags = append(flags, titleFlags...)
+	}
+	if input.Content != nil {
+		content, contentFlags, err := filters.Apply(filter.TargetPosts, *input.Content)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err = CheckLength(content, MaxPostLength, ErrPostTooLong); err != nil {
+			return nil, nil, err
+		}
+		updated.Content = content
+		flags = append(flags, contentFlags...)
+	}
+	edited := time.Now()
+	updated.Edited = &edited
+
+	return &updated, flags, nil
+}
+
+// FilteredPostInput runs the title and the content of a new post through the filters, checks
+// their length and returns the input to save along with the reasons to flag it.
+func FilteredPostInput(input model.CreatePostInput, filters *filter.Pipeline) (model.CreatePostInput, []string, error) {
+	title, titleFlags, err := filters.Apply(filter.TargetPosts, input.Title)
+	if err != nil {
+		return input, nil, err
 	}
-	result := make([]int, 0, len(ids))
-	for id := range ids {
-		result = append(result, id)
+	if err = CheckLength(title, MaxTitleLength, ErrTitleTooLong); err != nil {
+		return input, nil, err
+	}
+	content, contentFlags, err := filters.Apply(filter.TargetPosts, input.Content)
+	if err != nil {
+		return input, nil, err
+	}
+	if err = CheckLength(content, MaxPostLength, ErrPostTooLong); err != nil {
+		return input, nil, err
+	}
+
+	input.Title, input.Content = title, content
+	return input, append(titleFlags, contentFlags...), nil
+}
+
+func PostToModel(post *entity.Post) *model.Post {
+	return &model.Post{
+		ID:          post.ID,
+		AuthorID:    post.AuthorID,
+		Title:       post.Title,
+		Content:     post.Content,
+		Created:     post.Created,
+		Commentable: post.Commentable,
+		EditedAt:    post.Edited,
+		Hidden:      post.Hidden,
+		Score:       post.Score,
 	}
-	return result
 }
 
-func groupComments(comments []*model.Comment) map[int][]*model.Comment {
-	grouped := make(map[int][]*model.Comment)
-	for _, comment := range comments {
-		grouped[comment.Post.ID] = append(grouped[comment.Post.ID], comment)
+// CheckLength returns tooLong if the text has more than max characters.
+func CheckLength(text string, max int, tooLong error) error {
+	if utf8.RuneCountInString(text) > max {
+		return tooLong
 	}
-	return grouped
+	return nil
 }
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"Commentary/internal/auth"
+	"Commentary/internal/common"
+	"Commentary/internal/entity"
+	"Commentary/internal/graph/model"
+	"Commentary/internal/repo"
+	"context"
+	"time"
+)
+
+type reactionService struct {
+	reactionRepo repo.ReactionRepo
+	types        ReactionTypes
+}
+
+func NewReactionService(reactionRepo repo.ReactionRepo, types []string) common.ReactionService {
+	return &reactionService{reactionRepo: reactionRepo, types: NewReactionTypes(types)}
+}
+
+func (rs *reactionService) React(ctx context.Context, input model.ReactionInput) ([]*model.ReactionCount, error) {
+	userID, err := auth.ViewerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	target, err := rs.types.Target(input)
+	if err != nil {
+		return nil, err
+	}
+
+	err = rs.reactionRepo.AddReaction(ctx, &entity.Reaction{
+		UserID:  userID,
+		Target:  target,
+		Type:    input.Type,
+		Created: time.Now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return rs.targetReactions(ctx, target, userID)
+}
+
+func (rs *reactionService) Unreact(ctx context.Context, input model.ReactionInput) ([]*model.ReactionCount, error) {
+	userID, err := auth.ViewerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	target, err := rs.types.Target(input)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = rs.reactionRepo.RemoveReaction(ctx, userID, target, input.Type); err != nil {
+		return nil, err
+	}
+
+	return rs.targetReactions(ctx, target, userID)
+}
+
+func (rs *reactionService) GetPostReactions(ctx context.Context, postIDs []int) (map[int][]*model.ReactionCount, error) {
+	return rs.getReactions(ctx, entity.TargetPost, postIDs)
+}
+
+func (rs *reactionService) GetCommentReactions(ctx context.Context,
+	commentIDs []int) (map[int][]*model.ReactionCount, error) {
+	return rs.getReactions(ctx, entity.TargetComment, commentIDs)
+}
+
+func (rs *reactionService) getReactions(ctx context.Context, kind entity.TargetKind,
+	ids []int) (map[int][]*model.ReactionCount, error) {
+	viewerID, _ := auth.UserIDFromContext(ctx)
+
+	counts, err := rs.reactionRepo.GetReactionCounts(ctx, kind, ids, viewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	return GroupReactionCounts(ids, counts), nil
+}
+
+func (rs *reactionService) targetReactions(ctx context.Context, target entity.ReactionTarget,
+	viewerID int) ([]*model.ReactionCount, error) {
+	counts, err := rs.reactionRepo.GetReactionCounts(ctx, target.Kind, []int{target.ID}, viewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	return GroupReactionCounts([]int{target.ID}, counts)[target.ID], nil
+}
+
+// ReactionTypes is the set of reaction types users are allowed to leave.
+type ReactionTypes map[string]struct{}
+
+func NewReactionTypes(types []string) ReactionTypes {
+	set := make(ReactionTypes, len(types))
+	for _, reactionType := range types {
+		set[reactionType] = struct{}{}
+	}
+	return set
+}
+
+// Target validates the input and returns the post or comment it points to.
+func (rt ReactionTypes) Target(input model.ReactionInput) (entity.ReactionTarget, error) {
+	if _, ok := rt[input.Type]; !ok {
+		return entity.ReactionTarget{}, ErrUnknownReaction
+	}
+
+	switch {
+	case input.PostID != nil && input.CommentID == nil:
+		return entity.ReactionTarget{Kind: entity.TargetPost, ID: *input.PostID}, nil
+	case input.CommentID != nil && input.PostID == nil:
+		return entity.ReactionTarget{Kind: entity.TargetComment, ID: *input.CommentID}, nil
+	default:
+		return entity.ReactionTarget{}, ErrInvalidReactionTarget
+	}
+}
+
+// GroupReactionCounts splits counts by target, targets without reactions get an empty list.
+func GroupReactionCounts(ids []int, counts []*entity.ReactionCount) map[int][]*model.ReactionCount {
+	result := make(map[int][]*model.ReactionCount, len(ids))
+	for _, id := range ids {
+		result[id] = []*model.ReactionCount{}
+	}
+	for _, count := range counts {
+		result[count.TargetID] = append(result[count.TargetID], &model.ReactionCount{
+			Type:          count.Type,
+			Count:         count.Count,
+			ViewerReacted: count.ViewerReacted,
+		})
+	}
+	return result
+}
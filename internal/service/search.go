@@ -0,0 +1,66 @@
+package service
+
+import (
+	"Commentary/internal/common"
+	"Commentary/internal/entity"
+	"Commentary/internal/graph/model"
+	"Commentary/internal/pagination"
+	"Commentary/internal/repo"
+	"context"
+	"strings"
+)
+
+type searchService struct {
+	searchRepo repo.SearchRepo
+	moderation common.ModerationService
+}
+
+func NewSearchService(searchRepo repo.SearchRepo, moderation common.ModerationService) common.SearchService {
+	return &searchService{searchRepo: searchRepo, moderation: moderation}
+}
+
+func (ss *searchService) Search(ctx context.Context, text string, kinds []model.SearchKind, first *int,
+	after *string) (*model.SearchConnection, error) {
+	if strings.TrimSpace(text) == "" {
+		return nil, ErrEmptySearch
+	}
+	page, err := pagination.NewSearchPage(first, after)
+	if err != nil {
+		return nil, err
+	}
+	withHidden, err := ss.moderation.IsModerator(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	hits, err := ss.searchRepo.Search(ctx, text, SearchKinds(kinds), page, withHidden)
+	if err != nil {
+		return nil, err
+	}
+
+	hits, hasNext := pagination.Trim(hits, pagination.Page{First: page.First})
+	edges, pageInfo := newConnection(hits, page.After != nil, hasNext, searchEdge)
+	return &model.SearchConnection{Edges: edges, PageInfo: pageInfo}, nil
+}
+
+// SearchKinds converts the requested kinds, no kinds means searching everywhere.
+func SearchKinds(kinds []model.SearchKind) []entity.TargetKind {
+	if len(kinds) == 0 {
+		return []entity.TargetKind{entity.TargetPost, entity.TargetComment}
+	}
+
+	result := make([]entity.TargetKind, 0, len(kinds))
+	seen := make(map[model.SearchKind]bool, len(kinds))
+	for _, kind := range kinds {
+		if seen[kind] {
+			continue
+		}
+		seen[kind] = true
+		if kind == model.SearchKindComment {
+			result = append(result, entity.TargetComment)
+		} else {
+			result = append(result, entity.TargetPost)
+		}
+	}
+	return result
+}
@@ -1,20 +1,87 @@
 package service
 
 import (
+	"Commentary/internal/auth"
 	"Commentary/internal/common"
 	"Commentary/internal/graph/model"
-	"Commentary/internal/repo/pgdb"
+	"Commentary/internal/repo"
 	"context"
+	"errors"
+	"strings"
 )
 
+// MaxUsernameLength is the number of characters a username may take.
+const MaxUsernameLength = 20
+
 type userService struct {
-	userRepo pgdb.UserRepo
+	userRepo repo.UserRepo
+	tokens   *auth.TokenManager
+}
+
+func NewUserService(userRepo repo.UserRepo, tokens *auth.TokenManager) common.UserService {
+	return &userService{userRepo: userRepo, tokens: tokens}
+}
+
+func (us *userService) CreateUser(ctx context.Context, username, password string) (*model.User, error) {
+	username = strings.TrimSpace(username)
+	if username == "" {
+		return nil, ErrEmptyUsername
+	}
+	if err := CheckLength(username, MaxUsernameLength, ErrUsernameTooLong); err != nil {
+		return nil, err
+	}
+
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+
+	return us.userRepo.AddUser(ctx, username, hash)
+}
+
+func (us *userService) Login(ctx context.Context, username, password string) (*model.AuthPayload, error) {
+	user, err := us.userRepo.GetUserByUsername(ctx, strings.TrimSpace(username))
+	if err != nil {
+		if errors.Is(err, repo.ErrUserNotFound) {
+			return nil, auth.RejectPassword(password)
+		}
+		return nil, err
+	}
+
+	if err = auth.CheckPassword(user.PasswordHash, password); err != nil {
+		return nil, err
+	}
+
+	return NewAuthPayload(us.tokens, &model.User{ID: user.ID, Username: user.Username})
+}
+
+func (us *userService) Refresh(ctx context.Context, refreshToken string) (*model.AuthPayload, error) {
+	userID, err := us.tokens.Parse(refreshToken, auth.RefreshToken)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := us.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, auth.ErrInvalidToken
+	}
+
+	return NewAuthPayload(us.tokens, user)
 }
 
-func NewUserService(userRepo pgdb.UserRepo) common.UserService {
-	return &userService{userRepo: userRepo}
+func (us *userService) GetUsersByIDs(ctx context.Context, ids []int) (map[int]*model.User, error) {
+	return us.userRepo.GetUsersByIDs(ctx, ids)
 }
 
-func (us *userService) CreateUser(ctx context.Context, username string) (*model.User, error) {
-	return us.userRepo.AddUser(ctx, username)
+func NewAuthPayload(tokens *auth.TokenManager, user *model.User) (*model.AuthPayload, error) {
+	access, refresh, err := tokens.IssuePair(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.AuthPayload{
+		AccessToken:  access,
+		RefreshToken: refresh,
+		User:         user,
+	}, nil
 }
@@ -0,0 +1,2 @@
+CREATE INDEX idx_posts_created_id ON posts (created, id);
+CREATE INDEX idx_comments_post_id_created_id ON comments (post_id, created, id);
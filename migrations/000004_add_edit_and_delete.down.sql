@@ -0,0 +1,10 @@
+ALTER TABLE comments
+    DROP CONSTRAINT comments_post_id_fkey,
+    ADD CONSTRAINT comments_post_id_fkey FOREIGN KEY (post_id) REFERENCES posts (id);
+
+ALTER TABLE comments
+    DROP COLUMN IF EXISTS deleted,
+    DROP COLUMN IF EXISTS edited;
+
+ALTER TABLE posts
+    DROP COLUMN IF EXISTS edited;
@@ -0,0 +1,10 @@
+ALTER TABLE posts
+    ADD COLUMN edited TIMESTAMP;
+
+ALTER TABLE comments
+    ADD COLUMN edited  TIMESTAMP,
+    ADD COLUMN deleted BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE comments
+    DROP CONSTRAINT comments_post_id_fkey,
+    ADD CONSTRAINT comments_post_id_fkey FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE CASCADE;
@@ -0,0 +1,10 @@
+CREATE TABLE comment_revisions
+(
+    id         SERIAL PRIMARY KEY,
+    comment_id INTEGER       NOT NULL REFERENCES comments (id) ON DELETE CASCADE,
+    content    VARCHAR(2000) NOT NULL,
+    editor_id  INTEGER       NOT NULL REFERENCES users (id),
+    edited     TIMESTAMP     NOT NULL
+);
+
+CREATE INDEX idx_comment_revisions_comment_id ON comment_revisions (comment_id, edited, id);
@@ -0,0 +1,15 @@
+CREATE TABLE reactions
+(
+    id         SERIAL PRIMARY KEY,
+    user_id    INTEGER     NOT NULL REFERENCES users (id),
+    post_id    INTEGER REFERENCES posts (id) ON DELETE CASCADE,
+    comment_id INTEGER REFERENCES comments (id) ON DELETE CASCADE,
+    type       VARCHAR(32) NOT NULL,
+    created    TIMESTAMP   NOT NULL,
+    CHECK ((post_id IS NULL) <> (comment_id IS NULL)),
+    UNIQUE (user_id, post_id, type),
+    UNIQUE (user_id, comment_id, type)
+);
+
+CREATE INDEX idx_reactions_post_id ON reactions (post_id);
+CREATE INDEX idx_reactions_comment_id ON reactions (comment_id);
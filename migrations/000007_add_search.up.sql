@@ -0,0 +1,8 @@
+ALTER TABLE posts
+    ADD COLUMN search TSVECTOR GENERATED ALWAYS AS (to_tsvector('simple', title || ' ' || content)) STORED;
+
+ALTER TABLE comments
+    ADD COLUMN search TSVECTOR GENERATED ALWAYS AS (to_tsvector('simple', content)) STORED;
+
+CREATE INDEX idx_posts_search ON posts USING GIN (search);
+CREATE INDEX idx_comments_search ON comments USING GIN (search);
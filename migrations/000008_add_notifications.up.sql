@@ -0,0 +1,12 @@
+CREATE TABLE notifications
+(
+    id         SERIAL PRIMARY KEY,
+    user_id    INTEGER     NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    kind       VARCHAR(16) NOT NULL,
+    comment_id INTEGER     NOT NULL REFERENCES comments (id) ON DELETE CASCADE,
+    actor_id   INTEGER     NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    created    TIMESTAMP   NOT NULL,
+    read       BOOLEAN     NOT NULL DEFAULT FALSE
+);
+
+CREATE INDEX idx_notifications_user_id ON notifications (user_id, created, id);
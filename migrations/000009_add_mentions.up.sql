@@ -0,0 +1,13 @@
+CREATE TABLE mentions
+(
+    id         SERIAL PRIMARY KEY,
+    post_id    INTEGER REFERENCES posts (id) ON DELETE CASCADE,
+    comment_id INTEGER REFERENCES comments (id) ON DELETE CASCADE,
+    user_id    INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    start      INTEGER NOT NULL,
+    length     INTEGER NOT NULL,
+    CHECK ((post_id IS NULL) <> (comment_id IS NULL))
+);
+
+CREATE INDEX idx_mentions_post_id ON mentions (post_id);
+CREATE INDEX idx_mentions_comment_id ON mentions (comment_id);
@@ -0,0 +1,24 @@
+ALTER TABLE users
+    ADD COLUMN role VARCHAR(16) NOT NULL DEFAULT 'user';
+
+ALTER TABLE posts
+    ADD COLUMN hidden BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE comments
+    ADD COLUMN hidden BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE reports
+(
+    id          SERIAL PRIMARY KEY,
+    post_id     INTEGER REFERENCES posts (id) ON DELETE CASCADE,
+    comment_id  INTEGER REFERENCES comments (id) ON DELETE CASCADE,
+    reporter_id INTEGER   NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    reason      TEXT      NOT NULL,
+    created     TIMESTAMP NOT NULL,
+    resolved    BOOLEAN   NOT NULL DEFAULT FALSE,
+    CHECK ((post_id IS NULL) <> (comment_id IS NULL))
+);
+
+CREATE UNIQUE INDEX idx_reports_open_post ON reports (reporter_id, post_id) WHERE NOT resolved;
+CREATE UNIQUE INDEX idx_reports_open_comment ON reports (reporter_id, comment_id) WHERE NOT resolved;
+CREATE INDEX idx_reports_queue ON reports (created, id) WHERE NOT resolved;
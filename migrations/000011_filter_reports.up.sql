@@ -0,0 +1,2 @@
+ALTER TABLE reports
+    ALTER COLUMN reporter_id DROP NOT NULL;
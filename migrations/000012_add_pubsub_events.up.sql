@@ -0,0 +1,8 @@
+CREATE TABLE pubsub_events
+(
+    id      BIGSERIAL PRIMARY KEY,
+    payload TEXT      NOT NULL,
+    created TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_pubsub_events_created ON pubsub_events (created);